@@ -0,0 +1,295 @@
+// Package adaptor converts between net/http handlers and ngebut handlers.
+// It allows existing net/http handlers and middleware (pprof, promhttp, OAuth callbacks, ...)
+// to be registered on a ngebut router, and ngebut handlers or routers to be served by net/http.
+package adaptor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ryanbekhen/ngebut"
+)
+
+// HTTPHandlerFunc wraps a net/http handler function into a ngebut handler.
+func HTTPHandlerFunc(h http.HandlerFunc) ngebut.Handler {
+	return HTTPHandler(h)
+}
+
+// HTTPHandler wraps a net/http handler into a ngebut handler.
+// The status code, headers and body written by the handler are copied to the ngebut context.
+// Route parameters are exposed through http.Request.PathValue.
+func HTTPHandler(h http.Handler) ngebut.Handler {
+	return func(c *ngebut.Ctx) {
+		req := ConvertRequest(c)
+		w := newResponseWriter(c)
+		h.ServeHTTP(w, req)
+		w.finish()
+	}
+}
+
+// HTTPMiddleware wraps a net/http middleware into a ngebut middleware.
+// When the wrapped middleware calls the next handler, changes it made to the request
+// (context, headers, URL) are applied to the ngebut context and the ngebut chain continues.
+// The response of the chain is recorded and written to the writer passed to the next handler,
+// so that the middleware can read or change the status code, headers and body after next returns.
+// If the middleware writes a response without calling the next handler, the chain ends.
+func HTTPMiddleware(mw func(http.Handler) http.Handler) ngebut.Middleware {
+	return func(c *ngebut.Ctx) {
+		w := newResponseWriter(c)
+		called, pending := false, false
+		next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			called = true
+
+			// Propagate request modifications to the ngebut request, including removed headers
+			c.Request.SetContext(r.Context())
+			c.Request.URL = r.URL
+			header := *c.Request.Header
+			for k := range header {
+				if _, ok := r.Header[k]; !ok {
+					delete(header, k)
+				}
+			}
+			for k, values := range r.Header {
+				header[k] = values
+			}
+
+			// Apply the headers the middleware set on the response before calling next
+			w.copyHeaders()
+
+			// Record the response of the chain
+			rec := newResponseRecorder()
+			writer := c.Writer
+			c.Writer = ngebut.NewResponseWriter(rec)
+			c.Next()
+			ngebut.ReleaseResponseWriter(c.Writer)
+			c.Writer = writer
+
+			// Errors without a response are left to the error handler
+			if !rec.wroteHeader && c.GetError() != nil {
+				pending = true
+				return
+			}
+
+			rec.writeTo(rw, c.StatusCode())
+		})
+
+		mw(next).ServeHTTP(w, ConvertRequest(c))
+
+		if !called {
+			// The middleware handled the request itself
+			c.Abort()
+		}
+		if !pending {
+			w.finish()
+		}
+	}
+}
+
+// NgebutHandler wraps a ngebut handler into a net/http handler.
+func NgebutHandler(h ngebut.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ngebut.GetContext(w, r)
+		defer ngebut.ReleaseContext(ctx)
+
+		h(ctx)
+		finishContext(ctx)
+	})
+}
+
+// NgebutRouter exposes a ngebut router as a net/http handler.
+// Errors set with c.Error are written in the same way as the default error handler.
+// To use a custom error handler, serve a *ngebut.Server instead, which implements http.Handler.
+func NgebutRouter(router *ngebut.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ngebut.GetContext(w, r)
+		defer ngebut.ReleaseContext(ctx)
+
+		router.ServeHTTP(ctx, ctx.Request)
+		finishContext(ctx)
+	})
+}
+
+// finishContext writes any pending error and flushes the response of a ngebut context.
+func finishContext(ctx *ngebut.Ctx) {
+	if err := ctx.GetError(); err != nil {
+		statusCode := ctx.StatusCode()
+
+		var httpErr *ngebut.HttpError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		ctx.Status(statusCode)
+		ctx.String("%v", err)
+	}
+
+	ctx.Writer.Flush()
+}
+
+// ConvertRequest builds a net/http request from a ngebut context.
+// The request shares the context, URL, headers and body of the ngebut request, and
+// route parameters are available through PathValue.
+func ConvertRequest(c *ngebut.Ctx) *http.Request {
+	src := c.Request
+
+	u := *src.URL
+	req := &http.Request{
+		Method:        src.Method,
+		URL:           &u,
+		Proto:         src.Proto,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header, len(*src.Header)),
+		Body:          http.NoBody,
+		ContentLength: src.ContentLength,
+		Host:          src.Host,
+		RemoteAddr:    src.RemoteAddr,
+		RequestURI:    src.RequestURI,
+	}
+
+	if major, minor, ok := http.ParseHTTPVersion(src.Proto); ok {
+		req.ProtoMajor, req.ProtoMinor = major, minor
+	} else {
+		req.Proto = "HTTP/1.1"
+	}
+
+	for k, values := range *src.Header {
+		req.Header[k] = values
+	}
+
+	if len(src.Body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(src.Body))
+		req.ContentLength = int64(len(src.Body))
+	}
+
+	for name, value := range c.AllParams() {
+		req.SetPathValue(name, value)
+	}
+
+	return req.WithContext(src.Context())
+}
+
+// responseWriter implements http.ResponseWriter and http.Flusher on top of a ngebut context.
+type responseWriter struct {
+	c           *ngebut.Ctx
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+}
+
+// newResponseWriter creates a responseWriter for the given context.
+func newResponseWriter(c *ngebut.Ctx) *responseWriter {
+	return &responseWriter{
+		c:          c,
+		header:     make(http.Header),
+		statusCode: ngebut.StatusOK,
+	}
+}
+
+// Header returns the header map that will be sent by WriteHeader.
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader copies the headers and status code to the ngebut context.
+// Like net/http, only the first call has an effect.
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+
+	w.copyHeaders()
+	w.c.Status(statusCode)
+	w.c.Writer.WriteHeader(statusCode)
+}
+
+// Write writes the data to the ngebut response, sniffing the content type if none was set.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.header.Get(ngebut.HeaderContentType) == "" && len(b) > 0 {
+			w.header.Set(ngebut.HeaderContentType, http.DetectContentType(b))
+		}
+		w.WriteHeader(ngebut.StatusOK)
+	}
+	return w.c.Writer.Write(b)
+}
+
+// Flush sends the buffered data to the client.
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(ngebut.StatusOK)
+	}
+	w.c.Writer.Flush()
+}
+
+// copyHeaders copies the collected headers to the ngebut context.
+func (w *responseWriter) copyHeaders() {
+	for k, values := range w.header {
+		(*w.c.Header())[k] = values
+		(*w.c.Writer.Header())[k] = values
+	}
+}
+
+// finish writes the status line if the wrapped handler did not write anything.
+func (w *responseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(w.statusCode)
+	}
+}
+
+// responseRecorder implements http.ResponseWriter and records the response of the ngebut chain.
+type responseRecorder struct {
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// newResponseRecorder creates an empty responseRecorder.
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header:     make(http.Header),
+		statusCode: ngebut.StatusOK,
+	}
+}
+
+// Header returns the recorded headers.
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader records the status code. Like net/http, only the first call has an effect.
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.statusCode = statusCode
+}
+
+// Write records the data, along with the default status code if none was written.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(ngebut.StatusOK)
+	return r.body.Write(b)
+}
+
+// writeTo writes the recorded response to w. statusCode is used when no status was written.
+func (r *responseRecorder) writeTo(w http.ResponseWriter, statusCode int) {
+	if r.wroteHeader {
+		statusCode = r.statusCode
+	}
+
+	header := w.Header()
+	for k, values := range r.header {
+		header[k] = values
+	}
+
+	w.WriteHeader(statusCode)
+	if r.body.Len() > 0 {
+		w.Write(r.body.Bytes())
+	}
+}
@@ -0,0 +1,327 @@
+package adaptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ryanbekhen/ngebut"
+	"github.com/stretchr/testify/assert"
+)
+
+// serve runs a request through a ngebut router and returns the recorded response
+func serve(router *ngebut.Router, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ctx := ngebut.GetContext(w, req)
+	defer ngebut.ReleaseContext(ctx)
+
+	router.ServeHTTP(ctx, ctx.Request)
+	ctx.Writer.Flush()
+	return w
+}
+
+// TestHTTPHandler tests that a net/http handler can be used as a ngebut handler
+func TestHTTPHandler(t *testing.T) {
+	assert := assert.New(t)
+	router := ngebut.NewRouter()
+
+	router.POST("/users/:id", HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-User", r.PathValue("id"))
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + ":" + string(body) + ":" + r.URL.Query().Get("q")))
+	}))
+
+	req, _ := http.NewRequest(ngebut.MethodPost, "http://example.com/users/42?q=x", strings.NewReader("payload"))
+	w := serve(router, req)
+
+	assert.Equal(http.StatusCreated, w.Code, "status code should be preserved")
+	assert.Equal("42", w.Header().Get("X-User"), "path params should be exposed via PathValue")
+	assert.Equal([]string{"a=1", "b=2"}, w.Header().Values("Set-Cookie"), "multi-value headers should be preserved")
+	assert.Equal("POST:payload:x", w.Body.String(), "body should be preserved")
+}
+
+// TestHTTPHandlerDefaults tests status and content type defaults of wrapped handlers
+func TestHTTPHandlerDefaults(t *testing.T) {
+	assert := assert.New(t)
+	router := ngebut.NewRouter()
+
+	router.GET("/html", HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>hi</body></html>"))
+	}))
+	router.GET("/empty", HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Empty", "yes")
+	}))
+
+	req, _ := http.NewRequest(ngebut.MethodGet, "http://example.com/html", nil)
+	w := serve(router, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("text/html; charset=utf-8", w.Header().Get("Content-Type"), "content type should be sniffed")
+
+	req, _ = http.NewRequest(ngebut.MethodGet, "http://example.com/empty", nil)
+	w = serve(router, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("yes", w.Header().Get("X-Empty"), "headers should be written without a body")
+}
+
+// TestHTTPHandlerStreaming tests that flushes are forwarded to the ngebut writer
+func TestHTTPHandlerStreaming(t *testing.T) {
+	assert := assert.New(t)
+	router := ngebut.NewRouter()
+
+	router.GET("/stream", HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		assert.True(ok, "response writer should implement http.Flusher")
+		_, _ = w.Write([]byte("chunk1,"))
+		flusher.Flush()
+		_, _ = w.Write([]byte("chunk2"))
+		flusher.Flush()
+	}))
+
+	req, _ := http.NewRequest(ngebut.MethodGet, "http://example.com/stream", nil)
+	w := serve(router, req)
+
+	assert.True(w.Flushed, "underlying writer should be flushed")
+	assert.Equal("chunk1,chunk2", w.Body.String())
+}
+
+// TestHTTPHandlerRequestContext tests that the request context is passed to the net/http handler
+func TestHTTPHandlerRequestContext(t *testing.T) {
+	type ctxKey struct{}
+
+	router := ngebut.NewRouter()
+	router.GET("/ctx", func(c *ngebut.Ctx) {
+		c.Request.SetContext(context.WithValue(c.Request.Context(), ctxKey{}, "value"))
+		HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.Context().Value(ctxKey{}).(string)))
+		})(c)
+	})
+
+	req, _ := http.NewRequest(ngebut.MethodGet, "http://example.com/ctx", nil)
+	w := serve(router, req)
+	assert.Equal(t, "value", w.Body.String())
+}
+
+// TestHTTPMiddleware tests that net/http middleware can be used as ngebut middleware
+func TestHTTPMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	type ctxKey struct{}
+
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Token") != "secret" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Middleware", "yes")
+			r.Header.Set("X-User", "alice")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "from-mw")))
+		})
+	}
+
+	router := ngebut.NewRouter()
+	router.Use(HTTPMiddleware(mw))
+	router.GET("/", func(c *ngebut.Ctx) {
+		c.String("%s %s", c.Get("X-User"), c.Request.Context().Value(ctxKey{}))
+	})
+
+	// Allowed request continues the ngebut chain
+	req, _ := http.NewRequest(ngebut.MethodGet, "http://example.com/", nil)
+	req.Header.Set("X-Token", "secret")
+	w := serve(router, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("yes", w.Header().Get("X-Middleware"), "headers set by middleware should be kept")
+	assert.Equal("alice from-mw", w.Body.String(), "request changes should be propagated")
+
+	// Rejected request stops the chain
+	req, _ = http.NewRequest(ngebut.MethodGet, "http://example.com/", nil)
+	w = serve(router, req)
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Equal("forbidden\n", w.Body.String())
+}
+
+// statusWriter records the status code written through it
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// TestHTTPMiddlewareResponse tests that net/http middleware sees the response of the ngebut chain
+func TestHTTPMiddlewareResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	var statusCode int
+	var body string
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			sw := &statusWriter{ResponseWriter: rec}
+			next.ServeHTTP(sw, r)
+			statusCode, body = sw.statusCode, rec.Body.String()
+
+			for k, values := range rec.Header() {
+				w.Header()[k] = values
+			}
+			w.Header().Set("X-Status", strconv.Itoa(statusCode))
+			w.WriteHeader(rec.Code)
+			w.Write([]byte(strings.ToUpper(body)))
+		})
+	}
+
+	router := ngebut.NewRouter()
+	router.Use(HTTPMiddleware(mw))
+	router.GET("/", func(c *ngebut.Ctx) {
+		c.Set("X-Handler", "yes")
+		c.Status(http.StatusCreated).String("created")
+	})
+	router.GET("/empty", func(c *ngebut.Ctx) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req, _ := http.NewRequest(ngebut.MethodGet, "http://example.com/", nil)
+	w := serve(router, req)
+	assert.Equal(http.StatusCreated, statusCode, "the middleware should see the status after next")
+	assert.Equal("created", body)
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal("201", w.Header().Get("X-Status"), "the middleware should be able to change the response")
+	assert.Equal("yes", w.Header().Get("X-Handler"))
+	assert.Equal("CREATED", w.Body.String())
+
+	req, _ = http.NewRequest(ngebut.MethodGet, "http://example.com/empty", nil)
+	w = serve(router, req)
+	assert.Equal(http.StatusNoContent, statusCode, "the status should be written without a body")
+	assert.Equal(http.StatusNoContent, w.Code)
+}
+
+// TestHTTPMiddlewareWithoutNext tests that net/http middleware ends the chain by not calling next
+func TestHTTPMiddlewareWithoutNext(t *testing.T) {
+	assert := assert.New(t)
+
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "secret" {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			r.Header.Del("Authorization")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	handled := false
+	router := ngebut.NewRouter()
+	router.Use(HTTPMiddleware(mw))
+	router.GET("/", func(c *ngebut.Ctx) {
+		handled = true
+		c.String("secret data, authorization %q", c.Get("Authorization"))
+	})
+
+	req, _ := http.NewRequest(ngebut.MethodGet, "http://example.com/", nil)
+	w := serve(router, req)
+	assert.False(handled, "the handler should not run when next is not called")
+	assert.Equal(http.StatusFound, w.Code)
+	assert.Equal("/login", w.Header().Get("Location"))
+	assert.NotContains(w.Body.String(), "secret data")
+
+	// Headers removed by the middleware are removed from the ngebut request
+	req, _ = http.NewRequest(ngebut.MethodGet, "http://example.com/", nil)
+	req.Header.Set("Authorization", "secret")
+	w = serve(router, req)
+	assert.True(handled)
+	assert.Equal(`secret data, authorization ""`, w.Body.String())
+}
+
+// TestNgebutHandler tests that a ngebut handler can be served by net/http
+func TestNgebutHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NgebutHandler(func(c *ngebut.Ctx) {
+		c.Set("X-Method", c.Method())
+		c.Status(ngebut.StatusAccepted).String("accepted")
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(http.StatusAccepted, w.Code)
+	assert.Equal("PUT", w.Header().Get("X-Method"))
+	assert.Equal("accepted", w.Body.String())
+}
+
+// TestNgebutRouter tests that a ngebut router can be served by net/http
+func TestNgebutRouter(t *testing.T) {
+	assert := assert.New(t)
+
+	router := ngebut.NewRouter()
+	router.GET("/users/:id", func(c *ngebut.Ctx) {
+		c.String("user %s", c.Param("id"))
+	})
+	router.GET("/fail", func(c *ngebut.Ctx) {
+		c.Error(ngebut.NewHttpError(ngebut.StatusTeapot, "teapot"))
+	})
+	router.GET("/boom", func(c *ngebut.Ctx) {
+		c.Error(errors.New("boom"))
+	})
+
+	srv := httptest.NewServer(NgebutRouter(router))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/users/7")
+	assert.NoError(err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("user 7", string(body))
+
+	resp, err = http.Get(srv.URL + "/fail")
+	assert.NoError(err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(http.StatusTeapot, resp.StatusCode)
+	assert.Equal("teapot", string(body))
+
+	resp, err = http.Get(srv.URL + "/boom")
+	assert.NoError(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/missing")
+	assert.NoError(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+// TestServerAsHTTPHandler tests that a ngebut server implements http.Handler
+func TestServerAsHTTPHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := ngebut.DefaultConfig()
+	cfg.ErrorHandler = func(c *ngebut.Ctx) {
+		c.Status(ngebut.StatusBadRequest).String("custom: %v", c.GetError())
+	}
+	server := ngebut.New(cfg)
+	server.GET("/", func(c *ngebut.Ctx) {
+		c.Error(errors.New("bad"))
+	})
+
+	var h http.Handler = server
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.Equal("custom: bad", w.Body.String())
+}
@@ -0,0 +1,452 @@
+package ngebut
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types the binder converts values to specially
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// valueBinder binds the values of a request source, such as the query or the headers,
+// to the fields of a struct tagged with the name of the source.
+//
+// Nested structs are bound with dotted keys, "address.city" for the city field of the
+// address field, and bracketed keys such as "address[city]" are accepted as well.
+// Maps are bound with the same keys, "labels.env" for the env entry of labels, and
+// slices of structs with indexed keys such as "items.0.name" or "items[0][name]".
+type valueBinder struct {
+	tag    string              // Struct tag naming the fields, such as "query"
+	values map[string][]string // Values by normalized key
+	fold   bool                // Whether keys are matched ignoring case, as header names
+}
+
+// newValueBinder returns a binder of values to the fields tagged with tag.
+func newValueBinder(tag string, values map[string][]string, fold bool) *valueBinder {
+	normalized := make(map[string][]string, len(values))
+	for key, v := range values {
+		key = normalizeBindKey(key)
+		if fold {
+			key = strings.ToLower(key)
+		}
+		normalized[key] = append(normalized[key], v...)
+	}
+	return &valueBinder{tag: tag, values: normalized, fold: fold}
+}
+
+// normalizeBindKey turns the brackets of a key into dots, "items[0][name]" into
+// "items.0.name", and removes the empty brackets of slices, as in "tags[]".
+func normalizeBindKey(key string) string {
+	if !strings.Contains(key, "[") {
+		return key
+	}
+	key = strings.ReplaceAll(key, "[]", "")
+	key = strings.ReplaceAll(key, "][", ".")
+	key = strings.ReplaceAll(key, "[", ".")
+	return strings.ReplaceAll(key, "]", "")
+}
+
+// structPointer returns the value of obj, which must be a pointer to a struct.
+func structPointer(obj interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errors.New("obj must be a pointer to a struct")
+	}
+	return v, nil
+}
+
+// bind binds the values to the struct obj points to.
+func (b *valueBinder) bind(obj interface{}) error {
+	v, err := structPointer(obj)
+	if err != nil {
+		return err
+	}
+	return b.bindStruct(v.Elem(), "")
+}
+
+// bindStruct binds the values with the given key prefix to the fields of a struct.
+func (b *valueBinder) bindStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !isEmbeddedStruct(field) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get(b.tag), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Structs without a tag, such as embedded structs, are bound with the keys of their fields
+		if name == "" {
+			if isNestedStruct(field.Type) {
+				if err := b.bindFlattened(v.Field(i), prefix); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := b.bindField(v.Field(i), field, prefix+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindFlattened binds the fields of a struct without a tag, allocating pointers only
+// when one of the fields is bound.
+func (b *valueBinder) bindFlattened(v reflect.Value, prefix string) error {
+	if v.Kind() != reflect.Ptr {
+		return b.bindStruct(v, prefix)
+	}
+	if !v.IsNil() {
+		return b.bindStruct(v.Elem(), prefix)
+	}
+
+	elem := reflect.New(v.Type().Elem())
+	if err := b.bindStruct(elem.Elem(), prefix); err != nil {
+		return err
+	}
+	if !elem.Elem().IsZero() {
+		v.Set(elem)
+	}
+	return nil
+}
+
+// bindField binds the values of a key to a field.
+func (b *valueBinder) bindField(v reflect.Value, field reflect.StructField, key string) error {
+	if !b.has(key) {
+		return nil
+	}
+
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return b.bindField(v.Elem(), field, key)
+
+	case isScalarType(t):
+		values := b.lookup(key)
+		if len(values) == 0 || values[0] == "" {
+			return nil
+		}
+		return setBindValue(v, field, key, values[0])
+
+	case t.Kind() == reflect.Slice:
+		if isNestedStruct(t.Elem()) {
+			return b.bindStructSlice(v, key)
+		}
+		values := b.lookup(key)
+		if len(values) == 0 {
+			return nil
+		}
+		return setBindValues(v, field, key, values)
+
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		return b.bindMap(v, field, key)
+
+	case t.Kind() == reflect.Struct:
+		return b.bindStruct(v, key+".")
+	}
+
+	// Other types are not bound
+	return nil
+}
+
+// bindStructSlice binds indexed keys, such as "items.0.name", to a slice of structs.
+func (b *valueBinder) bindStructSlice(v reflect.Value, key string) error {
+	indexes := make(map[int]bool)
+	for _, k := range b.subkeys(key) {
+		if i, err := strconv.Atoi(k); err == nil && i >= 0 && i < 1000 {
+			indexes[i] = true
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	sorted := make([]int, 0, len(indexes))
+	for i := range indexes {
+		sorted = append(sorted, i)
+	}
+	sort.Ints(sorted)
+
+	slice := reflect.MakeSlice(v.Type(), sorted[len(sorted)-1]+1, sorted[len(sorted)-1]+1)
+	reflect.Copy(slice, v)
+	for _, i := range sorted {
+		elem := slice.Index(i)
+		prefix := key + "." + strconv.Itoa(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			elem = elem.Elem()
+		}
+		if err := b.bindStruct(elem, prefix+"."); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+// bindMap binds the keys under key, such as "labels.env", to the entries of a map.
+func (b *valueBinder) bindMap(v reflect.Value, field reflect.StructField, key string) error {
+	t := v.Type()
+	if !isScalarType(t.Elem()) && t.Elem().Kind() != reflect.Slice {
+		return nil
+	}
+
+	for _, k := range b.subkeys(key) {
+		values := b.lookup(key + "." + k)
+		if len(values) == 0 {
+			continue
+		}
+
+		elem := reflect.New(t.Elem()).Elem()
+		var err error
+		if t.Elem().Kind() == reflect.Slice {
+			err = setBindValues(elem, field, key+"."+k, values)
+		} else {
+			err = setBindValue(elem, field, key+"."+k, values[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+	}
+	return nil
+}
+
+// lookup returns the values of a key.
+func (b *valueBinder) lookup(key string) []string {
+	if b.fold {
+		key = strings.ToLower(key)
+	}
+	return b.values[key]
+}
+
+// has reports whether there are values for a key or for keys under it.
+func (b *valueBinder) has(key string) bool {
+	if b.fold {
+		key = strings.ToLower(key)
+	}
+	if _, ok := b.values[key]; ok {
+		return true
+	}
+	for k := range b.values {
+		if len(k) > len(key) && k[len(key)] == '.' && strings.HasPrefix(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// subkeys returns the distinct first elements of the keys under key,
+// "0" and "1" for "items.0.name" and "items.1.name".
+func (b *valueBinder) subkeys(key string) []string {
+	if b.fold {
+		key = strings.ToLower(key)
+	}
+
+	var subkeys []string
+	seen := make(map[string]bool)
+	for k := range b.values {
+		if len(k) <= len(key)+1 || k[len(key)] != '.' || !strings.HasPrefix(k, key) {
+			continue
+		}
+		sub, _, _ := strings.Cut(k[len(key)+1:], ".")
+		if !seen[sub] {
+			seen[sub] = true
+			subkeys = append(subkeys, sub)
+		}
+	}
+	sort.Strings(subkeys)
+	return subkeys
+}
+
+// isScalarType reports whether values of type t are converted from a single string.
+func isScalarType(t reflect.Type) bool {
+	if t == timeType || t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isEmbeddedStruct reports whether a field is an embedded struct, whose exported
+// fields can be set even when the struct type is unexported.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	return field.Anonymous && field.Type.Kind() == reflect.Struct
+}
+
+// isNestedStruct reports whether t is a struct, or a pointer to one, bound field by field.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isScalarType(t)
+}
+
+// setBindValues sets a slice to the conversions of values.
+func setBindValues(v reflect.Value, field reflect.StructField, key string, values []string) error {
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, value := range values {
+		elem := slice.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elem.Type().Elem()))
+			elem = elem.Elem()
+		}
+		if !isScalarType(elem.Type()) {
+			return nil
+		}
+		if err := setBindValue(elem, field, key, value); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+// setBindValue sets v to the conversion of a value to its type. The layout of time.Time
+// values is given by the time_format tag of the field, time.RFC3339 by default.
+func setBindValue(v reflect.Value, field reflect.StructField, key, value string) error {
+	switch t := v.Type(); {
+	case t == timeType:
+		layout := field.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		parsed, err := time.Parse(layout, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as time: %w", key, err)
+		}
+		v.Set(reflect.ValueOf(parsed))
+		return nil
+
+	case t == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as duration: %w", key, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as int: %w", key, err)
+		}
+		v.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as uint: %w", key, err)
+		}
+		v.SetUint(uintValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as float: %w", key, err)
+		}
+		v.SetFloat(floatValue)
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as bool: %w", key, err)
+		}
+		v.SetBool(boolValue)
+	}
+	return nil
+}
+
+// applyDefaults sets the fields of a struct with a default tag, and a zero value, to the
+// value of the tag. Values of slices are separated by commas, as in `default:"a,b"`.
+// It does nothing when obj is not a pointer to a struct.
+func applyDefaults(obj interface{}) error {
+	v, err := structPointer(obj)
+	if err != nil {
+		return nil
+	}
+	return applyStructDefaults(v.Elem())
+}
+
+// applyStructDefaults sets the zero fields of a struct with a default tag, recursively.
+func applyStructDefaults(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !isEmbeddedStruct(field) {
+			continue
+		}
+		fv := v.Field(i)
+
+		value, ok := field.Tag.Lookup("default")
+		if !ok {
+			// Defaults of nested structs, unless they are nil pointers
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() && isNestedStruct(fv.Type()) {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && isNestedStruct(fv.Type()) {
+				if err := applyStructDefaults(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !fv.IsZero() {
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+
+		var err error
+		switch {
+		case isScalarType(fv.Type()):
+			err = setBindValue(fv, field, field.Name, value)
+		case fv.Kind() == reflect.Slice:
+			err = setBindValues(fv, field, field.Name, strings.Split(value, ","))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bindLevel is a text unmarshaler for binding tests
+type bindLevel int
+
+func (l *bindLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return &url.Error{Op: "level", URL: string(text), Err: ErrUnsupportedMediaType}
+	}
+	return nil
+}
+
+type bindPaging struct {
+	Page  int `query:"page" default:"1"`
+	Limit int `query:"limit" default:"20"`
+}
+
+type bindItem struct {
+	ID  int    `query:"id"`
+	SKU string `query:"sku"`
+}
+
+type bindFilter struct {
+	bindPaging
+	Status string            `query:"status"`
+	Tags   []string          `query:"tag"`
+	IDs    []int             `query:"ids" default:"1,2"`
+	Labels map[string]string `query:"labels"`
+	Range  struct{ Min, Max *int }
+	Owner  *struct {
+		Name string `query:"name"`
+	} `query:"owner"`
+	Items    []bindItem    `query:"items"`
+	Since    time.Time     `query:"since" time_format:"2006-01-02"`
+	Until    *time.Time    `query:"until"`
+	Timeout  time.Duration `query:"timeout" default:"5s"`
+	Level    bindLevel     `query:"level"`
+	Verbose  *bool         `query:"verbose"`
+	Ignored  string        `query:"-"`
+	internal string
+}
+
+// TestBindQuery tests binding query parameters to nested and special types
+func TestBindQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	query := "status=open&tag=a&tag=b&labels[env]=prod&labels.team=core&owner[name]=ana" +
+		"&items[1][id]=7&items[0][id]=3&items[0][sku]=x1&since=2025-03-01&until=2025-03-02T10:00:00Z" +
+		"&level=high&verbose=true&limit=50&Ignored=x&internal=x"
+	req, err := http.NewRequest(MethodGet, "http://example.com/items?"+query, nil)
+	require.NoError(t, err)
+	ctx := GetContext(httptest.NewRecorder(), req)
+	defer ReleaseContext(ctx)
+
+	var filter bindFilter
+	require.NoError(t, ctx.BindQuery(&filter))
+
+	assert.Equal(1, filter.Page, "default of an embedded struct field")
+	assert.Equal(50, filter.Limit)
+	assert.Equal("open", filter.Status)
+	assert.Equal([]string{"a", "b"}, filter.Tags)
+	assert.Equal([]int{1, 2}, filter.IDs)
+	assert.Equal(map[string]string{"env": "prod", "team": "core"}, filter.Labels)
+	assert.Nil(filter.Range.Min, "fields without a tag should not be bound")
+	if assert.NotNil(filter.Owner) {
+		assert.Equal("ana", filter.Owner.Name)
+	}
+	assert.Equal([]bindItem{{ID: 3, SKU: "x1"}, {ID: 7}}, filter.Items)
+	assert.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), filter.Since)
+	if assert.NotNil(filter.Until) {
+		assert.Equal(time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC), *filter.Until)
+	}
+	assert.Equal(5*time.Second, filter.Timeout)
+	assert.Equal(bindLevel(2), filter.Level)
+	if assert.NotNil(filter.Verbose) {
+		assert.True(*filter.Verbose)
+	}
+	assert.Empty(filter.Ignored)
+	assert.Empty(filter.internal)
+
+	// Conversion errors name the key
+	for query, message := range map[string]string{
+		"items[0][id]=x":            "failed to parse items.0.id as int",
+		"since=today":               "failed to parse since as time",
+		"timeout=long":              "failed to parse timeout as duration",
+		"level=max":                 "failed to parse level",
+		"page=99999999999999999999": "failed to parse page as int",
+	} {
+		req, _ := http.NewRequest(MethodGet, "http://example.com/items?"+query, nil)
+		ctx := GetContext(httptest.NewRecorder(), req)
+		err := ctx.BindQuery(&bindFilter{})
+		if assert.Error(err, query) {
+			assert.Contains(err.Error(), message, query)
+		}
+		ReleaseContext(ctx)
+	}
+
+	assert.EqualError(ctx.BindQuery(filter), "obj must be a pointer to a struct")
+}
+
+// TestBindSources tests binding headers, route parameters and bodies, separately and combined
+func TestBindSources(t *testing.T) {
+	assert := assert.New(t)
+
+	type updateUser struct {
+		ID      int      `uri:"id" json:"id"`
+		Name    string   `json:"name" form:"name" xml:"name"`
+		Roles   []string `json:"roles" form:"role"`
+		Notify  bool     `query:"notify" json:"-" default:"true"`
+		Version string   `query:"v" default:"1"`
+		TraceID string   `header:"X-Trace-Id"`
+		Lang    string   `header:"accept-language" default:"en"`
+	}
+
+	var (
+		bound   updateUser
+		bindErr error
+		bind    func(c *Ctx) error
+	)
+	router := NewRouter()
+	router.PUT("/users/:id", func(c *Ctx) {
+		bound = updateUser{}
+		bindErr = bind(c)
+	})
+
+	serve := func(target, contentType, body string, header http.Header) {
+		req, _ := http.NewRequest(MethodPut, "http://example.com"+target, strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if contentType != "" {
+			req.Header.Set(HeaderContentType, contentType)
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+	}
+
+	// Body decoders picked from the Content-Type
+	bind = func(c *Ctx) error { return c.Bind(&bound) }
+	serve("/users/7", MIMEApplicationJSON, `{"id":1,"name":"Ana","roles":["admin"]}`, nil)
+	assert.NoError(bindErr)
+	assert.Equal(updateUser{ID: 1, Name: "Ana", Roles: []string{"admin"}, Notify: true, Version: "1", Lang: "en"}, bound)
+
+	serve("/users/7", MIMEApplicationForm, "name=Budi&role=a&role=b", nil)
+	assert.NoError(bindErr)
+	assert.Equal("Budi", bound.Name)
+	assert.Equal([]string{"a", "b"}, bound.Roles)
+
+	serve("/users/7", MIMEApplicationXML, `<user><name>Citra</name></user>`, nil)
+	assert.NoError(bindErr)
+	assert.Equal("Citra", bound.Name)
+
+	serve("/users/7", "text/csv-unknown", "a,b", nil)
+	assert.ErrorIs(bindErr, ErrUnsupportedMediaType)
+
+	serve("/users/7", "", "", nil)
+	assert.NoError(bindErr, "requests without a body should bind the defaults only")
+	assert.Equal("1", bound.Version)
+
+	// Headers and route parameters
+	bind = func(c *Ctx) error { return c.BindHeader(&bound) }
+	serve("/users/7", "", "", http.Header{"X-Trace-Id": {"abc"}, "Accept-Language": {"id"}})
+	assert.NoError(bindErr)
+	assert.Equal("abc", bound.TraceID)
+	assert.Equal("id", bound.Lang)
+
+	bind = func(c *Ctx) error { return c.BindURI(&bound) }
+	serve("/users/7", "", "", nil)
+	assert.NoError(bindErr)
+	assert.Equal(7, bound.ID)
+
+	// All the sources, route parameters taking precedence over the body
+	bind = func(c *Ctx) error { return c.BindAll(&bound) }
+	serve("/users/7?notify=false&v=2", MIMEApplicationJSON, `{"id":1,"name":"Dewi"}`, http.Header{"X-Trace-Id": {"xyz"}})
+	assert.NoError(bindErr)
+	assert.Equal(updateUser{ID: 7, Name: "Dewi", Notify: false, Version: "2", TraceID: "xyz", Lang: "en"}, bound)
+
+	serve("/users/x", "", "", nil)
+	assert.ErrorContains(bindErr, "failed to parse id as int")
+	serve("/users/7", MIMEApplicationJSON, `{"id":`, nil)
+	assert.Error(bindErr)
+}
@@ -0,0 +1,151 @@
+package ngebut
+
+import (
+	"path"
+	"strings"
+)
+
+// cleanPath returns the canonical form of a URL path.
+// It resolves "." and ".." elements, replaces multiple slashes with a single one
+// and makes sure the path starts with a slash. A trailing slash is kept.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if hasTrailingSlash(p) && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// toggleTrailingSlash adds a trailing slash to a path without one, and removes it otherwise.
+func toggleTrailingSlash(p string) string {
+	if hasTrailingSlash(p) {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
+// pathMatches reports whether a route registered for method, or for GET when HEAD
+// requests fall back to GET routes, matches the path.
+func (r *Router) pathMatches(method, p string) bool {
+	if r.methodMatchesPath(method, p) {
+		return true
+	}
+	return method == MethodHead && r.HandleHEAD && r.methodMatchesPath(MethodGet, p)
+}
+
+// serve calls the route registered for method, or for GET when HEAD requests fall
+// back to GET routes, that matches the path. It reports whether a route was found.
+func (r *Router) serve(ctx *Ctx, req *Request, method, p string) bool {
+	if r.serveMethod(ctx, req, method, p) {
+		return true
+	}
+
+	// HEAD requests fall back to the GET route; the method stays HEAD so handlers can detect it
+	return method == MethodHead && r.HandleHEAD && r.serveMethod(ctx, req, MethodGet, p)
+}
+
+// lookupPath returns the path of a route matching p when trailing slashes or letter case
+// are ignored, as allowed by the StrictRouting and CaseSensitive options.
+func (r *Router) lookupPath(method, p string, ignoreSlash, ignoreCase bool) (string, bool) {
+	if r.pathMatches(method, p) {
+		return p, true
+	}
+	if ignoreSlash && p != "/" {
+		if toggled := toggleTrailingSlash(p); r.pathMatches(method, toggled) {
+			return toggled, true
+		}
+	}
+	if ignoreCase {
+		if fixed, ok := r.findCaseInsensitivePath(method, p); ok {
+			return fixed, true
+		}
+		if ignoreSlash && p != "/" {
+			if fixed, ok := r.findCaseInsensitivePath(method, toggleTrailingSlash(p)); ok {
+				return fixed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// servesPath reports whether a route serves requests for method and path, ignoring
+// trailing slashes or letter case as allowed by the StrictRouting and CaseSensitive options.
+func (r *Router) servesPath(method, p string) bool {
+	_, ok := r.lookupPath(method, p, !r.StrictRouting, !r.CaseSensitive)
+	return ok
+}
+
+// findCaseInsensitivePath returns the path with the letter case of a route matching p
+// when case is ignored. Parameter and wildcard values keep their case.
+func (r *Router) findCaseInsensitivePath(method, p string) (string, bool) {
+	t := r.routeTable()
+	if root := t.trees[method]; root != nil {
+		if fixed, ok := root.matchFold(p, 0, make([]byte, 0, len(p)+1)); ok {
+			return string(fixed), true
+		}
+	}
+
+	// HEAD requests fall back to the GET routes
+	if root := t.trees[MethodGet]; method == MethodHead && r.HandleHEAD && root != nil {
+		if fixed, ok := root.matchFold(p, 0, make([]byte, 0, len(p)+1)); ok {
+			return string(fixed), true
+		}
+	}
+	return "", false
+}
+
+// redirectPath returns the path a request should be redirected to, as allowed by the
+// RedirectTrailingSlash and RedirectFixedPath options.
+//
+// Browsers read a Location starting with "//" or "/\\" as a URL of another host, so
+// leading slashes are collapsed and paths with backslashes are never redirected.
+func (r *Router) redirectPath(method, p string) (string, bool) {
+	if method == MethodConnect || strings.IndexByte(p, '\\') >= 0 {
+		return "", false
+	}
+
+	// With strict routing, "/users/" is redirected to "/users" and vice versa
+	if r.RedirectTrailingSlash && r.StrictRouting {
+		if trimmed := "/" + strings.TrimLeft(p, "/"); trimmed != "/" {
+			if toggled := toggleTrailingSlash(trimmed); isLocalPath(toggled) && r.pathMatches(method, toggled) {
+				return toggled, true
+			}
+		}
+	}
+
+	// Clean the path and look it up ignoring letter case
+	if r.RedirectFixedPath {
+		fixed, ok := r.lookupPath(method, cleanPath(p), r.RedirectTrailingSlash, true)
+		if ok && fixed != p && isLocalPath(fixed) {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+// isLocalPath reports whether a redirect location is a path of the same host,
+// one that doesn't start with "//" or "/\\".
+func isLocalPath(location string) bool {
+	if location == "" || location[0] != '/' {
+		return false
+	}
+	return len(location) == 1 || location[1] != '/' && location[1] != '\\'
+}
+
+// Pre-allocated handler for canonical path redirects
+// It uses 301 Moved Permanently for GET requests and 308 Permanent Redirect for other
+// methods, which must not change. The Location header is set before it is called.
+var redirectHandler = func(c *Ctx) {
+	status := StatusPermanentRedirect
+	if c.Method() == MethodGet {
+		status = StatusMovedPermanently
+	}
+	c.Status(status)
+	c.prepareResponse("")
+}
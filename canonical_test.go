@@ -0,0 +1,193 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCleanPath tests the canonical form of URL paths
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"users", "/users"},
+		{"//users", "/users"},
+		{"/users/", "/users/"},
+		{"//users/../users", "/users"},
+		{"/users/./1//", "/users/1/"},
+		{"/../..", "/"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, cleanPath(test.path), "cleanPath(%q)", test.path)
+	}
+}
+
+// TestRouterPathCanonicalisation tests trailing slash and fixed path handling
+func TestRouterPathCanonicalisation(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) { c.String("%s %s", c.Path(), c.Param("name")) }
+
+	router.GET("/users", handler)
+	router.GET("/docs/", handler)
+	router.GET("/users/:name", handler)
+	router.POST("/items", handler)
+	router.GET("/files/*", handler)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com/", nil)
+		req.URL.Path = path
+		req.URL.RawQuery = "page=2"
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Routing is strict and case sensitive by default
+	assert.Equal(StatusNotFound, serve(MethodGet, "/users/").Code, "paths with another trailing slash should be different routes")
+	assert.Equal(StatusNotFound, serve(MethodGet, "/docs").Code)
+	assert.Equal(StatusNotFound, serve(MethodGet, "/USERS").Code)
+
+	// With strict routing the path can be redirected to the route
+	router.RedirectTrailingSlash = true
+	w := serve(MethodGet, "/users/")
+	assert.Equal(StatusMovedPermanently, w.Code, "GET requests should be redirected with 301")
+	assert.Equal("/users?page=2", w.Header().Get(HeaderLocation), "the query should be kept")
+
+	w = serve(MethodGet, "/docs")
+	assert.Equal(StatusMovedPermanently, w.Code)
+	assert.Equal("/docs/?page=2", w.Header().Get(HeaderLocation))
+
+	w = serve(MethodPost, "/items/")
+	assert.Equal(StatusPermanentRedirect, w.Code, "other methods should be redirected with 308")
+	assert.Equal("/items?page=2", w.Header().Get(HeaderLocation))
+
+	// A trailing wildcard matches paths with a trailing slash
+	w = serve(MethodGet, "/files/a/")
+	assert.Equal(StatusOK, w.Code)
+
+	// Without strict routing the trailing slash is ignored
+	router.StrictRouting = false
+	assert.Equal(StatusOK, serve(MethodGet, "/users/").Code)
+	assert.Equal(StatusOK, serve(MethodGet, "/docs").Code)
+	assert.Equal(StatusOK, serve(MethodGet, "/users/bob/").Code)
+
+	router.StrictRouting = true
+	router.RedirectTrailingSlash = false
+
+	// Fixed paths are cleaned and looked up ignoring case
+	router.RedirectFixedPath = true
+	w = serve(MethodGet, "//users/../users")
+	assert.Equal(StatusMovedPermanently, w.Code)
+	assert.Equal("/users?page=2", w.Header().Get(HeaderLocation))
+
+	w = serve(MethodGet, "/USERS/Bob")
+	assert.Equal(StatusMovedPermanently, w.Code)
+	assert.Equal("/users/Bob?page=2", w.Header().Get(HeaderLocation), "parameter values should keep their case")
+
+	assert.Equal(StatusNotFound, serve(MethodGet, "/missing").Code)
+
+	// Case insensitive routing serves the route directly
+	router.RedirectFixedPath = false
+	router.CaseSensitive = false
+	w = serve(MethodGet, "/Users/Bob")
+	assert.Equal(StatusOK, w.Code)
+	assert.Equal("/Users/Bob Bob", w.Body.String(), "parameter values should keep their case")
+	assert.Equal(StatusOK, serve(MethodHead, "/FILES/a").Code, "HEAD requests should fall back to GET routes")
+	assert.Equal(StatusNotFound, serve(MethodGet, "/Users/Bob/extra").Code)
+}
+
+// TestRouterRedirectPathOpenRedirect tests that canonical path redirects stay on the host
+func TestRouterRedirectPathOpenRedirect(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.RedirectTrailingSlash = true
+	router.GET("/:name", func(c *Ctx) { c.String("%s", c.Param("name")) })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// "//evil.com" would be a URL of another host to browsers
+	w := serve("//evil.com/")
+	assert.Equal(StatusMovedPermanently, w.Code)
+	assert.Equal("/evil.com", w.Header().Get(HeaderLocation), "leading slashes should be collapsed")
+
+	w = serve("///evil.com/")
+	assert.Equal("/evil.com", w.Header().Get(HeaderLocation))
+
+	// Backslashes are read as slashes by browsers
+	w = serve("/\\evil.com/")
+	assert.Equal(StatusNotFound, w.Code)
+	assert.Empty(w.Header().Get(HeaderLocation))
+
+	router.RedirectFixedPath = true
+	w = serve("/\\evil.com/")
+	assert.Empty(w.Header().Get(HeaderLocation))
+
+	for _, location := range []string{"//evil.com", "/\\evil.com", "", "evil.com"} {
+		assert.False(isLocalPath(location), location)
+	}
+	assert.True(isLocalPath("/"))
+	assert.True(isLocalPath("/users"))
+}
+
+// TestStaticCanonicalPath tests serving static files for paths matched by canonical routing
+func TestStaticCanonicalPath(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	assert.NoError(os.Mkdir(filepath.Join(dir, "docs"), 0o755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "docs", "a.txt"), []byte("a"), 0o644))
+	fsys := fstest.MapFS{"docs/a.txt": {Data: []byte("a")}}
+
+	router := NewRouter()
+	router.STATIC("/static", dir)
+	router.STATIC("/embed", ".", Static{FS: fsys})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	for _, prefix := range []string{"/static", "/embed"} {
+		for _, path := range []string{prefix + "/docs/a.txt", prefix + "//docs/a.txt", prefix + "/docs//a.txt", "/" + prefix + "/docs/a.txt"} {
+			w := serve(path)
+			assert.Equal(StatusOK, w.Code, path)
+			assert.Equal("a", w.Body.String(), path)
+		}
+	}
+
+	// The prefix matched ignoring case is not part of the file path
+	router.CaseSensitive = false
+	for _, path := range []string{"/Static/docs/a.txt", "/EMBED/docs/a.txt"} {
+		w := serve(path)
+		assert.Equal(StatusOK, w.Code, path)
+		assert.Equal("a", w.Body.String(), path)
+	}
+}
@@ -0,0 +1,278 @@
+package ngebut
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/goccy/go-json"
+)
+
+// Codec encodes and decodes the bodies of a media type.
+// Codecs are registered with RegisterCodec, and used by the response helpers,
+// such as JSON, XML and Encode, and by binding, such as BindJSON, BindXML and Decode.
+type Codec interface {
+	// Marshal returns the encoding of v.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into the value pointed to by v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// CodecFuncs is a Codec made of a marshal and an unmarshal function.
+type CodecFuncs struct {
+	MarshalFunc   func(v interface{}) ([]byte, error)
+	UnmarshalFunc func(data []byte, v interface{}) error
+}
+
+// Marshal calls MarshalFunc.
+func (f CodecFuncs) Marshal(v interface{}) ([]byte, error) {
+	return f.MarshalFunc(v)
+}
+
+// Unmarshal calls UnmarshalFunc.
+func (f CodecFuncs) Unmarshal(data []byte, v interface{}) error {
+	return f.UnmarshalFunc(data, v)
+}
+
+// ErrUnsupportedMediaType is returned by Ctx.Decode and Ctx.Encode when no codec
+// is registered for the media type.
+var ErrUnsupportedMediaType = NewHttpError(StatusUnsupportedMediaType, "Unsupported Media Type")
+
+// Built-in codecs, the JSON codec is also the default of Config.JSONEncoder and Config.JSONDecoder
+var (
+	jsonCodec Codec = CodecFuncs{MarshalFunc: json.Marshal, UnmarshalFunc: json.Unmarshal}
+	xmlCodec  Codec = CodecFuncs{MarshalFunc: xml.Marshal, UnmarshalFunc: xml.Unmarshal}
+)
+
+// codecs holds the registered codecs by media type, replaced as a whole on registration
+// so that lookups don't lock
+var (
+	codecs   atomic.Pointer[map[string]Codec]
+	codecsMu sync.Mutex
+
+	// jsonCodecRegistered reports whether the JSON codec was replaced, which disables
+	// the fast paths of Ctx.JSON
+	jsonCodecRegistered atomic.Bool
+)
+
+func init() {
+	codecs.Store(&map[string]Codec{
+		MIMEApplicationJSON: jsonCodec,
+		MIMEApplicationXML:  xmlCodec,
+		MIMETextXML:         xmlCodec,
+	})
+}
+
+// RegisterCodec registers the codec of a media type, such as "application/msgpack",
+// replacing the codec registered for it, if any. JSON and XML codecs are registered
+// for "application/json", "application/xml" and "text/xml".
+//
+// Codecs are usually registered at startup, before the server handles requests.
+func RegisterCodec(mediaType string, codec Codec) {
+	mediaType, _ = parseMediaType(mediaType)
+	if mediaType == "" || codec == nil {
+		panic("codec must have a media type")
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	registered := make(map[string]Codec, len(*codecs.Load())+1)
+	for k, v := range *codecs.Load() {
+		registered[k] = v
+	}
+	registered[mediaType] = codec
+	codecs.Store(&registered)
+
+	if mediaType == MIMEApplicationJSON {
+		jsonCodecRegistered.Store(true)
+	}
+}
+
+// LookupCodec returns the codec registered for the media type of a Content-Type
+// header value, such as "application/json; charset=utf-8". Media types with a
+// "+json" or "+xml" structured syntax suffix use the JSON or XML codec unless
+// they have a codec of their own.
+func LookupCodec(contentType string) (Codec, bool) {
+	mediaType, _ := parseMediaType(contentType)
+	registered := *codecs.Load()
+	if codec, ok := registered[mediaType]; ok {
+		return codec, true
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		codec, ok := registered[MIMEApplicationJSON]
+		return codec, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		codec, ok := registered[MIMEApplicationXML]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// newJSONCodec returns the JSON codec of the server configuration, or nil when it uses
+// the registered JSON codec. When only one of encoder and decoder is set, the other
+// direction uses the JSON codec registered at the time of the call.
+func newJSONCodec(encoder func(v interface{}) ([]byte, error), decoder func(data []byte, v interface{}) error) Codec {
+	if encoder == nil && decoder == nil {
+		return nil
+	}
+	if encoder == nil {
+		encoder = func(v interface{}) ([]byte, error) {
+			return registeredJSONCodec().Marshal(v)
+		}
+	}
+	if decoder == nil {
+		decoder = func(data []byte, v interface{}) error {
+			return registeredJSONCodec().Unmarshal(data, v)
+		}
+	}
+	return CodecFuncs{MarshalFunc: encoder, UnmarshalFunc: decoder}
+}
+
+// registeredJSONCodec returns the codec registered for "application/json".
+func registeredJSONCodec() Codec {
+	codec, _ := LookupCodec(MIMEApplicationJSON)
+	return codec
+}
+
+// codec returns the codec of a media type for the request, which is the JSON codec
+// of the server configuration for JSON.
+func (c *Ctx) codec(contentType string) (Codec, bool) {
+	if c.jsonCodec != nil {
+		if mediaType, _ := parseMediaType(contentType); mediaType == MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json") {
+			return c.jsonCodec, true
+		}
+	}
+	return LookupCodec(contentType)
+}
+
+// Encode sends a response with the given content type, encoding obj with the codec
+// registered for its media type. The response isn't written when there is no such
+// codec, which is reported with ErrUnsupportedMediaType, or when encoding fails.
+//
+// Example:
+//
+//	if err := c.Encode("application/msgpack", user); err != nil {
+//		c.Error(err)
+//	}
+func (c *Ctx) Encode(contentType string, obj interface{}) error {
+	codec, ok := c.codec(contentType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+
+	data, err := codec.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	c.Data(contentType, data)
+	return nil
+}
+
+// Decode decodes the request body into obj with the codec registered for the media
+// type of the Content-Type header, and returns ErrUnsupportedMediaType when there is none.
+func (c *Ctx) Decode(obj interface{}) error {
+	contentType := c.Request.Header.Get(HeaderContentType)
+	codec, ok := c.codec(contentType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+
+	if c.Request.Body == nil {
+		return errors.New("request body is nil")
+	}
+	return codec.Unmarshal(c.Request.Body, obj)
+}
+
+// XML sends an XML response by encoding the provided object with the codec registered
+// for "application/xml". It sets the Content-Type header to "application/xml; charset=utf-8".
+//
+// Parameters:
+//   - obj: The object to be encoded to XML
+//
+// Note: This method writes the response immediately and sets the status code.
+func (c *Ctx) XML(obj interface{}) {
+	codec, _ := c.codec(MIMEApplicationXML)
+	data, err := codec.Marshal(obj)
+	if err != nil {
+		c.Error(fmt.Errorf("XML encoding error: %w", err))
+		return
+	}
+
+	c.Data(MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), data...))
+}
+
+// BindXML unmarshals the XML data from the request body into the provided object,
+// with the codec registered for "application/xml".
+//
+// Parameters:
+//   - obj: The object to unmarshal the XML data into
+//
+// Returns:
+//   - An error if the request body is nil or if unmarshaling fails
+//   - nil if successful
+func (c *Ctx) BindXML(obj interface{}) error {
+	if c.Request.Body == nil {
+		return errors.New("request body is nil")
+	}
+
+	codec, _ := c.codec(MIMEApplicationXML)
+	if err := codec.Unmarshal(c.Request.Body, obj); err != nil {
+		return fmt.Errorf("failed to unmarshal XML: %w", err)
+	}
+
+	return nil
+}
+
+// jsonpCallbackPattern matches JSONP callback names, JavaScript identifiers separated by dots
+var jsonpCallbackPattern = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+// ErrInvalidCallback is the error of JSONP responses whose callback name is not valid.
+var ErrInvalidCallback = NewHttpError(StatusBadRequest, "invalid JSONP callback")
+
+// JSONP sends a JSONP response, calling the JavaScript function named callback with the
+// JSON encoding of obj. The callback usually comes from the query, as in c.Query("callback").
+// It sends a plain JSON response when callback is empty.
+//
+// Callback names must be JavaScript identifiers, optionally separated by dots, such as
+// "handle" or "app.handle"; other names make the request fail with ErrInvalidCallback,
+// so that they can't inject script.
+func (c *Ctx) JSONP(obj interface{}, callback string) {
+	if callback == "" {
+		c.JSON(obj)
+		return
+	}
+	if len(callback) > 128 || !jsonpCallbackPattern.MatchString(callback) {
+		c.Error(ErrInvalidCallback)
+		return
+	}
+
+	codec, _ := c.codec(MIMEApplicationJSON)
+	data, err := codec.Marshal(obj)
+	if err != nil {
+		c.Error(jsonEncodingErr)
+		return
+	}
+
+	// The comment keeps the response from starting with attacker-controlled bytes
+	buf := bufferPool.Get()
+	buf.WriteString("/**/ typeof ")
+	buf.WriteString(callback)
+	buf.WriteString(` === "function" && `)
+	buf.WriteString(callback)
+	buf.WriteByte('(')
+	buf.Write(data)
+	buf.WriteString(");")
+
+	c.Set(HeaderXContentTypeOptions, "nosniff")
+	c.Data(MIMETextJavaScriptCharsetUTF8, buf.B)
+	bufferPool.Put(buf)
+}
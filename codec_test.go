@@ -0,0 +1,199 @@
+package ngebut
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codecUser struct {
+	XMLName struct{} `json:"-" xml:"user"`
+	ID      int      `json:"id" xml:"id,attr"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+// TestCtxXML tests XML responses and binding
+func TestCtxXML(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	router.GET("/user", func(c *Ctx) { c.XML(codecUser{ID: 1, Name: "Ana"}) })
+	router.POST("/user", func(c *Ctx) {
+		var user codecUser
+		if err := c.BindXML(&user); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(user)
+	})
+
+	req, _ := http.NewRequest(MethodGet, "http://example.com/user", nil)
+	w := httptest.NewRecorder()
+	ctx := GetContext(w, req)
+	router.ServeHTTP(ctx, ctx.Request)
+	ctx.Writer.Flush()
+	ReleaseContext(ctx)
+
+	assert.Equal(MIMEApplicationXMLCharsetUTF8, w.Header().Get(HeaderContentType))
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<user id="1"><name>Ana</name></user>`, w.Body.String())
+
+	req, _ = http.NewRequest(MethodPost, "http://example.com/user", strings.NewReader(`<user id="2"><name>Budi</name></user>`))
+	req.Header.Set(HeaderContentType, MIMEApplicationXML)
+	w = httptest.NewRecorder()
+	ctx = GetContext(w, req)
+	router.ServeHTTP(ctx, ctx.Request)
+	ctx.Writer.Flush()
+	assert.NoError(ctx.GetError())
+	ReleaseContext(ctx)
+
+	assert.Equal(`{"id":2,"name":"Budi"}`, w.Body.String())
+}
+
+// TestCtxJSONP tests JSONP responses and callback validation
+func TestCtxJSONP(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.GET("/user", func(c *Ctx) { c.JSONP(map[string]string{"name": "Ana\u2028"}, c.Query("callback")) })
+
+	serve := func(target string) (*httptest.ResponseRecorder, error) {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+target, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w, ctx.GetError()
+	}
+
+	w, err := serve("/user?callback=app.handle")
+	assert.NoError(err)
+	assert.Equal(MIMETextJavaScriptCharsetUTF8, w.Header().Get(HeaderContentType))
+	assert.Equal("nosniff", w.Header().Get(HeaderXContentTypeOptions))
+	assert.Equal(`/**/ typeof app.handle === "function" && app.handle({"name":"Ana\u2028"});`, w.Body.String())
+
+	w, err = serve("/user")
+	assert.NoError(err)
+	assert.Equal(`{"name":"Ana\u2028"}`, w.Body.String(), "JSON should be sent without a callback")
+
+	for _, callback := range []string{"alert(1)//", "a-b", "1abc", "a..b", "<script>"} {
+		_, err = serve("/user?callback=" + callback)
+		assert.ErrorIs(err, ErrInvalidCallback, callback)
+	}
+}
+
+// TestCodecs tests registering codecs and replacing the JSON codec in the configuration
+func TestCodecs(t *testing.T) {
+	assert := assert.New(t)
+
+	// A codec of its own media type
+	RegisterCodec("text/csv", CodecFuncs{
+		MarshalFunc: func(v interface{}) ([]byte, error) {
+			return []byte(strings.Join(v.([]string), ",")), nil
+		},
+		UnmarshalFunc: func(data []byte, v interface{}) error {
+			*v.(*[]string) = strings.Split(string(data), ",")
+			return nil
+		},
+	})
+	codec, ok := LookupCodec("text/csv; charset=utf-8")
+	assert.True(ok)
+	assert.NotNil(codec)
+	_, ok = LookupCodec("application/problem+json")
+	assert.True(ok, "+json media types should use the JSON codec")
+	_, ok = LookupCodec("application/msgpack")
+	assert.False(ok)
+
+	// The JSON codec of the configuration, with deterministic indented output
+	server := New(Config{
+		ErrorHandler: defaultErrorHandler,
+		JSONEncoder: func(v interface{}) ([]byte, error) {
+			return json.MarshalIndent(v, "", "  ")
+		},
+		JSONDecoder: func(data []byte, v interface{}) error {
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			return decoder.Decode(v)
+		},
+	})
+	server.POST("/users", func(c *Ctx) {
+		var user codecUser
+		if err := c.BindJSON(&user); err != nil {
+			c.Status(StatusBadRequest)
+			c.String("%v", err)
+			return
+		}
+		c.JSON(user)
+	})
+	server.POST("/decode", func(c *Ctx) {
+		var values []string
+		if err := c.Decode(&values); err != nil {
+			c.Error(err)
+			return
+		}
+		if err := c.Encode("text/csv", append(values, "d")); err != nil {
+			c.Error(err)
+		}
+	})
+
+	serve := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(MethodPost, "http://example.com"+path, strings.NewReader(body))
+		req.Header.Set(HeaderContentType, contentType)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/users", MIMEApplicationJSON, `{"id":3,"name":"Citra"}`)
+	assert.Equal("{\n  \"id\": 3,\n  \"name\": \"Citra\"\n}", w.Body.String())
+	w = serve("/users", MIMEApplicationJSON, `{"id":3,"extra":true}`)
+	assert.Equal(StatusBadRequest, w.Code)
+	assert.Contains(w.Body.String(), "unknown field")
+
+	w = serve("/decode", "text/csv", "a,b,c")
+	assert.Equal("a,b,c,d", w.Body.String())
+	assert.Equal("text/csv", w.Header().Get(HeaderContentType))
+	assert.Equal(StatusUnsupportedMediaType, serve("/decode", "application/msgpack", "").Code)
+}
+
+// TestNewJSONCodecFallback tests that the JSON codec of a configuration with only an
+// encoder or a decoder uses the registered JSON codec for the other direction
+func TestNewJSONCodecFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	var marshals, unmarshals int
+	RegisterCodec(MIMEApplicationJSON, CodecFuncs{
+		MarshalFunc: func(v interface{}) ([]byte, error) {
+			marshals++
+			return jsonCodec.Marshal(v)
+		},
+		UnmarshalFunc: func(data []byte, v interface{}) error {
+			unmarshals++
+			return jsonCodec.Unmarshal(data, v)
+		},
+	})
+	defer func() {
+		RegisterCodec(MIMEApplicationJSON, jsonCodec)
+		jsonCodecRegistered.Store(false)
+	}()
+
+	assert.Nil(newJSONCodec(nil, nil), "the registered codec should be used without a configuration")
+
+	codec := newJSONCodec(json.Marshal, nil)
+	var user codecUser
+	assert.NoError(codec.Unmarshal([]byte(`{"id":1,"name":"Ayu"}`), &user))
+	assert.Equal(1, unmarshals, "decoding should use the registered codec")
+	_, err := codec.Marshal(user)
+	assert.NoError(err)
+	assert.Equal(0, marshals, "encoding should use the configured encoder")
+
+	codec = newJSONCodec(nil, json.Unmarshal)
+	data, err := codec.Marshal(user)
+	assert.NoError(err)
+	assert.JSONEq(`{"id":1,"name":"Ayu"}`, string(data))
+	assert.Equal(1, marshals, "encoding should use the registered codec")
+}
@@ -1,6 +1,9 @@
 package ngebut
 
-import "time"
+import (
+	"io/fs"
+	"time"
+)
 
 // Config represents server configuration options.
 type Config struct {
@@ -16,8 +19,33 @@ type Config struct {
 	// DisableStartupMessage determines whether to print the startup message when the server starts.
 	DisableStartupMessage bool
 
+	// PrintRoutes determines whether to print the route table in the startup message.
+	PrintRoutes bool
+
 	// ErrorHandler is called when an error occurs during request processing.
 	ErrorHandler Handler
+
+	// StrictRegistration makes route conflicts, such as "/users/:id" and "/users/:name" registered
+	// for the same method, fail Listen with an error instead of panicking at registration.
+	StrictRegistration bool
+
+	// Prefork enables the use of multiple child processes listening on the same port via SO_REUSEPORT.
+	// The master process re-executes the binary, restarts children that crash and forwards shutdown signals.
+	// Crashed children are restarted with an increasing delay, and Listen returns an error when they keep
+	// crashing. Children that don't exit within 10 seconds of a shutdown signal are killed.
+	Prefork bool
+
+	// PreforkProcesses is the number of child processes started in prefork mode.
+	// Zero means runtime.GOMAXPROCS(0).
+	PreforkProcesses int
+
+	// JSONEncoder encodes the JSON responses of Ctx.JSON, Ctx.JSONP and Ctx.Encode,
+	// replacing the JSON codec registered with RegisterCodec. Nil means the registered codec.
+	JSONEncoder func(v interface{}) ([]byte, error)
+
+	// JSONDecoder decodes the JSON request bodies of Ctx.BindJSON and Ctx.Decode,
+	// replacing the JSON codec registered with RegisterCodec. Nil means the registered codec.
+	JSONDecoder func(data []byte, v interface{}) error
 }
 
 // DefaultConfig returns a default server configuration with pre-configured timeouts
@@ -27,24 +55,37 @@ type Config struct {
 // - WriteTimeout: 10 seconds
 // - IdleTimeout: 15 seconds
 // - DisableStartupMessage: false
+// - PrintRoutes: false
 // - ErrorHandler: default error handler
+// - StrictRegistration: false
+// - Prefork: false
 func DefaultConfig() Config {
 	return Config{
 		ReadTimeout:           5 * time.Second,
 		WriteTimeout:          10 * time.Second,
 		IdleTimeout:           15 * time.Second,
 		DisableStartupMessage: false,
+		PrintRoutes:           false,
 		ErrorHandler:          defaultErrorHandler,
+		StrictRegistration:    false,
+		Prefork:               false,
 	}
 }
 
 // Static defines configuration options when defining static assets.
 type Static struct {
+	// FS is the file system to serve the files from, such as an embed.FS, instead of
+	// the OS file system. The root passed to STATIC is then a directory of FS, "." for
+	// its root. Caching, byte ranges, browsing and index files work the same way.
+	// Optional. Default: nil
+	FS fs.FS `json:"-"`
+
 	// When set to true, the server tries minimizing CPU usage by caching compressed files.
 	// Optional. Default value false
 	Compress bool `json:"compress"`
 
 	// When set to true, enables byte range requests.
+	// Requests for several ranges get a multipart/byteranges response.
 	// Optional. Default value false
 	ByteRange bool `json:"byte_range"`
 
@@ -110,3 +151,37 @@ var DefaultStaticConfig = Static{
 	MaxCacheItems:  1000,              // 1000 files
 	ModifyResponse: nil,
 }
+
+// SendFile defines configuration options when sending a file with Ctx.SendFile.
+type SendFile struct {
+	// FS is the file system to read the file from, such as an embed.FS, instead of
+	// the OS file system. The files of FS are not cached in memory.
+	// Optional. Default: nil
+	FS fs.FS `json:"-"`
+
+	// When set to true, enables byte range requests.
+	// Optional. Default value false
+	ByteRange bool `json:"byte_range"`
+
+	// When set to true, sends the file as an attachment named after the file.
+	// Optional. Default value false.
+	Download bool `json:"download"`
+
+	// The value for the Cache-Control HTTP-header
+	// that is set on the file response. MaxAge is defined in seconds.
+	//
+	// Optional. Default value 0.
+	MaxAge int `json:"max_age"`
+
+	// When set to true, enables in-memory caching of file contents.
+	// Optional. Default value false.
+	InMemoryCache bool `json:"in_memory_cache"`
+
+	// Maximum size of the in-memory cache in bytes.
+	// Optional. Default value 100MB.
+	MaxCacheSize int64 `json:"max_cache_size"`
+
+	// Maximum number of files to store in the in-memory cache.
+	// Optional. Default value 1000.
+	MaxCacheItems int `json:"max_cache_items"`
+}
@@ -14,6 +14,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, config.IdleTimeout, 15*time.Second, "DefaultConfig().IdleTimeout should be 15 seconds")
 	assert.Equal(t, config.DisableStartupMessage, false, "DefaultConfig().DisableStartupMessage should be false")
 	assert.NotNil(t, config.ErrorHandler, "DefaultConfig().ErrorHandler should not be nil")
+	assert.False(t, config.Prefork, "DefaultConfig().Prefork should be false")
 }
 
 // TestConfigZeroValues tests that a zero-value Config has zero values for all fields
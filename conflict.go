@@ -0,0 +1,125 @@
+package ngebut
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/ryanbekhen/ngebut/internal/radix"
+)
+
+// ErrRouteConflict is matched by the errors of conflicting route registrations.
+var ErrRouteConflict = errors.New("route conflict")
+
+// RouteConflictError describes a route registration that conflicts with an existing route.
+// Two routes conflict when they have the same method and match the same paths, such as
+// "/users/:id" and "/users/:name". Parameters with different constraints don't conflict.
+type RouteConflictError struct {
+	Method         string
+	Pattern        string
+	Source         string // file:line of the conflicting registration
+	ExistingRoute  string // Pattern of the existing route
+	ExistingSource string // file:line of the registration of the existing route
+}
+
+// Error implements the error interface.
+func (e *RouteConflictError) Error() string {
+	return fmt.Sprintf("%s: %s %s registered at %s conflicts with %s %s registered at %s",
+		ErrRouteConflict, e.Method, e.Pattern, e.Source, e.Method, e.ExistingRoute, e.ExistingSource)
+}
+
+// Is reports whether target is ErrRouteConflict.
+func (e *RouteConflictError) Is(target error) bool {
+	return target == ErrRouteConflict
+}
+
+// routeShape returns the paths a pattern matches, in a form where patterns matching
+// overlapping paths are equal: parameter names are left out, keeping their constraints.
+func routeShape(pattern string) string {
+	var sb strings.Builder
+	for _, segment := range radix.ParsePattern(pattern) {
+		sb.WriteByte('/')
+		switch {
+		case segment.Kind == radix.Param:
+			sb.WriteByte(':')
+			sb.WriteString(segment.Constraint)
+		case segment.Kind == radix.Wildcard:
+			// "*" and "+" both match every path with at least one segment
+			sb.WriteByte('*')
+		default:
+			sb.WriteString(segment.Path)
+		}
+	}
+	if sb.Len() == 0 || hasTrailingSlash(pattern) {
+		sb.WriteByte('/')
+	}
+	return sb.String()
+}
+
+// findConflict returns the error describing the conflict of a new route with a route
+// registered for the same method, or nil if there is none.
+func (r *Router) findConflict(method, pattern, source string) *RouteConflictError {
+	shapes := r.routeShapes[method]
+	for _, variant := range radix.ExpandOptional(pattern) {
+		if index, exists := shapes[routeShape(variant)]; exists {
+			existing := r.Routes[index]
+			return &RouteConflictError{
+				Method:         method,
+				Pattern:        pattern,
+				Source:         source,
+				ExistingRoute:  existing.Pattern,
+				ExistingSource: existing.Source,
+			}
+		}
+	}
+	return nil
+}
+
+// addRouteShapes records the paths matched by the route at index of r.Routes.
+func (r *Router) addRouteShapes(index int) {
+	rt := &r.Routes[index]
+	if r.routeShapes[rt.Method] == nil {
+		r.routeShapes[rt.Method] = make(map[string]int)
+	}
+	for _, variant := range radix.ExpandOptional(rt.Pattern) {
+		r.routeShapes[rt.Method][routeShape(variant)] = index
+	}
+}
+
+// Err returns the route conflicts found by Handle with StrictRegistration enabled,
+// including those of its host, mounted and version routers, joined into one error, or nil if
+// there were none.
+func (r *Router) Err() error {
+	errs := append([]error(nil), r.registrationErrs...)
+	for _, h := range r.hosts {
+		errs = append(errs, h.router.Err())
+	}
+	for _, m := range r.mounts {
+		errs = append(errs, m.router.Err())
+	}
+	for _, v := range r.versions {
+		errs = append(errs, v.router.Err())
+	}
+	return errors.Join(errs...)
+}
+
+// packagePrefix is the prefix of the names of the functions of this package
+var packagePrefix = strings.TrimSuffix(funcName(NewRouter), "NewRouter")
+
+// callerSource returns the file:line of the first caller outside this package,
+// which is where a route was registered.
+func callerSource() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ngebut
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// registerConflict registers a route and returns the panic value, if any
+func registerConflict(router *Router, method, pattern string) (recovered interface{}) {
+	defer func() { recovered = recover() }()
+	router.Handle(pattern, method, func(c *Ctx) {})
+	return nil
+}
+
+// TestRouterConflicts tests route conflict detection
+func TestRouterConflicts(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) {}
+
+	router.GET("/users", handler)
+	router.GET("/users/:id", handler)
+	router.GET("/files/:name?", handler)
+	router.GET("/assets/*", handler)
+
+	tests := []struct {
+		method   string
+		pattern  string
+		conflict bool
+	}{
+		{MethodGet, "/users", true},
+		{MethodGet, "/users/:name", true},
+		{MethodGet, "/files", true},
+		{MethodGet, "/assets/*path", true},
+		{MethodPost, "/users/:name", false},
+		{MethodGet, "/users/", false},
+		{MethodGet, "/users/new", false},
+		{MethodGet, "/users/:id<int>", false},
+		{MethodGet, "/users/:id/posts", false},
+		{MethodGet, "/assets/+", true},
+	}
+
+	for _, test := range tests {
+		recovered := registerConflict(router, test.method, test.pattern)
+		if !test.conflict {
+			assert.Nil(recovered, "%s %s should not conflict", test.method, test.pattern)
+			continue
+		}
+
+		err, ok := recovered.(*RouteConflictError)
+		if assert.True(ok, "%s %s should panic with a RouteConflictError", test.method, test.pattern) {
+			assert.ErrorIs(err, ErrRouteConflict)
+			assert.Contains(err.Error(), "conflict_test.go:", "the message should name the source of both routes")
+			assert.Contains(err.Source, "conflict_test.go:")
+			assert.Contains(err.ExistingSource, "conflict_test.go:")
+		}
+	}
+}
+
+// TestRouterStrictRegistration tests recording conflicts instead of panicking
+func TestRouterStrictRegistration(t *testing.T) {
+	router := NewRouter()
+	router.StrictRegistration = true
+	router.GET("/users/:id", func(c *Ctx) {})
+	assert.NotPanics(t, func() { router.GET("/users/:name", func(c *Ctx) {}) })
+	assert.Len(t, router.Routes, 1, "the conflicting route should not be registered")
+
+	var conflict *RouteConflictError
+	assert.True(t, errors.As(router.Err(), &conflict))
+	assert.Equal(t, "/users/:name", conflict.Pattern)
+	assert.Equal(t, "/users/:id", conflict.ExistingRoute)
+
+	// A skipped route is not named, and the previous route keeps its name
+	router.GET("/a", func(c *Ctx) {}).Name("a")
+	assert.NotPanics(t, func() { router.GET("/a", func(c *Ctx) {}).Name("dup") })
+	_, err := router.URL("dup", nil, nil)
+	assert.ErrorIs(t, err, ErrRouteNotFound, "the name of a skipped route should not be given to another route")
+	path, err := router.URL("a", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/a", path)
+
+	server := New(Config{StrictRegistration: true, DisableStartupMessage: true})
+	server.GET("/", func(c *Ctx) {})
+	server.GET("/", func(c *Ctx) {})
+	assert.ErrorIs(t, server.Listen(":0"), ErrRouteConflict, "Listen should refuse to start")
+}
+
+// TestRouterPriority tests that static segments take precedence over parameters and wildcards
+func TestRouterPriority(t *testing.T) {
+	router := NewRouter()
+	router.GET("/users/*", func(c *Ctx) { c.String("wildcard") })
+	router.GET("/users/:id", func(c *Ctx) { c.String("param") })
+	router.GET("/users/new", func(c *Ctx) { c.String("static") })
+
+	tests := map[string]string{
+		"/users/new":   "static",
+		"/users/1":     "param",
+		"/users/1/bio": "wildcard",
+	}
+	for path, expected := range tests {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		ReleaseContext(ctx)
+		assert.Equal(t, expected, w.Body.String(), "route serving %s", path)
+	}
+}
@@ -181,6 +181,9 @@ const (
 	HeaderSecWebSocketVersion    = "Sec-WebSocket-Version"
 
 	// Other.
+	HeaderAcceptVersion       = "Accept-Version"
+	HeaderDeprecation         = "Deprecation"
+	HeaderSunset              = "Sunset"
 	HeaderAcceptPatch         = "Accept-Patch"
 	HeaderAcceptPushPolicy    = "Accept-Push-Policy"
 	HeaderAcceptSignature     = "Accept-Signature"
@@ -0,0 +1,200 @@
+package ngebut
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Route parameters can be constrained by appending a constraint to the parameter name,
+// for example "/users/:id<int>". A segment that does not satisfy the constraint does not
+// match the route, so the request falls through to other routes or to NotFound.
+//
+// Several constraints can be combined with a semicolon, e.g. ":id<int;min(1)>".
+// The supported constraints are:
+//
+//   - int: a signed integer
+//   - bool: a boolean as accepted by strconv.ParseBool
+//   - float: a floating point number
+//   - alpha: ASCII letters only
+//   - uuid, guid: a UUID such as 123e4567-e89b-12d3-a456-426614174000
+//   - datetime(layout): a time in the given time.Parse layout
+//   - regex(expr): a value fully matched by the regular expression
+//   - minLen(n), maxLen(n), len(n), betweenLen(min,max): length in characters
+//   - min(n), max(n), range(min,max): an integer in the given range
+//
+// Constraints apply to a single path segment and cannot contain a slash.
+
+// uuidRegex matches UUIDs in their canonical textual representation
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// compileConstraint compiles a constraint expression such as "int;min(1)" into a function
+// that reports whether a parameter value satisfies it.
+// It panics if the expression is invalid, like an invalid route pattern.
+func compileConstraint(expr string) func(value string) bool {
+	parts := splitConstraints(expr)
+	checks := make([]func(string) bool, 0, len(parts))
+	for _, part := range parts {
+		check, err := compileSingleConstraint(part)
+		if err != nil {
+			panic(fmt.Sprintf("invalid route constraint %q: %v", expr, err))
+		}
+		checks = append(checks, check)
+	}
+
+	if len(checks) == 1 {
+		return checks[0]
+	}
+	return func(value string) bool {
+		for _, check := range checks {
+			if !check(value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// splitConstraints splits a constraint expression on semicolons outside of parentheses.
+func splitConstraints(expr string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ';':
+			if depth == 0 {
+				parts = append(parts, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// compileSingleConstraint compiles a single constraint such as "int" or "min(1)".
+func compileSingleConstraint(constraint string) (func(string) bool, error) {
+	name, arg := constraint, ""
+	if i := strings.IndexByte(constraint, '('); i >= 0 {
+		if constraint[len(constraint)-1] != ')' {
+			return nil, fmt.Errorf("missing closing parenthesis in %q", constraint)
+		}
+		name, arg = constraint[:i], constraint[i+1:len(constraint)-1]
+	}
+
+	switch name {
+	case "int":
+		return func(v string) bool {
+			_, err := strconv.ParseInt(v, 10, 64)
+			return err == nil
+		}, nil
+	case "bool":
+		return func(v string) bool {
+			_, err := strconv.ParseBool(v)
+			return err == nil
+		}, nil
+	case "float":
+		return func(v string) bool {
+			_, err := strconv.ParseFloat(v, 64)
+			return err == nil
+		}, nil
+	case "alpha":
+		return func(v string) bool {
+			for i := 0; i < len(v); i++ {
+				if c := v[i] | 0x20; c < 'a' || c > 'z' {
+					return false
+				}
+			}
+			return len(v) > 0
+		}, nil
+	case "uuid", "guid":
+		return uuidRegex.MatchString, nil
+	case "datetime":
+		if arg == "" {
+			return nil, fmt.Errorf("datetime requires a layout")
+		}
+		return func(v string) bool {
+			_, err := time.Parse(arg, v)
+			return err == nil
+		}, nil
+	case "regex":
+		re, err := regexp.Compile("^(?:" + arg + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case "minLen", "maxLen", "len":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s requires an integer", name)
+		}
+		switch name {
+		case "minLen":
+			return func(v string) bool { return utf8.RuneCountInString(v) >= n }, nil
+		case "maxLen":
+			return func(v string) bool { return utf8.RuneCountInString(v) <= n }, nil
+		default:
+			return func(v string) bool { return utf8.RuneCountInString(v) == n }, nil
+		}
+	case "betweenLen":
+		lo, hi, err := parseConstraintRange(arg)
+		if err != nil {
+			return nil, fmt.Errorf("betweenLen requires two integers")
+		}
+		return func(v string) bool {
+			n := int64(utf8.RuneCountInString(v))
+			return n >= lo && n <= hi
+		}, nil
+	case "min", "max":
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s requires an integer", name)
+		}
+		if name == "min" {
+			return func(v string) bool {
+				i, err := strconv.ParseInt(v, 10, 64)
+				return err == nil && i >= n
+			}, nil
+		}
+		return func(v string) bool {
+			i, err := strconv.ParseInt(v, 10, 64)
+			return err == nil && i <= n
+		}, nil
+	case "range":
+		lo, hi, err := parseConstraintRange(arg)
+		if err != nil {
+			return nil, fmt.Errorf("range requires two integers")
+		}
+		return func(v string) bool {
+			i, err := strconv.ParseInt(v, 10, 64)
+			return err == nil && i >= lo && i <= hi
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown constraint %q", name)
+}
+
+// parseConstraintRange parses the "min,max" argument of range constraints.
+func parseConstraintRange(arg string) (int64, int64, error) {
+	first, second, ok := strings.Cut(arg, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q", arg)
+	}
+	lo, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, err := strconv.ParseInt(strings.TrimSpace(second), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lo, hi, nil
+}
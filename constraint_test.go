@@ -0,0 +1,56 @@
+package ngebut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompileConstraint tests the supported route parameter constraints
+func TestCompileConstraint(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		valid      []string
+		invalid    []string
+	}{
+		{"int", []string{"42", "-7"}, []string{"abc", "4.2", ""}},
+		{"bool", []string{"true", "0"}, []string{"yes"}},
+		{"float", []string{"4.2", "1e3"}, []string{"x"}},
+		{"alpha", []string{"abc", "XyZ"}, []string{"ab1", ""}},
+		{"uuid", []string{"123e4567-e89b-12d3-a456-426614174000"}, []string{"123e4567", "not-a-uuid"}},
+		{"datetime(2006-01-02)", []string{"2024-02-29"}, []string{"2023-02-29", "02-29-2024"}},
+		{"regex([a-z-]+)", []string{"hello-world"}, []string{"Hello", "a_b"}},
+		{"regex(\\d{2}|x)", []string{"12", "x"}, []string{"123", "12x"}},
+		{"minLen(3)", []string{"abc", "héllo"}, []string{"ab"}},
+		{"maxLen(3)", []string{"abc", "hé"}, []string{"abcd"}},
+		{"len(2)", []string{"ab"}, []string{"a", "abc"}},
+		{"betweenLen(2,3)", []string{"ab", "abc"}, []string{"a", "abcd"}},
+		{"min(5)", []string{"5", "10"}, []string{"4", "x"}},
+		{"max(5)", []string{"5", "-1"}, []string{"6"}},
+		{"range(1, 10)", []string{"1", "10"}, []string{"0", "11"}},
+		{"int;min(1)", []string{"1"}, []string{"0", "x"}},
+	}
+
+	for _, tc := range testCases {
+		check := compileConstraint(tc.constraint)
+		for _, v := range tc.valid {
+			assert.True(t, check(v), "%s should accept %q", tc.constraint, v)
+		}
+		for _, v := range tc.invalid {
+			assert.False(t, check(v), "%s should reject %q", tc.constraint, v)
+		}
+	}
+}
+
+// TestCompileConstraintInvalid tests that invalid constraints panic at registration
+func TestCompileConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{"unknown", "min(x)", "range(1)", "regex([)", "datetime()", "len(1"} {
+		assert.Panics(t, func() {
+			compileConstraint(constraint)
+		}, "%s should panic", constraint)
+	}
+
+	assert.Panics(t, func() {
+		NewRouter().GET("/users/:id<nope>", func(c *Ctx) {})
+	}, "registering a route with an invalid constraint should panic")
+}
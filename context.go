@@ -8,8 +8,11 @@ import (
 	"github.com/ryanbekhen/ngebut/internal/unsafe"
 	"github.com/valyala/bytebufferpool"
 	"github.com/valyala/fastjson"
+	"math"
 	"net"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,6 +27,13 @@ type Ctx struct {
 	statusCode int
 	err        error
 	userData   map[string]interface{}
+	trailer    *Header      // Response trailer fields, nil until SetTrailer is called
+	router     *Router      // Router that is serving the request, used for named routes
+	rootRouter *Router      // Router the request was first given to, the parent of host, mounted and version routers
+	hostParams *routeParams // Values of the parameters of the matched host pattern, see Router.Host
+	mountPath  string       // Prefix of the mounted router serving the request, see Router.Mount
+	baseURL    string       // Path prefixes of all the mounted routers serving the request
+	jsonCodec  Codec        // JSON codec of the server configuration, nil for the registered codec
 
 	// Cache for parameter lookup to avoid repeated context lookups
 	paramCache cachedParamMap
@@ -32,9 +42,7 @@ type Ctx struct {
 	queryCache cachedQueryMap
 
 	// Fields for the middleware pattern
-	middlewareStack []MiddlewareFunc   // Dynamic middleware stack (legacy)
-	fixedMiddleware [16]MiddlewareFunc // Fixed-size buffer for middleware functions (new optimized approach)
-	fixedCount      int                // Number of middleware functions in the fixed buffer
+	middlewareStack []MiddlewareFunc // Middleware of the chain being run, see CompileMiddleware
 	middlewareIndex int
 	handler         Handler
 }
@@ -48,7 +56,6 @@ var contextPool = pool.New(func() *Ctx {
 		statusCode:      StatusOK,
 		err:             nil,
 		middlewareStack: make([]MiddlewareFunc, 0, 4), // Pre-allocate capacity for common middleware count
-		fixedCount:      0,                            // No middleware in fixed buffer initially
 		middlewareIndex: -1,
 		paramCache:      cachedParamMap{valid: false, params: nil, routeParams: nil, fixedParams: nil},
 		queryCache:      cachedQueryMap{valid: false, values: nil},
@@ -213,7 +220,9 @@ func (c *Ctx) GetError() error {
 // Next calls the next middleware or handler in the stack.
 // If there are no more middleware functions, it calls the final handler.
 // This method is typically called within middleware to pass control to the next middleware
-// or to the final route handler.
+// or to the final route handler. The rest of the chain has completed when Next returns.
+// A middleware that returns without calling Next lets the chain continue, unless it set
+// an error or an error status.
 //
 // Example usage in middleware:
 //
@@ -223,12 +232,16 @@ func (c *Ctx) GetError() error {
 //	    // Do something after the next middleware or handler has completed
 //	}
 func (c *Ctx) Next() {
-	c.middlewareIndex++
+	if c.middlewareIndex >= abortIndex {
+		return
+	}
 
-	// Ultra-fast path: use fixed-size buffer if available
-	if c.fixedCount > 0 {
-		// If we've gone through all middleware in the fixed buffer, call the final handler
-		if c.middlewareIndex >= c.fixedCount {
+	for {
+		c.middlewareIndex++
+		index := c.middlewareIndex
+
+		// If we've gone through all middleware, call the final handler
+		if index >= len(c.middlewareStack) {
 			// We need to check if the handler is nil to avoid panics
 			if c.handler != nil {
 				c.handler(c)
@@ -236,28 +249,30 @@ func (c *Ctx) Next() {
 			return
 		}
 
-		// Call the next middleware from the fixed buffer
-		// This is the most common case, so we optimize it
-		c.fixedMiddleware[c.middlewareIndex](c)
-		return
-	}
-
-	// Legacy path: use dynamic middleware stack
-	// This path is only used for backward compatibility
-	middlewareLen := len(c.middlewareStack)
+		c.middlewareStack[index](c)
 
-	// Fast check if we've gone through all middleware
-	if c.middlewareIndex >= middlewareLen {
-		// We need to check if the handler is nil to avoid panics
-		if c.handler != nil {
-			c.handler(c)
+		// Stop if the middleware ran the rest of the chain, or set an error
+		if c.middlewareIndex != index || c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
+			return
 		}
-		return
 	}
+}
+
+// abortIndex is the middleware index of a chain ended with Abort.
+const abortIndex = math.MaxInt32
 
-	// Call the next middleware from the dynamic stack
-	// This is the slowest path, but it's rarely used
-	c.middlewareStack[c.middlewareIndex](c)
+// Abort ends the middleware chain: the remaining middleware and the handler are not called,
+// even by Next. It is used by middleware that answers the request itself with a response
+// that is not an error.
+//
+// Example:
+//
+//	func Maintenance(c *ngebut.Ctx) {
+//	    c.String("back soon")
+//	    c.Abort()
+//	}
+func (c *Ctx) Abort() {
+	c.middlewareIndex = abortIndex
 }
 
 // GetContext gets a Ctx from the pool and initializes it with the given writer and request.
@@ -317,8 +332,11 @@ func ReleaseContext(ctx *Ctx) {
 		}
 	}
 
+	ctx.trailer = nil
+	ctx.router = nil
+	ctx.rootRouter = nil
+
 	ctx.middlewareStack = ctx.middlewareStack[:0]
-	ctx.fixedCount = 0
 	ctx.middlewareIndex = -1
 	ctx.handler = nil
 
@@ -336,6 +354,13 @@ func ReleaseContext(ctx *Ctx) {
 		releaseParams(ctx.paramCache.fixedParams)
 		ctx.paramCache.fixedParams = nil
 	}
+	ctx.mountPath = ""
+	ctx.baseURL = ""
+	ctx.jsonCodec = nil
+	if ctx.hostParams != nil {
+		releaseRouteParams(ctx.hostParams)
+		ctx.hostParams = nil
+	}
 
 	// Reset the query cache but keep the map for reuse
 	ctx.queryCache.valid = false
@@ -392,10 +417,18 @@ func (c *Ctx) Method() string {
 	return c.Request.Method
 }
 
+// IsHead reports whether the request is a HEAD request.
+// GET handlers also serve HEAD requests, and can use it to skip producing a body
+// that will not be sent. Setting the Content-Length header keeps it in the response.
+func (c *Ctx) IsHead() bool {
+	return c.Method() == MethodHead
+}
+
 // Path returns the URL path of the request.
 // If the request is nil, it returns an empty string.
 // This method is useful for determining the requested resource.
 // For example, for a request to "/users/123", it would return "/users/123".
+// Inside a router mounted with Router.Mount, the mount prefix is left out, see BaseURL.
 // If the request is nil, it returns an empty string.
 func (c *Ctx) Path() string {
 	if c.Request == nil {
@@ -404,6 +437,20 @@ func (c *Ctx) Path() string {
 	return c.Request.URL.Path
 }
 
+// MountPath returns the prefix the router serving the request was mounted at with
+// Router.Mount, or an empty string if the router is not mounted.
+// For example, it returns "/admin" for a router mounted with Mount("/admin", admin).
+func (c *Ctx) MountPath() string {
+	return c.mountPath
+}
+
+// BaseURL returns the path prefix of the router serving the request, including the
+// prefixes of all the mounted routers it is nested in, or an empty string if the router
+// is not mounted. Path returns the rest of the path, so BaseURL()+Path() is the full path.
+func (c *Ctx) BaseURL() string {
+	return c.baseURL
+}
+
 // IP returns the client's IP address.
 // It tries to determine the real IP address by checking various headers
 // that might be set by proxies, before falling back to the direct connection IP.
@@ -598,6 +645,96 @@ func (c *Ctx) Get(key string) string {
 	return c.Request.Header.Get(key)
 }
 
+// DeclareTrailer announces the names of trailer fields that will be sent after the body.
+// It adds the names to the Trailer response header; the values are set with SetTrailer.
+//
+// Parameters:
+//   - keys: The trailer field names
+//
+// Returns:
+//   - The context itself for method chaining
+func (c *Ctx) DeclareTrailer(keys ...string) *Ctx {
+	if len(keys) == 0 {
+		return c
+	}
+
+	// The names are declared on the response, the request may have a Trailer header of its own
+	header := c.Writer.Header()
+	names := make([]string, 0, len(keys)+1)
+	if existing := header.Get(HeaderTrailer); existing != "" {
+		names = append(names, existing)
+	}
+	for _, key := range keys {
+		names = append(names, textproto.CanonicalMIMEHeaderKey(key))
+	}
+	header.Set(HeaderTrailer, strings.Join(names, ", "))
+	return c
+}
+
+// SetTrailer sets a trailer field to be sent after the response body.
+// It can be called after the body has been written. Setting any trailer
+// makes the server send the body with chunked transfer encoding.
+// Trailers that were not declared with DeclareTrailer are declared automatically.
+//
+// Parameters:
+//   - key: The trailer field name
+//   - value: The trailer field value
+//
+// Returns:
+//   - The context itself for method chaining
+func (c *Ctx) SetTrailer(key, value string) *Ctx {
+	if c.trailer == nil {
+		c.trailer = NewHeader()
+	}
+	c.trailer.Set(key, value)
+
+	// When running behind net/http, hand the trailer to the underlying writer
+	if adapter, ok := c.Writer.(*httpResponseWriterAdapter); ok && adapter.writer != nil {
+		if _, internal := adapter.writer.(*responseRecorder); !internal {
+			adapter.writer.Header().Set(http.TrailerPrefix+key, value)
+		}
+	}
+	return c
+}
+
+// Trailer returns the response trailer fields set with SetTrailer.
+//
+// Returns:
+//   - The trailer fields, or nil if no trailer has been set
+func (c *Ctx) Trailer() *Header {
+	return c.trailer
+}
+
+// RedirectToRoute redirects the client to the URL of a named route.
+// The URL is built with Router.URL from the given parameters and query values.
+//
+// Parameters:
+//   - name: The name of the route
+//   - params: The route parameter values
+//   - query: The query values to append, may be nil
+//   - status: Optional redirect status code, defaults to 302 Found
+//
+// Returns:
+//   - An error if the route does not exist or a parameter is missing
+func (c *Ctx) RedirectToRoute(name string, params map[string]string, query url.Values, status ...int) error {
+	if c.router == nil {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+
+	location, err := c.router.URL(name, params, query)
+	if err != nil {
+		return err
+	}
+
+	code := StatusFound
+	if len(status) > 0 {
+		code = status[0]
+	}
+
+	c.redirect(location, code)
+	return nil
+}
+
 // cachedParamMap caches the parameters to avoid repeated lookups
 type cachedParamMap struct {
 	params      *paramSlice  // Legacy parameter storage
@@ -637,10 +774,15 @@ func releaseParamKeyCache(m map[string]struct{}) {
 
 // Param retrieves a URL path parameter value by its key.
 // For example, in a route "/users/:id", Param("id") would return the value in the URL path.
+// Wildcards are read by name ("/static/*path" → Param("path")) or by symbol and position:
+// Param("*") or Param("*1") for the first unnamed *, Param("*2") for the second, likewise for +.
 //
 // Parameters:
 //   - key: The parameter name to retrieve
 //
+// Parameters of the host pattern matched with Router.Host are also available,
+// for example Param("tenant") for the host pattern ":tenant.example.com".
+//
 // Returns:
 //   - The parameter value as a string, or empty string if not found
 func (c *Ctx) Param(key string) string {
@@ -648,6 +790,20 @@ func (c *Ctx) Param(key string) string {
 		return ""
 	}
 
+	// The first unnamed wildcard can also be read as "*1" or "+1"
+	if key == "*1" || key == "+1" {
+		key = key[:1]
+	}
+
+	value := c.routeParam(key)
+	if value == "" && c.hostParams != nil {
+		value, _ = c.hostParams.Get(key)
+	}
+	return value
+}
+
+// routeParam retrieves a URL path parameter value by its key.
+func (c *Ctx) routeParam(key string) string {
 	// Ultra-fast path: Use cached routeParams with fixed arrays if available
 	// This is now the most optimized path with zero allocations
 	if c.paramCache.valid && c.paramCache.routeParams != nil {
@@ -785,6 +941,62 @@ func (c *Ctx) Param(key string) string {
 	return ""
 }
 
+// AllParams returns a copy of all URL path parameters captured for the current route,
+// and of the parameters of the matched host pattern. The returned map is freshly allocated,
+// so it is safe to keep after the request completes.
+//
+// Returns:
+//   - A map of parameter names to values, or an empty map if the route has no parameters
+func (c *Ctx) AllParams() map[string]string {
+	params := c.allRouteParams()
+
+	// Host parameters don't override path parameters with the same name
+	if rp := c.hostParams; rp != nil {
+		for i := 0; i < rp.count; i++ {
+			if _, exists := params[rp.fixedKeys[i]]; !exists {
+				params[rp.fixedKeys[i]] = rp.fixedValues[i]
+			}
+		}
+	}
+	return params
+}
+
+// allRouteParams returns a copy of all URL path parameters captured for the current route.
+func (c *Ctx) allRouteParams() map[string]string {
+	if !c.paramCache.valid {
+		return map[string]string{}
+	}
+
+	if rp := c.paramCache.routeParams; rp != nil {
+		params := make(map[string]string, rp.count+len(rp.keys))
+		for i := 0; i < rp.count; i++ {
+			params[rp.fixedKeys[i]] = rp.fixedValues[i]
+		}
+		for i := 0; i < len(rp.keys); i++ {
+			params[rp.keys[i]] = rp.values[i]
+		}
+		return params
+	}
+
+	if p := c.paramCache.fixedParams; p != nil {
+		params := make(map[string]string, p.len)
+		for i := 0; i < p.len; i++ {
+			params[p.keys[i]] = p.values[i]
+		}
+		return params
+	}
+
+	if ps := c.paramCache.params; ps != nil {
+		params := make(map[string]string, len(ps.entries))
+		for _, entry := range ps.entries {
+			params[entry.key] = entry.value
+		}
+		return params
+	}
+
+	return map[string]string{}
+}
+
 // ensureQueryCache ensures that the query cache is populated
 // It returns the cached values map
 func (c *Ctx) ensureQueryCache() map[string][]string {
@@ -1177,6 +1389,17 @@ func (c *Ctx) JSON(obj interface{}) {
 
 	c.Writer.WriteHeader(c.statusCode)
 
+	// Encode with the JSON codec when it is replaced, without the fast paths
+	if c.jsonCodec != nil || jsonCodecRegistered.Load() {
+		codec, _ := c.codec(MIMEApplicationJSON)
+		if data, err := codec.Marshal(obj); err != nil {
+			c.Error(jsonEncodingErr)
+		} else {
+			_, _ = c.Writer.Write(data)
+		}
+		return
+	}
+
 	// Fast path for nil objects
 	if obj == nil {
 		_, _ = c.Writer.Write(jsonNull)
@@ -4,11 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"github.com/goccy/go-json"
 	"net/http"
 	"net/url"
 	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -40,7 +38,8 @@ func (c *Ctx) BindJSON(obj interface{}) error {
 	}
 
 	// Unmarshal the JSON data into the provided object
-	if err := json.Unmarshal(c.Request.Body, obj); err != nil {
+	codec, _ := c.codec(MIMEApplicationJSON)
+	if err := codec.Unmarshal(c.Request.Body, obj); err != nil {
 		return fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 
@@ -54,7 +53,8 @@ func (c *Ctx) BindJSON(obj interface{}) error {
 // - text/plain (treated as URL-encoded)
 // - empty Content-Type (treated as URL-encoded)
 // The struct fields should be tagged with `form:"field_name"` to specify the form field name.
-// If a field doesn't have a form tag, it will be skipped.
+// If a field doesn't have a form tag, it will be skipped, except for structs which are bound
+// field by field. Fields are bound as with BindQuery, including default tags.
 // Parameters:
 //   - obj: The object to bind the form data to
 //
@@ -85,24 +85,179 @@ func (c *Ctx) BindForm(obj interface{}) error {
 		return errors.New("obj must be a pointer to a struct")
 	}
 
+	values, err := c.formValues()
+	if err != nil {
+		return err
+	}
+
+	// Bind the form values to the struct fields
+	if err := applyDefaults(obj); err != nil {
+		return err
+	}
+	return newValueBinder("form", values, false).bind(obj)
+}
+
+// Bind decodes the request body into the provided object, with the decoder picked from
+// the Content-Type header: form data is bound with BindForm, and other media types, such
+// as JSON and XML, are decoded with the codec registered for them, see RegisterCodec.
+// Fields with a default tag are set to its value unless the body sets them.
+//
+// Parameters:
+//   - obj: The object to decode the body into
+//
+// Returns:
+//   - ErrUnsupportedMediaType if there is no decoder for the Content-Type
+//   - An error if decoding fails
+//   - nil if successful, or if the request has no body
+func (c *Ctx) Bind(obj interface{}) error {
+	if err := applyDefaults(obj); err != nil {
+		return err
+	}
+	return c.bindBody(obj)
+}
+
+// BindQuery binds the query parameters of the request to the fields of the provided
+// struct tagged with `query:"name"`.
+//
+// Repeated parameters are bound to slices, and the fields of nested structs and the
+// entries of maps to dotted or bracketed keys, such as "filter.status" or "filter[status]"
+// for the status field of the filter field. Slices of structs are bound to indexed keys,
+// such as "items[0][id]". Fields of embedded structs, and of other structs without a tag,
+// are bound with their own tags.
+//
+// Fields can be strings, booleans, numbers, pointers to them, time.Time, parsed with the
+// layout of the time_format tag and time.RFC3339 by default, time.Duration and types
+// implementing encoding.TextUnmarshaler. Fields with a default tag, such as
+// `default:"20"`, are set to its value unless the request sets them.
+//
+// Parameters:
+//   - obj: The pointer to the struct to bind the query parameters to
+//
+// Returns:
+//   - An error if obj is not a pointer to a struct or if a value can't be converted
+//   - nil if successful
+//
+// Example:
+//
+//	type ListParams struct {
+//		Page    int           `query:"page" default:"1"`
+//		Tags    []string      `query:"tag"`
+//		Since   time.Time     `query:"since" time_format:"2006-01-02"`
+//		Timeout time.Duration `query:"timeout" default:"5s"`
+//	}
+func (c *Ctx) BindQuery(obj interface{}) error {
+	return c.bindValues(obj, "query", c.ensureQueryCache(), false)
+}
+
+// BindHeader binds the request headers to the fields of the provided struct tagged
+// with `header:"Name"`. Header names are matched ignoring case, and fields are bound
+// as with BindQuery.
+func (c *Ctx) BindHeader(obj interface{}) error {
+	return c.bindValues(obj, "header", *c.Request.Header, true)
+}
+
+// BindURI binds the route parameters of the request to the fields of the provided
+// struct tagged with `uri:"name"`, the name of the parameter. Fields are bound as
+// with BindQuery.
+func (c *Ctx) BindURI(obj interface{}) error {
+	return c.bindValues(obj, "uri", c.uriValues(), false)
+}
+
+// BindAll binds the whole request to the provided struct: the body with Bind, using the
+// form or json tags of the fields, then the query parameters, the headers and the route
+// parameters, using the query, header and uri tags. Values of the later sources take
+// precedence, so a route parameter overrides a body field with the same struct field.
+// Fields with a default tag are set to its value unless the request sets them.
+//
+// Example:
+//
+//	type UpdateUser struct {
+//		ID      int    `uri:"id"`
+//		Name    string `json:"name" form:"name"`
+//		Notify  bool   `query:"notify" default:"true"`
+//		TraceID string `header:"X-Trace-Id"`
+//	}
+func (c *Ctx) BindAll(obj interface{}) error {
+	if _, err := structPointer(obj); err != nil {
+		return err
+	}
+	if err := applyDefaults(obj); err != nil {
+		return err
+	}
+
+	if err := c.bindBody(obj); err != nil {
+		return err
+	}
+	if err := newValueBinder("query", c.ensureQueryCache(), false).bind(obj); err != nil {
+		return err
+	}
+	if err := newValueBinder("header", *c.Request.Header, true).bind(obj); err != nil {
+		return err
+	}
+	return newValueBinder("uri", c.uriValues(), false).bind(obj)
+}
+
+// bindBody decodes the request body into obj, without the default values.
+func (c *Ctx) bindBody(obj interface{}) error {
+	if len(c.Request.Body) == 0 {
+		return nil
+	}
+
+	mediaType, _ := parseMediaType(c.Request.Header.Get(HeaderContentType))
+	if mediaType == MIMEApplicationForm || mediaType == MIMEMultipartForm {
+		if _, err := structPointer(obj); err != nil {
+			return err
+		}
+		values, err := c.formValues()
+		if err != nil {
+			return err
+		}
+		return newValueBinder("form", values, false).bind(obj)
+	}
+	return c.Decode(obj)
+}
+
+// bindValues binds the values of a request source to the fields of obj tagged with tag.
+func (c *Ctx) bindValues(obj interface{}, tag string, values map[string][]string, fold bool) error {
+	if _, err := structPointer(obj); err != nil {
+		return err
+	}
+	if err := applyDefaults(obj); err != nil {
+		return err
+	}
+	return newValueBinder(tag, values, fold).bind(obj)
+}
+
+// uriValues returns the route parameters of the request as binding values.
+func (c *Ctx) uriValues() map[string][]string {
+	params := c.AllParams()
+	values := make(map[string][]string, len(params))
+	for name, value := range params {
+		values[name] = []string{value}
+	}
+	return values
+}
+
+// formValues parses the form data of the request body, see BindForm for the supported Content-Types.
+func (c *Ctx) formValues() (url.Values, error) {
 	// Parse the form data based on the Content-Type header
 	contentType := c.Request.Header.Get("Content-Type")
 	var values url.Values
+	var err error
 
 	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
 		// Parse URL-encoded form data
-		body := string(c.Request.Body)
-		var err error
-		values, err = url.ParseQuery(body)
+		values, err = url.ParseQuery(string(c.Request.Body))
 		if err != nil {
-			return fmt.Errorf("failed to parse form data: %w", err)
+			return nil, fmt.Errorf("failed to parse form data: %w", err)
 		}
 	} else if strings.HasPrefix(contentType, "multipart/form-data") {
 		// Parse multipart form data
 		// Create a new http.Request with the same body for parsing
-		httpReq, err := http.NewRequest(c.Request.Method, c.Request.URL.String(), bytes.NewReader(c.Request.Body))
+		var httpReq *http.Request
+		httpReq, err = http.NewRequest(c.Request.Method, c.Request.URL.String(), bytes.NewReader(c.Request.Body))
 		if err != nil {
-			return fmt.Errorf("failed to create request for multipart parsing: %w", err)
+			return nil, fmt.Errorf("failed to create request for multipart parsing: %w", err)
 		}
 
 		// Copy headers to ensure Content-Type with boundary is preserved
@@ -113,82 +268,19 @@ func (c *Ctx) BindForm(obj interface{}) error {
 		// Parse the multipart form
 		err = httpReq.ParseMultipartForm(32 << 20) // 32MB max memory
 		if err != nil {
-			return fmt.Errorf("failed to parse multipart form: %w", err)
+			return nil, fmt.Errorf("failed to parse multipart form: %w", err)
 		}
 
 		values = httpReq.Form
 	} else if contentType == "" || strings.HasPrefix(contentType, "text/plain") {
 		// Handle plain form data or no content type (treat as URL-encoded)
-		body := string(c.Request.Body)
-		var err error
-		values, err = url.ParseQuery(body)
+		values, err = url.ParseQuery(string(c.Request.Body))
 		if err != nil {
-			return fmt.Errorf("failed to parse form data: %w", err)
+			return nil, fmt.Errorf("failed to parse form data: %w", err)
 		}
 	} else {
-		return fmt.Errorf("unsupported Content-Type for form binding: %s", contentType)
+		return nil, fmt.Errorf("unsupported Content-Type for form binding: %s", contentType)
 	}
 
-	// Bind the form values to the struct fields
-	objElem := objValue.Elem()
-	objType := objElem.Type()
-
-	for i := 0; i < objElem.NumField(); i++ {
-		field := objType.Field(i)
-		fieldValue := objElem.Field(i)
-
-		// Skip unexported fields
-		if !fieldValue.CanSet() {
-			continue
-		}
-
-		// Get the form tag
-		formTag := field.Tag.Get("form")
-		if formTag == "" {
-			// Skip fields without a form tag
-			continue
-		}
-
-		// Get the form value
-		formValue := values.Get(formTag)
-		if formValue == "" {
-			// Skip empty values
-			continue
-		}
-
-		// Set the field value based on its type
-		switch fieldValue.Kind() {
-		case reflect.String:
-			fieldValue.SetString(formValue)
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			intValue, err := strconv.ParseInt(formValue, 10, 64)
-			if err != nil {
-				return fmt.Errorf("failed to parse %s as int: %w", formTag, err)
-			}
-			fieldValue.SetInt(intValue)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			uintValue, err := strconv.ParseUint(formValue, 10, 64)
-			if err != nil {
-				return fmt.Errorf("failed to parse %s as uint: %w", formTag, err)
-			}
-			fieldValue.SetUint(uintValue)
-		case reflect.Float32, reflect.Float64:
-			floatValue, err := strconv.ParseFloat(formValue, 64)
-			if err != nil {
-				return fmt.Errorf("failed to parse %s as float: %w", formTag, err)
-			}
-			fieldValue.SetFloat(floatValue)
-		case reflect.Bool:
-			boolValue, err := strconv.ParseBool(formValue)
-			if err != nil {
-				return fmt.Errorf("failed to parse %s as bool: %w", formTag, err)
-			}
-			fieldValue.SetBool(boolValue)
-		default:
-			// Skip unsupported types
-			continue
-		}
-	}
-
-	return nil
+	return values, nil
 }
@@ -1,9 +1,11 @@
 package ngebut
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"mime/multipart"
 	"net"
 	"net/http"
@@ -784,3 +786,115 @@ func TestGetParam(t *testing.T) {
 	// Test getting a non-existent parameter
 	assert.Equal(t, "", ctx.GetParam("name"), "GetParam should return empty string for non-existent parameters")
 }
+
+// TestAllParams tests the AllParams method
+func TestAllParams(t *testing.T) {
+	router := NewRouter()
+
+	var params map[string]string
+	router.GET("/users/:id/posts/:post", func(c *Ctx) {
+		params = c.AllParams()
+	})
+
+	req, _ := http.NewRequest(MethodGet, "/users/1/posts/2", nil)
+	res := httptest.NewRecorder()
+	ctx := GetContext(res, req)
+	router.ServeHTTP(ctx, ctx.Request)
+
+	assert.Equal(t, map[string]string{"id": "1", "post": "2"}, params, "AllParams should return all route parameters")
+
+	// Test with no parameters
+	req, _ = http.NewRequest(MethodGet, "/test", nil)
+	ctx = GetContext(httptest.NewRecorder(), req)
+	assert.Empty(t, ctx.AllParams(), "AllParams should return an empty map when there are no parameters")
+}
+
+// TestTrailers tests declaring and setting response trailers and reading request trailers
+func TestTrailers(t *testing.T) {
+	assert := assert.New(t)
+
+	server := New(DefaultConfig())
+	server.POST("/upload", func(c *Ctx) {
+		c.DeclareTrailer("X-Checksum")
+		c.String("received %d bytes", len(c.Request.Body))
+
+		// Trailer values can be set after the body is written
+		c.SetTrailer("X-Checksum", "abc123")
+		if c.Request.Trailer != nil {
+			c.SetTrailer("X-Echo", c.Request.Trailer.Get("X-Client-Sum"))
+		}
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// Send a chunked request with trailers
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("hello"))
+		_ = pw.Close()
+	}()
+	req, _ := http.NewRequest(MethodPost, ts.URL+"/upload", pr)
+	req.Trailer = http.Header{"X-Client-Sum": nil}
+	req.Trailer.Set("X-Client-Sum", "client")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	defer resp.Body.Close()
+
+	_, declared := resp.Trailer["X-Checksum"]
+	assert.True(declared, "declared trailers should be announced")
+	_, copied := resp.Trailer["X-Client-Sum"]
+	assert.False(copied, "the trailers declared by the request should not be announced")
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal("received 5 bytes", string(body))
+	assert.Equal("abc123", resp.Trailer.Get("X-Checksum"), "declared trailer should be sent")
+	assert.Equal("client", resp.Trailer.Get("X-Echo"), "request trailers should be available on the request")
+
+	// Trailer returns the trailers set on the context
+	ctx := GetContext(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/", nil))
+	assert.Nil(ctx.Trailer(), "Trailer should be nil before SetTrailer is called")
+	ctx.SetTrailer("X-Test", "1")
+	assert.Equal("1", ctx.Trailer().Get("X-Test"))
+
+	// Trailers are declared on the response headers, not next to the request's own
+	req, _ = http.NewRequest(MethodPost, "/upload", nil)
+	req.Header.Set(HeaderTrailer, "X-Client-Sum")
+	w := httptest.NewRecorder()
+	ctx = GetContext(w, req)
+	ctx.DeclareTrailer("X-Checksum").DeclareTrailer("grpc-status")
+	assert.Equal("X-Checksum, Grpc-Status", w.Header().Get(HeaderTrailer))
+	assert.Equal("X-Client-Sum", ctx.Request.Header.Get(HeaderTrailer), "the request header should be left unchanged")
+}
+
+// TestRequestTrailers tests reading the trailers of a chunked request body
+func TestRequestTrailers(t *testing.T) {
+	assert := assert.New(t)
+
+	raw := "POST /upload HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"Trailer: X-Checksum, X-Missing\r\n" +
+		"\r\n" +
+		"5\r\nhello\r\n" +
+		"6\r\n world\r\n" +
+		"0\r\n" +
+		"X-Checksum: abc123\r\n" +
+		"\r\n"
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	assert.NoError(err)
+
+	ctx := GetContext(httptest.NewRecorder(), req)
+	defer ReleaseContext(ctx)
+	assert.Equal("hello world", string(ctx.Request.Body))
+	if assert.NotNil(ctx.Request.Trailer) {
+		assert.Equal("abc123", ctx.Request.Trailer.Get("X-Checksum"))
+		assert.Empty(ctx.Request.Trailer.Values("X-Missing"), "declared trailers that were not sent should be left out")
+	}
+
+	// Requests without trailers have none
+	plain := GetContext(httptest.NewRecorder(), httptest.NewRequest(MethodPost, "/upload", strings.NewReader("hello")))
+	defer ReleaseContext(plain)
+	assert.Nil(plain.Request.Trailer)
+}
@@ -1,10 +1,18 @@
 package ngebut
 
+import (
+	"sort"
+	"strings"
+)
+
 // Group represents a group of routes with a common prefix and middleware.
 type Group struct {
 	prefix          string
 	router          *Router
 	middlewareFuncs []MiddlewareFunc
+	name            string // Prefix for the names of routes registered in the group
+	lastMethod      string // Method of the last route registered in the group, empty if none
+	lastPattern     string // Pattern of the last route registered in the group
 }
 
 // Group creates a new route group with the given prefix.
@@ -18,7 +26,26 @@ func (r *Router) Group(prefix string) *Group {
 
 // Use adds middleware to the group.
 // It accepts middleware functions that take a context parameter.
+// The middleware runs after the router middleware, for the routes registered in the
+// group after it, like route handlers.
+//
+// A path prefix, relative to the group prefix, can be given as the first argument to add
+// path-scoped middleware to the router instead, see Router.Use. It runs for every request
+// under the prefix, including requests that match no route.
+//
+// Example:
+//
+//	admin := router.Group("/admin")
+//	admin.Use("", auth)       // every request under /admin
+//	admin.Use("/users", audit) // every request under /admin/users
 func (g *Group) Use(middleware ...interface{}) *Group {
+	if len(middleware) > 0 {
+		if prefix, ok := middleware[0].(string); ok {
+			g.router.Use(append([]interface{}{joinPath(g.prefix, prefix)}, middleware[1:]...)...)
+			return g
+		}
+	}
+
 	for _, m := range middleware {
 		switch m := m.(type) {
 		case Middleware:
@@ -32,6 +59,94 @@ func (g *Group) Use(middleware ...interface{}) *Group {
 	return g
 }
 
+// Name assigns a name to the group or to its most recently registered route.
+// Before any route is registered in the group, the name becomes a prefix for the names
+// of the group's routes and sub-groups. Afterwards, it names the last registered route,
+// prefixed with the group name.
+// It panics if the resulting route name is already used.
+//
+// Example:
+//
+//	api := router.Group("/api").Name("api.")
+//	api.GET("/users", listUsers).Name("users") // named "api.users"
+func (g *Group) Name(name string) *Group {
+	if g.lastMethod == "" {
+		g.name += name
+		return g
+	}
+	g.router.nameRoute(g.lastMethod, g.lastPattern, g.name+name)
+	return g
+}
+
+// NotFound sets the handler for requests that don't match any route, when their path
+// is under the group prefix. The group with the longest matching prefix is used, and
+// requests outside every group prefix are handled by the router's NotFound handler.
+func (g *Group) NotFound(handler Handler) *Group {
+	g.router.groupScope(g.prefix).notFound = handler
+	return g
+}
+
+// ErrorHandler sets the handler for errors of requests whose path is under the group prefix.
+// The group with the longest matching prefix is used, and errors of requests outside every
+// group prefix are handled by the server's error handler.
+func (g *Group) ErrorHandler(handler Handler) *Group {
+	g.router.groupScope(g.prefix).errorHandler = handler
+	return g
+}
+
+// groupScope holds the handlers of a group that apply to every path under its prefix.
+type groupScope struct {
+	prefix       string // Group prefix without a trailing slash
+	notFound     Handler
+	errorHandler Handler
+}
+
+// contains reports whether the path is the prefix or a path under it.
+func (s *groupScope) contains(path string) bool {
+	return hasPathPrefix(path, s.prefix)
+}
+
+// groupScope returns the scope of the group prefix, creating it if needed.
+func (r *Router) groupScope(prefix string) *groupScope {
+	prefix = strings.TrimRight(prefix, "/")
+	for _, s := range r.groupScopes {
+		if s.prefix == prefix {
+			return s
+		}
+	}
+
+	s := &groupScope{prefix: prefix}
+	r.groupScopes = append(r.groupScopes, s)
+
+	// The longest prefix is tried first
+	sort.SliceStable(r.groupScopes, func(i, j int) bool {
+		return len(r.groupScopes[i].prefix) > len(r.groupScopes[j].prefix)
+	})
+	return s
+}
+
+// notFoundHandler returns the NotFound handler of the group with the longest prefix
+// matching path, or the router's NotFound handler.
+func (r *Router) notFoundHandler(path string) Handler {
+	for _, s := range r.groupScopes {
+		if s.notFound != nil && s.contains(path) {
+			return s.notFound
+		}
+	}
+	return r.NotFound
+}
+
+// errorHandler returns the error handler of the group with the longest prefix
+// matching path, or the router's ErrorHandler.
+func (r *Router) errorHandler(path string) Handler {
+	for _, s := range r.groupScopes {
+		if s.errorHandler != nil && s.contains(path) {
+			return s.errorHandler
+		}
+	}
+	return r.ErrorHandler
+}
+
 // GET registers a new route with the GET method.
 func (g *Group) GET(pattern string, handlers ...Handler) *Group {
 	g.Handle(pattern, MethodGet, handlers...)
@@ -89,35 +204,36 @@ func (g *Group) PATCH(pattern string, handlers ...Handler) *Group {
 // Handle registers a new route with the given pattern and method.
 func (g *Group) Handle(pattern, method string, handlers ...Handler) *Group {
 	// Prepend the group prefix to the pattern
-	fullPattern := g.prefix
-	if pattern != "" {
-		if pattern[0] != '/' {
-			fullPattern += "/"
+	fullPattern := joinPath(g.prefix, pattern)
+
+	// The group middleware runs before the route handlers
+	if len(g.middlewareFuncs) > 0 {
+		all := make([]Handler, 0, len(g.middlewareFuncs)+len(handlers))
+		for _, m := range g.middlewareFuncs {
+			all = append(all, Handler(m))
 		}
-		fullPattern += pattern
+		handlers = append(all, handlers...)
 	}
 
 	// Register the route with the router, passing all handlers
-	g.router.Handle(fullPattern, method, handlers...)
+	// A conflicting route is not registered with StrictRegistration
+	if g.router.handle(fullPattern, method, handlers) {
+		g.lastMethod, g.lastPattern = method, fullPattern
+	}
 	return g
 }
 
 // Group creates a sub-group with the given prefix.
 func (g *Group) Group(prefix string) *Group {
 	// Prepend the parent group's prefix to the new group's prefix
-	fullPrefix := g.prefix
-	if prefix != "" {
-		if prefix[0] != '/' {
-			fullPrefix += "/"
-		}
-		fullPrefix += prefix
-	}
+	fullPrefix := joinPath(g.prefix, prefix)
 
 	// Create a new group with the combined prefix and parent's router
 	subGroup := &Group{
 		prefix:          fullPrefix,
 		router:          g.router,
 		middlewareFuncs: make([]MiddlewareFunc, len(g.middlewareFuncs)),
+		name:            g.name,
 	}
 
 	// Copy the parent group's middleware to the new group
@@ -125,3 +241,28 @@ func (g *Group) Group(prefix string) *Group {
 
 	return subGroup
 }
+
+// joinPath appends a path to a group prefix, adding a slash between them if needed.
+func joinPath(prefix, path string) string {
+	if path != "" && path[0] != '/' {
+		return prefix + "/" + path
+	}
+	return prefix + path
+}
+
+// Route creates a sub-group with the given prefix and passes it to fn, to define
+// nested groups in closures. It returns the group itself for chaining.
+//
+// Example:
+//
+//	api.Route("/users", func(users *ngebut.Group) {
+//		users.GET("", listUsers)
+//		users.Route("/:id", func(user *ngebut.Group) {
+//			user.GET("", getUser)
+//			user.DELETE("", deleteUser)
+//		})
+//	})
+func (g *Group) Route(prefix string, fn func(g *Group)) *Group {
+	fn(g.Group(prefix))
+	return g
+}
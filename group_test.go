@@ -1,6 +1,9 @@
 package ngebut
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -174,3 +177,149 @@ func TestGroupSubGroup(t *testing.T) {
 	nestedGroup := subGroup.Group("/users")
 	assert.Equal(t, "/api/v1/users", nestedGroup.prefix, "nestedGroup.prefix doesn't match expected value")
 }
+
+// TestGroupMiddleware tests that group middleware runs for the routes of the group
+func TestGroupMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(c *Ctx) {
+			order = append(order, name)
+			c.Next()
+		}
+	}
+	router.Use(trace("router"))
+	router.GET("/outside", func(c *Ctx) { order = append(order, "outside") })
+
+	api := router.Group("/api")
+	api.GET("/before", func(c *Ctx) { order = append(order, "before") })
+	api.Use(trace("api"))
+	api.GET("/users", func(c *Ctx) { order = append(order, "users") })
+
+	v1 := api.Group("/v1")
+	v1.Use(trace("v1"))
+	v1.GET("/items", Handler(trace("route")), func(c *Ctx) { order = append(order, "items") })
+
+	serve := func(path string) []string {
+		order = nil
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		ctx := GetContext(httptest.NewRecorder(), req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		return order
+	}
+
+	assert.Equal([]string{"router", "api", "users"}, serve("/api/users"), "group middleware should run after the router middleware")
+	assert.Equal([]string{"router", "api", "v1", "route", "items"}, serve("/api/v1/items"), "sub-groups should run the parent middleware first")
+	assert.Equal([]string{"router", "before"}, serve("/api/before"), "routes registered before Use should not run it")
+	assert.Equal([]string{"router", "outside"}, serve("/outside"))
+}
+
+// TestGroupName tests naming groups and their routes
+func TestGroupName(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) {}
+
+	api := router.Group("/api").Name("api.")
+	api.GET("/users", handler).Name("users")
+	api.GET("/users/:id", handler).Name("user")
+
+	v1 := api.Group("/v1").Name("v1.")
+	v1.GET("/status", handler).Name("status")
+
+	u, err := router.URL("api.users", nil, nil)
+	assert.NoError(err)
+	assert.Equal("/api/users", u)
+
+	u, err = router.URL("api.user", map[string]string{"id": "5"}, nil)
+	assert.NoError(err)
+	assert.Equal("/api/users/5", u)
+
+	u, err = router.URL("api.v1.status", nil, nil)
+	assert.NoError(err)
+	assert.Equal("/api/v1/status", u)
+
+	assert.Panics(func() {
+		api.GET("/other", handler).Name("users")
+	}, "duplicate names should panic")
+}
+
+// TestGroupNameAfterRemove tests naming the last route of a group after routes are removed
+func TestGroupNameAfterRemove(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) {}
+
+	router.GET("/first", handler)
+	api := router.Group("/api")
+	api.GET("/users", handler)
+
+	// Removing an earlier route moves the group route in Routes
+	assert.True(router.Remove(MethodGet, "/first"))
+	api.Name("users")
+
+	u, err := router.URL("users", nil, nil)
+	assert.NoError(err)
+	assert.Equal("/api/users", u, "the name should be assigned to the last route of the group")
+
+	// Routes of a published table are not changed
+	published := router.routeTable()
+	api.Name("users.list")
+	assert.Equal("users", published.routes[0].Name)
+	assert.Equal("users.list", router.routeTable().routes[0].Name)
+}
+
+// TestGroupNotFoundAndErrorHandler tests group scoped NotFound and error handlers
+func TestGroupNotFoundAndErrorHandler(t *testing.T) {
+	server := New(Config{})
+	fail := func(c *Ctx) { c.Error(errors.New("boom")) }
+
+	server.GET("/fail", fail)
+
+	api := server.Group("/api")
+	api.NotFound(func(c *Ctx) {
+		c.Status(StatusNotFound)
+		c.JSON(map[string]string{"title": "not found"})
+	})
+	api.ErrorHandler(func(c *Ctx) {
+		c.Status(StatusInternalServerError)
+		c.JSON(map[string]string{"title": c.GetError().Error()})
+	})
+	api.GET("/fail", fail)
+
+	v2 := api.Group("/v2")
+	v2.NotFound(func(c *Ctx) {
+		c.Status(StatusNotFound)
+		c.String("v2 not found")
+	})
+	v2.GET("/fail", fail)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/api/missing")
+	assert.Equal(t, StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"title":"not found"}`, w.Body.String(), "the group NotFound handler should be used")
+
+	w = serve("/api/v2/missing")
+	assert.Equal(t, "v2 not found", w.Body.String(), "the longest prefix should win")
+
+	w = serve("/apis")
+	assert.Equal(t, "404 page not found", w.Body.String(), "paths outside the group should use the router handler")
+
+	w = serve("/api/fail")
+	assert.Equal(t, StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"title":"boom"}`, w.Body.String(), "the group error handler should be used")
+
+	w = serve("/api/v2/fail")
+	assert.JSONEq(t, `{"title":"boom"}`, w.Body.String(), "sub-groups should inherit the error handler")
+
+	w = serve("/fail")
+	assert.Equal(t, "boom", w.Body.String(), "errors outside the group should use the server handler")
+}
@@ -14,108 +14,27 @@ type Middleware func(c *Ctx)
 type MiddlewareFunc = Middleware
 
 // CompileMiddleware precomposes multiple middleware functions into a single handler function.
-// This eliminates the need for runtime middleware chaining, reducing allocations and function call overhead.
-// The middleware functions are executed in the order they are provided.
+// The middleware functions are executed in the order they are provided, followed by the handler.
 //
-// Unlike the dynamic middleware approach that uses c.Next(), this function creates a static chain
-// where each middleware is directly called in sequence, eliminating the overhead of dynamic dispatch.
-//
-// Note: This approach is most effective for middleware that doesn't rely on c.Next() for complex
-// behavior like executing code after the next middleware completes.
+// A middleware that calls c.Next() runs the rest of the chain before Next returns, so that
+// it can act on the response, as with the dynamic middleware stack. A middleware that returns
+// without calling c.Next() lets the chain continue, unless it set an error or an error status.
 func CompileMiddleware(handler Handler, middleware ...Middleware) Handler {
 	// If there's no middleware, just return the handler
 	if len(middleware) == 0 {
 		return handler
 	}
 
-	// For small middleware chains, use specialized functions to avoid runtime slice iteration
-	// This creates a true zero-allocation middleware chain with direct function calls
-	switch len(middleware) {
-	case 1:
-		// Optimize for single middleware case (very common)
-		m1 := middleware[0]
-		return func(c *Ctx) {
-			m1(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			handler(c)
-		}
-	case 2:
-		// Optimize for two middleware case
-		m1, m2 := middleware[0], middleware[1]
-		return func(c *Ctx) {
-			m1(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			m2(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			handler(c)
-		}
-	case 3:
-		// Optimize for three middleware case
-		m1, m2, m3 := middleware[0], middleware[1], middleware[2]
-		return func(c *Ctx) {
-			m1(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			m2(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			m3(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			handler(c)
-		}
-	case 4:
-		// Optimize for four middleware case
-		m1, m2, m3, m4 := middleware[0], middleware[1], middleware[2], middleware[3]
-		return func(c *Ctx) {
-			m1(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			m2(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			m3(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			m4(c)
-			if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-				return
-			}
-			handler(c)
-		}
-	default:
-		// For longer chains, fall back to the loop-based approach
-		// Create a new handler that executes all middleware in sequence
-		return func(c *Ctx) {
-			// Create a chain of function calls without using c.Next()
-			// This eliminates the overhead of dynamic dispatch
+	// The capacity is limited so that appending to the stack of a context never writes to the chain
+	middleware = middleware[:len(middleware):len(middleware)]
 
-			// Execute all middleware in sequence
-			for _, m := range middleware {
-				// Call each middleware directly
-				// If the middleware sets an error or writes a response, we should stop
-				m(c)
+	return func(c *Ctx) {
+		// Keep the stack of an enclosing chain, such as the one of a mounted router
+		stack, index, next := c.middlewareStack, c.middlewareIndex, c.handler
 
-				// Check if an error was set or if a response was written
-				if c.GetError() != nil || (c.Writer != nil && c.statusCode >= 400) {
-					return
-				}
-			}
+		c.middlewareStack, c.middlewareIndex, c.handler = middleware, -1, handler
+		c.Next()
 
-			// Finally, call the handler
-			handler(c)
-		}
+		c.middlewareStack, c.middlewareIndex, c.handler = stack, index, next
 	}
 }
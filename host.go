@@ -0,0 +1,139 @@
+package ngebut
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// hostRouter is a router serving the requests for the hosts matching a pattern.
+type hostRouter struct {
+	pattern  string
+	labels   []string // Labels of the pattern, e.g. [":tenant", "example", "com"]
+	params   int      // Number of parameter and wildcard labels
+	router   *Router
+	handlers []Handler // Serves the request with router, wrapped by the parent middleware
+}
+
+// Host returns a router whose routes only match requests for hosts matching pattern.
+// Requests for hosts that match no pattern are served by the router itself.
+//
+// The pattern is matched label by label, ignoring case and the port of the request host.
+// A label starting with ':' matches any label and is available with Ctx.Param,
+// and a leading '*' label matches one or more labels, available as Param("*").
+// Patterns without parameters take precedence over patterns with parameters.
+//
+// The host router has its own routes, middleware and NotFound handler.
+// The middleware of the parent router runs before it. It takes the routing options of
+// the parent, such as StrictRouting and CaseSensitive, when it is created.
+//
+// Example:
+//
+//	api := router.Host("api.example.com")
+//	api.GET("/users", listUsers)
+//
+//	tenants := router.Host(":tenant.example.com")
+//	tenants.GET("/", func(c *ngebut.Ctx) {
+//		c.String("tenant %s", c.Param("tenant"))
+//	})
+func (r *Router) Host(pattern string) *Router {
+	pattern = strings.ToLower(pattern)
+	for _, h := range r.hosts {
+		if h.pattern == pattern {
+			return h.router
+		}
+	}
+
+	h := &hostRouter{
+		pattern: pattern,
+		labels:  strings.Split(pattern, "."),
+		router:  NewRouter(),
+	}
+	r.inheritOptions(h.router)
+	for i, label := range h.labels {
+		if label == "" {
+			panic("host pattern " + pattern + " has an empty label")
+		}
+		if label == "*" && i != 0 {
+			panic("host pattern " + pattern + " has a wildcard that is not the first label")
+		}
+		if label[0] == ':' || label == "*" {
+			h.params++
+		}
+	}
+	h.handlers = []Handler{func(c *Ctx) {
+		h.router.ServeHTTP(c, c.Request)
+	}}
+
+	r.hosts = append(r.hosts, h)
+
+	// Patterns without parameters are tried first
+	sort.SliceStable(r.hosts, func(i, j int) bool {
+		return r.hosts[i].params == 0 && r.hosts[j].params > 0
+	})
+
+	return h.router
+}
+
+// match reports whether the host matches the pattern, storing the values of the
+// parameter labels in params.
+func (h *hostRouter) match(host string, params *routeParams) bool {
+	for i := len(h.labels) - 1; i >= 0; i-- {
+		label := h.labels[i]
+		if host == "" {
+			return false
+		}
+
+		// A leading wildcard takes the remaining labels
+		if label == "*" {
+			params.Set("*", host)
+			return true
+		}
+
+		var part string
+		if j := strings.LastIndexByte(host, '.'); j >= 0 {
+			part, host = host[j+1:], host[:j]
+			if host == "" {
+				return false
+			}
+		} else {
+			part, host = host, ""
+		}
+
+		if label[0] == ':' {
+			if part == "" {
+				return false
+			}
+			params.Set(label[1:], part)
+		} else if label != part {
+			return false
+		}
+	}
+	return host == ""
+}
+
+// matchHost returns the host router for the request host, storing the values of
+// its parameter labels in the context. It returns nil if no host pattern matches.
+func (r *Router) matchHost(ctx *Ctx, requestHost string) *hostRouter {
+	host := strings.ToLower(requestHost)
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	// A fully qualified host name may end with a dot
+	host = strings.TrimSuffix(host, ".")
+
+	params := getRouteParams()
+	for _, h := range r.hosts {
+		params.Reset()
+		if h.match(host, params) {
+			if params.count > 0 || len(params.keys) > 0 {
+				ctx.hostParams = params
+			} else {
+				releaseRouteParams(params)
+			}
+			return h
+		}
+	}
+	releaseRouteParams(params)
+	return nil
+}
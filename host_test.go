@@ -0,0 +1,119 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouterHost tests host based routing
+func TestRouterHost(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var order []string
+	router.Use(func(c *Ctx) {
+		order = append(order, "global")
+		c.Next()
+	})
+
+	router.GET("/", func(c *Ctx) { c.String("default") })
+
+	api := router.Host("api.example.com")
+	api.GET("/", func(c *Ctx) { c.String("api") })
+	assert.Same(api, router.Host("API.example.com"), "Host should return the existing router for a pattern")
+
+	tenants := router.Host(":tenant.example.com")
+	tenants.GET("/users/:id", func(c *Ctx) {
+		c.String("%s %s %d", c.Param("tenant"), c.Param("id"), len(c.AllParams()))
+	})
+
+	assets := router.Host("*.cdn.example.com")
+	assets.GET("/", func(c *Ctx) { c.String("cdn %s", c.Param("*")) })
+
+	serve := func(host, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://"+host+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	assert.Equal("api", serve("api.example.com", "/").Body.String(), "static host patterns take precedence")
+	assert.Equal("api", serve("API.Example.com:8080", "/").Body.String(), "case and port should be ignored")
+	assert.Equal("acme 42 2", serve("acme.example.com", "/users/42").Body.String(), "host params should be available")
+	assert.Equal("cdn a.b", serve("a.b.cdn.example.com", "/").Body.String(), "a wildcard takes the leading labels")
+
+	// Hosts without a matching pattern fall back to the default router
+	assert.Equal("default", serve("example.com", "/").Body.String())
+	assert.Equal("default", serve("a.b.example.com", "/").Body.String())
+
+	// A matching host router handles missing routes itself
+	assert.Equal(StatusNotFound, serve("acme.example.com", "/").Code)
+
+	// The parent middleware runs for host routes
+	order = nil
+	serve("api.example.com", "/")
+	assert.Equal([]string{"global"}, order)
+
+	// Host routes are listed with their host pattern
+	infos := router.routeInfos()
+	assert.Len(infos, 4)
+	assert.Equal("", infos[0].Host)
+	assert.Equal("*.cdn.example.com", infos[1].Host)
+	assert.Len(infos[1].Middleware, 1, "the parent middleware should be listed")
+}
+
+// TestRouterHostInvalidPattern tests that invalid host patterns panic
+func TestRouterHostInvalidPattern(t *testing.T) {
+	router := NewRouter()
+	assert.Panics(t, func() { router.Host("api..example.com") }, "empty labels should panic")
+	assert.Panics(t, func() { router.Host("api.*.com") }, "inner wildcards should panic")
+}
+
+// TestRouterHostInheritsOptions tests that host routers take the routing options of the parent
+func TestRouterHostInheritsOptions(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.RedirectTrailingSlash = true
+	router.CaseSensitive = false
+	router.HandleMethodNotAllowed = false
+	router.StrictRegistration = true
+
+	api := router.Host("api.example.com")
+	assert.True(api.RedirectTrailingSlash)
+	assert.False(api.CaseSensitive)
+	assert.False(api.HandleMethodNotAllowed)
+	assert.True(api.StrictRegistration)
+
+	api.GET("/users", func(c *Ctx) { c.String("users") })
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://api.example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	assert.Equal("users", serve(MethodGet, "/USERS").Body.String(), "routing should not be case sensitive")
+	w := serve(MethodGet, "/users/")
+	assert.Equal(StatusMovedPermanently, w.Code, "routing should be strict")
+	assert.Equal("/users", w.Header().Get(HeaderLocation))
+	assert.Equal(StatusNotFound, serve(MethodPost, "/users").Code, "405 responses should be disabled")
+
+	// Conflicts of host routes are reported by the parent
+	assert.NoError(router.Err())
+	assert.NotPanics(func() {
+		api.GET("/items/:id", func(c *Ctx) {})
+		api.GET("/items/:name", func(c *Ctx) {})
+	})
+	var conflict *RouteConflictError
+	assert.ErrorAs(router.Err(), &conflict)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/textproto"
 	"strconv"
 	"sync"
 	"time"
@@ -134,6 +135,7 @@ type Codec struct {
 	ContentLength int
 	Buf           *bytebufferpool.ByteBuffer
 	Router        interface{} // Using interface{} to avoid cyclic imports
+	Trailer       Header      // Trailer fields of the last parsed chunked request, nil if none
 }
 
 // StatusText returns a text for the HTTP status code.
@@ -206,6 +208,8 @@ var (
 
 // Parse parses HTTP request data.
 func (hc *Codec) Parse(data []byte) (int, []byte, error) {
+	hc.Trailer = nil
+
 	bodyOffset, err := hc.Parser.Parse(data)
 	if err != nil {
 		return 0, nil, err
@@ -231,6 +235,16 @@ func (hc *Codec) Parse(data []byte) (int, []byte, error) {
 	}
 
 	// Transfer-Encoding: chunked (less common case)
+	// When the header is present, walk the chunks so that trailer fields are handled correctly
+	if te := hc.Parser.FindHeader(transferEncodingHeader); te != nil && bytes.Contains(bytes.ToLower(te), chunkedValue) {
+		n, body, trailer, err := parseChunkedMessage(data[bodyOffset:])
+		if err != nil {
+			return 0, nil, err
+		}
+		hc.Trailer = trailer
+		return bodyOffset + n, body, nil
+	}
+
 	// Use a more efficient approach to find the last chunk marker
 	dataLen := len(data)
 	bodyData := data[bodyOffset:]
@@ -471,6 +485,85 @@ func parseChunkedBody(data []byte) ([]byte, error) {
 	return result, nil
 }
 
+// parseChunkedMessage walks a chunked body including its trailer section.
+// It returns the number of bytes consumed, the decoded body and the trailer fields (nil if none).
+// ErrIncompleteBody is returned when the data does not yet contain the whole message.
+func parseChunkedMessage(data []byte) (int, []byte, Header, error) {
+	var body []byte
+	var chunks int
+	i := 0
+
+	for {
+		// Find the end of the chunk size line
+		lineEnd := bytes.IndexByte(data[i:], '\n')
+		if lineEnd == -1 {
+			return 0, nil, nil, ErrIncompleteBody
+		}
+		line := bytes.TrimRight(data[i:i+lineEnd], "\r")
+		i += lineEnd + 1
+
+		// Ignore chunk extensions
+		if ext := bytes.IndexByte(line, ';'); ext != -1 {
+			line = line[:ext]
+		}
+
+		size, err := strconv.ParseInt(unsafeByteToString(bytes.TrimSpace(line)), 16, 32)
+		if err != nil || size < 0 {
+			return 0, nil, nil, ErrInvalidChunk
+		}
+
+		// The last chunk is followed by the trailer section
+		if size == 0 {
+			break
+		}
+
+		if i+int(size)+2 > len(data) {
+			return 0, nil, nil, ErrIncompleteBody
+		}
+		if data[i+int(size)] != '\r' || data[i+int(size)+1] != '\n' {
+			return 0, nil, nil, ErrInvalidChunk
+		}
+
+		// Avoid copying for the common single chunk case
+		chunk := data[i : i+int(size)]
+		switch chunks {
+		case 0:
+			body = chunk
+		case 1:
+			body = append(append(make([]byte, 0, len(body)+len(chunk)), body...), chunk...)
+		default:
+			body = append(body, chunk...)
+		}
+		chunks++
+		i += int(size) + 2
+	}
+
+	// Parse the trailer fields up to the terminating empty line
+	var trailer Header
+	for {
+		lineEnd := bytes.IndexByte(data[i:], '\n')
+		if lineEnd == -1 {
+			return 0, nil, nil, ErrIncompleteBody
+		}
+		line := bytes.TrimRight(data[i:i+lineEnd], "\r")
+		i += lineEnd + 1
+
+		if len(line) == 0 {
+			return i, body, trailer, nil
+		}
+
+		colon := bytes.IndexByte(line, ':')
+		if colon <= 0 {
+			return 0, nil, nil, ErrInvalidChunk
+		}
+		if trailer == nil {
+			trailer = make(Header)
+		}
+		key := textproto.CanonicalMIMEHeaderKey(string(bytes.TrimSpace(line[:colon])))
+		trailer[key] = append(trailer[key], string(bytes.TrimSpace(line[colon+1:])))
+	}
+}
+
 // Helper function to parse chunked body using standard library as a fallback
 func parseChunkedBodyFallback(data []byte) ([]byte, error) {
 	// Get a reader from the pool
@@ -569,6 +662,7 @@ func (hc *Codec) ResetParser() {
 func (hc *Codec) Reset() {
 	// Reset the parser
 	hc.ResetParser()
+	hc.Trailer = nil
 
 	// Clear the buffer
 	if hc.Buf != nil {
@@ -589,6 +683,18 @@ var ResponseBufferPool bytebufferpool.Pool
 
 // Common header constants to avoid allocations
 var (
+	// transferEncodingHeader is the Transfer-Encoding header name used to detect chunked requests
+	transferEncodingHeader = []byte("Transfer-Encoding")
+
+	// chunkedValue is the chunked transfer coding name
+	chunkedValue = []byte("chunked")
+
+	// chunkedHeader is the Transfer-Encoding header line for chunked responses
+	chunkedHeader = []byte("Transfer-Encoding: chunked\r\n")
+
+	// lastChunkBytes is the zero-length chunk that terminates a chunked body
+	lastChunkBytes = []byte("0\r\n")
+
 	// contentLengthPrefix is the prefix for the Content-Length header
 	contentLengthPrefix = []byte("Content-Length: ")
 
@@ -716,6 +822,110 @@ func (hc *Codec) WriteResponse(statusCode int, header Header, body []byte) {
 	}
 }
 
+// WriteHeadResponse writes the status line and headers of a response to a HEAD request.
+// The Content-Length header reports contentLength, the size of the body a GET request
+// would have received, unless header already has a Content-Length entry.
+func (hc *Codec) WriteHeadResponse(statusCode int, header Header, contentLength int) {
+	if hc.Buf == nil {
+		hc.Buf = ResponseBufferPool.Get()
+	} else {
+		hc.Buf.Reset()
+	}
+
+	// Write the status line
+	hc.Buf.Write(httpVersion)
+	if codeBytes, ok := statusCodeBytes[statusCode]; ok {
+		hc.Buf.Write(codeBytes)
+	} else {
+		hc.Buf.B = strconv.AppendInt(hc.Buf.B, int64(statusCode), 10)
+	}
+	hc.Buf.WriteByte(' ')
+	hc.Buf.WriteString(StatusText(statusCode))
+	hc.Buf.Write(crlfBytes)
+
+	// Add Date header
+	hc.Buf.Write(getDateHeader())
+
+	// Add custom headers
+	for k, values := range header {
+		for _, v := range values {
+			hc.Buf.WriteString(k)
+			hc.Buf.Write(colonSpace)
+			hc.Buf.WriteString(v)
+			hc.Buf.Write(crlfBytes)
+		}
+	}
+
+	// Add Content-Length header unless the handler set one
+	if _, ok := header["Content-Length"]; !ok {
+		hc.Buf.Write(contentLengthPrefix)
+		hc.Buf.B = strconv.AppendInt(hc.Buf.B, int64(contentLength), 10)
+		hc.Buf.Write(crlfBytes)
+	}
+
+	// The response ends with the headers
+	hc.Buf.Write(crlfBytes)
+}
+
+// WriteChunkedResponse writes an HTTP response with a chunked body followed by trailer fields.
+// Any Content-Length or Transfer-Encoding entries in header are ignored since the body is chunked.
+func (hc *Codec) WriteChunkedResponse(statusCode int, header Header, body []byte, trailer Header) {
+	if hc.Buf == nil {
+		hc.Buf = ResponseBufferPool.Get()
+	} else {
+		hc.Buf.Reset()
+	}
+
+	// Write the status line
+	hc.Buf.Write(httpVersion)
+	if codeBytes, ok := statusCodeBytes[statusCode]; ok {
+		hc.Buf.Write(codeBytes)
+	} else {
+		hc.Buf.B = strconv.AppendInt(hc.Buf.B, int64(statusCode), 10)
+	}
+	hc.Buf.WriteByte(' ')
+	hc.Buf.WriteString(StatusText(statusCode))
+	hc.Buf.Write(crlfBytes)
+
+	// Add Date header
+	hc.Buf.Write(getDateHeader())
+
+	// Add custom headers
+	for k, values := range header {
+		if k == "Content-Length" || k == "Transfer-Encoding" {
+			continue
+		}
+		for _, v := range values {
+			hc.Buf.WriteString(k)
+			hc.Buf.Write(colonSpace)
+			hc.Buf.WriteString(v)
+			hc.Buf.Write(crlfBytes)
+		}
+	}
+	hc.Buf.Write(chunkedHeader)
+	hc.Buf.Write(crlfBytes)
+
+	// Write the body as a single chunk
+	if len(body) > 0 {
+		hc.Buf.B = strconv.AppendInt(hc.Buf.B, int64(len(body)), 16)
+		hc.Buf.Write(crlfBytes)
+		hc.Buf.Write(body)
+		hc.Buf.Write(crlfBytes)
+	}
+
+	// Write the last chunk, the trailer fields and the final CRLF
+	hc.Buf.Write(lastChunkBytes)
+	for k, values := range trailer {
+		for _, v := range values {
+			hc.Buf.WriteString(k)
+			hc.Buf.Write(colonSpace)
+			hc.Buf.WriteString(v)
+			hc.Buf.Write(crlfBytes)
+		}
+	}
+	hc.Buf.Write(crlfBytes)
+}
+
 // codecPool is a pool of Codec objects for reuse
 var codecPool = pool.New(func() *Codec {
 	return &Codec{
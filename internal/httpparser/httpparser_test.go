@@ -1,6 +1,7 @@
 package httpparser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -143,6 +144,67 @@ func TestCodecParse(t *testing.T) {
 	assert.Equal(t, "Hello", string(body), "Body content should match")
 }
 
+// TestCodecParseChunkedTrailers tests parsing chunked requests with trailer fields
+func TestCodecParseChunkedTrailers(t *testing.T) {
+	hc := NewCodec(nil)
+
+	// Multiple chunks followed by trailers and a pipelined request
+	req := "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"5\r\nHello\r\n6;ext=1\r\n World\r\n0\r\nX-Checksum: abc\r\ngrpc-status: 0\r\n\r\n"
+	next := "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	n, body, err := hc.Parse([]byte(req + next))
+
+	assert.NoError(t, err, "Parse should not return error for chunked request with trailers")
+	assert.Equal(t, len(req), n, "Parse should stop at the end of the trailer section")
+	assert.Equal(t, "Hello World", string(body), "Chunks should be concatenated")
+	assert.Equal(t, Header{"X-Checksum": {"abc"}, "Grpc-Status": {"0"}}, hc.Trailer, "Trailers should be parsed")
+
+	// A chunked request without trailers leaves Trailer nil
+	hc.ResetParser()
+	_, _, err = hc.Parse([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, hc.Trailer, "Trailer should be nil when no trailers were sent")
+
+	// Incomplete trailer section
+	hc.ResetParser()
+	_, _, err = hc.Parse([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nX-Checksum: abc\r\n"))
+	assert.ErrorIs(t, err, ErrIncompleteBody, "Parse should report an incomplete body")
+}
+
+// TestCodecWriteChunkedResponse tests writing a chunked response with trailers
+func TestCodecWriteChunkedResponse(t *testing.T) {
+	hc := NewCodec(nil)
+
+	header := Header{
+		"Content-Type":   {"text/plain"},
+		"Content-Length": {"5"},
+		"Trailer":        {"X-Checksum"},
+	}
+	hc.WriteChunkedResponse(200, header, []byte("Hello"), Header{"X-Checksum": {"abc"}})
+
+	resp := string(hc.Buf.B)
+	assert.Contains(t, resp, "Transfer-Encoding: chunked\r\n", "Response should be chunked")
+	assert.Contains(t, resp, "Trailer: X-Checksum\r\n", "Response should declare trailers")
+	assert.NotContains(t, resp, "Content-Length", "Chunked responses must not have a Content-Length")
+	assert.True(t, strings.HasSuffix(resp, "\r\n\r\n5\r\nHello\r\n0\r\nX-Checksum: abc\r\n\r\n"), "Body and trailers should be chunk encoded")
+}
+
+// TestCodecWriteHeadResponse tests writing a response to a HEAD request
+func TestCodecWriteHeadResponse(t *testing.T) {
+	hc := NewCodec(nil)
+
+	hc.WriteHeadResponse(200, Header{"Content-Type": {"text/plain"}}, 5)
+	resp := string(hc.Buf.B)
+	assert.Contains(t, resp, "Content-Length: 5\r\n", "Content-Length should report the GET body size")
+	assert.True(t, strings.HasSuffix(resp, "\r\n\r\n"), "Response should end with the headers")
+
+	// A Content-Length set by the handler is kept
+	hc.WriteHeadResponse(200, Header{"Content-Length": {"1024"}}, 0)
+	resp = string(hc.Buf.B)
+	assert.Contains(t, resp, "Content-Length: 1024\r\n", "Handler Content-Length should be kept")
+	assert.Equal(t, 1, strings.Count(resp, "Content-Length"), "Content-Length should be written once")
+}
+
 // TestParserReset tests that the parser can be reset
 func TestParserReset(t *testing.T) {
 	// Create a new Codec
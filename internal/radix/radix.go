@@ -1,80 +1,13 @@
+// Package radix parses route patterns into segments, which the router compiles into
+// its route trees.
 package radix
 
 import (
-	"github.com/ryanbekhen/ngebut/internal/unsafe"
+	"strconv"
 	"strings"
-	"sync"
 )
 
-// PathMatchContext is a reusable context for path matching operations
-// It pre-allocates memory for common operations to reduce allocations
-type PathMatchContext struct {
-	// Segments for path matching
-	segments []string
-
-	// Temporary byte slice for path operations
-	pathBytes []byte
-
-	// Reusable parameter map
-	params map[string]string
-}
-
-// Reset resets the context for reuse
-func (c *PathMatchContext) Reset() {
-	// Clear segments without deallocating
-	c.segments = c.segments[:0]
-
-	// Clear pathBytes without deallocating
-	c.pathBytes = c.pathBytes[:0]
-
-	// Clear params without deallocating
-	for k := range c.params {
-		delete(c.params, k)
-	}
-}
-
-// pathMatchContextPool is a pool of PathMatchContext objects
-var pathMatchContextPool = sync.Pool{
-	New: func() interface{} {
-		return &PathMatchContext{
-			segments:  make([]string, 0, 16),      // Pre-allocate for common path depth
-			pathBytes: make([]byte, 0, 128),       // Pre-allocate for common path length
-			params:    make(map[string]string, 8), // Pre-allocate for common number of params
-		}
-	},
-}
-
-// getPathMatchContext gets a PathMatchContext from the pool
-func getPathMatchContext() *PathMatchContext {
-	return pathMatchContextPool.Get().(*PathMatchContext)
-}
-
-// releasePathMatchContext returns a PathMatchContext to the pool
-func releasePathMatchContext(ctx *PathMatchContext) {
-	ctx.Reset()
-	pathMatchContextPool.Put(ctx)
-}
-
-// segmentsPool is a pool of string slices for reuse when splitting paths
-var segmentsPool = sync.Pool{
-	New: func() interface{} {
-		return make([]string, 0, 16) // Pre-allocate with capacity for 16 segments
-	},
-}
-
-// getSegments gets a segments slice from the pool
-func getSegments() []string {
-	return segmentsPool.Get().([]string)
-}
-
-// releaseSegments returns a segments slice to the pool
-func releaseSegments(s []string) {
-	// Clear the slice without deallocating
-	s = s[:0]
-	segmentsPool.Put(s)
-}
-
-// Kind represents the type of node in the radix tree
+// Kind represents the type of a route pattern segment
 type Kind uint8
 
 const (
@@ -86,526 +19,118 @@ const (
 	Wildcard
 )
 
-// Node represents a node in the radix tree
-type Node struct {
-	// Path is the path segment this node represents
-	Path string
-	// Kind is the type of node (static, param, wildcard)
-	Kind Kind
-	// Children are the child nodes
-	Children []*Node
-	// Handlers are the handlers for this node, indexed by HTTP method
-	Handlers map[string]interface{}
-	// ParamName is the name of the parameter (for Param nodes)
-	ParamName string
-	// IsEnd indicates if this node is the end of a route
-	IsEnd bool
-}
-
-// NewNode creates a new radix tree node
-func NewNode() *Node {
-	return &Node{
-		Children: make([]*Node, 0),
-		Handlers: make(map[string]interface{}),
+// SplitParam splits a parameter segment such as ":id<int>" into the parameter name
+// and the constraint expression. The leading colon is optional.
+func SplitParam(segment string) (name, constraint string) {
+	if len(segment) > 0 && segment[0] == ':' {
+		segment = segment[1:]
 	}
-}
-
-// Tree represents a radix tree for routing
-type Tree struct {
-	Root *Node
-}
-
-// NewTree creates a new radix tree
-func NewTree() *Tree {
-	return &Tree{
-		Root: NewNode(),
+	if i := strings.IndexByte(segment, '<'); i >= 0 && segment[len(segment)-1] == '>' {
+		return segment[:i], segment[i+1 : len(segment)-1]
 	}
+	return segment, ""
 }
 
-// Insert adds a route to the radix tree
-func (t *Tree) Insert(path string, method string, handler interface{}) {
-	if path == "" {
-		return
-	}
-
-	// Ensure path starts with /
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-
-	// Split the path into segments
-	segments := splitPath(path)
-	defer releaseSegments(segments) // Release the segments slice back to the pool when done
-
-	// Start at the root node
-	current := t.Root
-
-	// Traverse the tree and insert nodes as needed
-	for i, segment := range segments {
-		if segment == "" {
+// Segment describes a segment of a route pattern.
+type Segment struct {
+	// Path is the segment as written in the pattern
+	Path string
+	// Kind is the type of the segment
+	Kind Kind
+	// Name is the parameter name (for Param and Wildcard segments)
+	Name string
+	// Constraint is the parameter constraint expression, e.g. "int" for :id<int>
+	Constraint string
+	// Optional indicates that the parameter may be omitted, e.g. :name?
+	Optional bool
+	// OneOrMore indicates a + wildcard, which must match at least one character
+	OneOrMore bool
+}
+
+// ParsePattern splits a route pattern into its segments.
+// Parameters are written :name, :name<constraint> or :name? for optional parameters.
+// Wildcards are written * (zero or more characters) or + (one or more characters),
+// optionally followed by a name such as *path. Unnamed wildcards are named after their
+// symbol: the first * is named "*", the following ones "*2", "*3" and so on, likewise for +.
+func ParsePattern(pattern string) []Segment {
+	segments := make([]Segment, 0, 8)
+	stars, pluses := 0, 0
+
+	for _, part := range strings.Split(pattern, "/") {
+		if part == "" {
 			continue
 		}
 
-		// Determine the kind of segment
-		var kind Kind
-		var paramName string
-
-		if segment[0] == ':' {
-			kind = Param
-			paramName = segment[1:] // Remove the : prefix
-		} else if segment == "*" {
-			kind = Wildcard
-		} else {
-			kind = Static
-		}
-
-		// Look for an existing child node that matches
-		var matchingChild *Node
-		for _, child := range current.Children {
-			if child.Kind == kind && (kind != Static || child.Path == segment) {
-				if kind == Param && child.ParamName != paramName {
-					continue
-				}
-				matchingChild = child
-				break
-			}
-		}
-
-		// If no matching child was found, create a new one
-		if matchingChild == nil {
-			matchingChild = &Node{
-				Path:      segment,
-				Kind:      kind,
-				Children:  make([]*Node, 0),
-				Handlers:  make(map[string]interface{}),
-				ParamName: paramName,
+		segment := Segment{Path: part, Kind: Static}
+		switch {
+		case part[0] == ':':
+			segment.Kind = Param
+			name := part
+			if strings.HasSuffix(name, "?") {
+				segment.Optional = true
+				name = name[:len(name)-1]
 			}
-			current.Children = append(current.Children, matchingChild)
-		}
-
-		// Move to the matching child
-		current = matchingChild
-
-		// If this is the last segment, mark it as the end of a route
-		if i == len(segments)-1 {
-			current.IsEnd = true
-			current.Handlers[method] = handler
-		}
-	}
-}
-
-// Find searches for a route in the radix tree
-func (t *Tree) Find(path string, params map[string]string) (map[string]interface{}, bool) {
-	if path == "" {
-		return nil, false
-	}
-
-	// Ensure path starts with /
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-
-	// Split the path into segments
-	segments := splitPath(path)
-
-	// Start at the root node
-	current := t.Root
-
-	// Traverse the tree to find the matching node
-	result, found := findNode(current, segments, 0, params)
-
-	// Release the segments slice back to the pool
-	releaseSegments(segments)
-
-	return result, found
-}
-
-// FindBytesWithContext searches for a route in the radix tree using a byte slice path and a pre-allocated context
-// This is the most optimized version that avoids all allocations
-func (t *Tree) FindBytesWithContext(path []byte, ctx *PathMatchContext) (map[string]interface{}, bool) {
-	if len(path) == 0 {
-		return nil, false
-	}
-
-	// Use the pre-allocated segments slice
-	segments := ctx.segments
-
-	// Handle path directly as bytes to avoid string conversion
-	if path[0] != '/' {
-		// For paths without leading slash, add an empty segment at the beginning
-		segments = append(segments, "")
-
-		// Split the path manually to avoid allocations
-		start := 0
-		for i := 0; i < len(path); i++ {
-			if path[i] == '/' {
-				// Add segment to the slice using unsafe for zero-allocation conversion
-				if i > start {
-					segments = append(segments, unsafe.B2S(path[start:i]))
-				} else {
-					segments = append(segments, "")
+			segment.Name, segment.Constraint = SplitParam(name)
+		case (part[0] == '*' || part[0] == '+') && isWildcardName(part[1:]):
+			segment.Kind = Wildcard
+			segment.OneOrMore = part[0] == '+'
+			segment.Name = part[1:]
+			if segment.Name == "" {
+				count := &stars
+				if segment.OneOrMore {
+					count = &pluses
 				}
-				start = i + 1
-			}
-		}
-
-		// Add the last segment
-		if start < len(path) {
-			segments = append(segments, unsafe.B2S(path[start:]))
-		} else if start == len(path) {
-			segments = append(segments, "")
-		}
-	} else {
-		// Path already has leading slash, use normal splitPath logic but inline it
-		// to avoid an extra function call and string conversion
-
-		// Remove trailing slash if present
-		pathLen := len(path)
-		if pathLen > 1 && path[pathLen-1] == '/' {
-			pathLen--
-			path = path[:pathLen]
-		}
-
-		// Split the path manually to avoid allocations
-		start := 1 // Start after the leading slash
-		for i := 1; i < pathLen; i++ {
-			if path[i] == '/' {
-				// Add segment to the slice using unsafe for zero-allocation conversion
-				if i > start {
-					segments = append(segments, unsafe.B2S(path[start:i]))
-				} else {
-					segments = append(segments, "")
+				*count++
+				segment.Name = part[:1]
+				if *count > 1 {
+					segment.Name += strconv.Itoa(*count)
 				}
-				start = i + 1
 			}
 		}
 
-		// Add the last segment
-		if start < pathLen {
-			segments = append(segments, unsafe.B2S(path[start:pathLen]))
-		} else if start == pathLen {
-			segments = append(segments, "")
-		}
+		segments = append(segments, segment)
 	}
 
-	// Update the context's segments
-	ctx.segments = segments
-
-	// Start at the root node
-	current := t.Root
-
-	// Traverse the tree to find the matching node
-	result, found := findNode(current, segments, 0, ctx.params)
-
-	return result, found
+	return segments
 }
 
-// FindBytes searches for a route in the radix tree using a byte slice path
-// This avoids string conversion when processing HTTP requests
-func (t *Tree) FindBytes(path []byte, params map[string]string) (map[string]interface{}, bool) {
-	if len(path) == 0 {
-		return nil, false
-	}
-
-	// Get a path match context from the pool
-	ctx := getPathMatchContext()
-	defer releasePathMatchContext(ctx)
-
-	// Use the pre-allocated segments slice
-	segments := ctx.segments
-
-	// Handle path directly as bytes to avoid string conversion
-	if path[0] != '/' {
-		// For paths without leading slash, add an empty segment at the beginning
-		segments = append(segments, "")
-
-		// Split the path manually to avoid allocations
-		start := 0
-		for i := 0; i < len(path); i++ {
-			if path[i] == '/' {
-				// Add segment to the slice using unsafe for zero-allocation conversion
-				if i > start {
-					segments = append(segments, unsafe.B2S(path[start:i]))
-				} else {
-					segments = append(segments, "")
-				}
-				start = i + 1
-			}
-		}
-
-		// Add the last segment
-		if start < len(path) {
-			segments = append(segments, unsafe.B2S(path[start:]))
-		} else if start == len(path) {
-			segments = append(segments, "")
-		}
-	} else {
-		// Path already has leading slash, use normal splitPath logic but inline it
-		// to avoid an extra function call and string conversion
-
-		// Remove trailing slash if present
-		pathLen := len(path)
-		if pathLen > 1 && path[pathLen-1] == '/' {
-			pathLen--
-			path = path[:pathLen]
-		}
-
-		// Split the path manually to avoid allocations
-		start := 1 // Start after the leading slash
-		for i := 1; i < pathLen; i++ {
-			if path[i] == '/' {
-				// Add segment to the slice using unsafe for zero-allocation conversion
-				if i > start {
-					segments = append(segments, unsafe.B2S(path[start:i]))
-				} else {
-					segments = append(segments, "")
-				}
-				start = i + 1
-			}
-		}
-
-		// Add the last segment
-		if start < pathLen {
-			segments = append(segments, unsafe.B2S(path[start:pathLen]))
-		} else if start == pathLen {
-			segments = append(segments, "")
+// isWildcardName reports whether s can be used as the name of a wildcard.
+func isWildcardName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
 		}
 	}
-
-	// Update the context's segments
-	ctx.segments = segments
-
-	// Start at the root node
-	current := t.Root
-
-	// Traverse the tree to find the matching node
-	result, found := findNode(current, segments, 0, params)
-
-	return result, found
-}
-
-// FindStatic searches for a static route in the radix tree without parameter extraction
-// This is an optimization for routes without parameters
-func (t *Tree) FindStatic(path string) (map[string]interface{}, bool) {
-	if path == "" {
-		return nil, false
-	}
-
-	// Ensure path starts with /
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-
-	// Split the path into segments
-	segments := splitPath(path)
-
-	// Start at the root node
-	current := t.Root
-
-	// Traverse the tree to find the matching node
-	result, found := findStaticNode(current, segments, 0)
-
-	// Release the segments slice back to the pool
-	releaseSegments(segments)
-
-	return result, found
+	return true
 }
 
-// FindStaticBytes searches for a static route in the radix tree using a byte slice path
-// This avoids string conversion when processing HTTP requests
-func (t *Tree) FindStaticBytes(path []byte) (map[string]interface{}, bool) {
-	if len(path) == 0 {
-		return nil, false
+// ExpandOptional returns the patterns matched by a pattern with optional parameters,
+// one for each combination of present and omitted optional parameters.
+// A pattern without optional parameters is returned as is.
+func ExpandOptional(pattern string) []string {
+	if !strings.Contains(pattern, "?") {
+		return []string{pattern}
 	}
 
-	// Get a path match context from the pool
-	ctx := getPathMatchContext()
-	defer releasePathMatchContext(ctx)
-
-	// Use the pre-allocated segments slice
-	segments := ctx.segments
-
-	// Handle path directly as bytes to avoid string conversion
-	if path[0] != '/' {
-		// For paths without leading slash, add an empty segment at the beginning
-		segments = append(segments, "")
-
-		// Split the path manually to avoid allocations
-		start := 0
-		for i := 0; i < len(path); i++ {
-			if path[i] == '/' {
-				// Add segment to the slice using unsafe for zero-allocation conversion
-				if i > start {
-					segments = append(segments, unsafe.B2S(path[start:i]))
-				} else {
-					segments = append(segments, "")
-				}
-				start = i + 1
-			}
-		}
-
-		// Add the last segment
-		if start < len(path) {
-			segments = append(segments, unsafe.B2S(path[start:]))
-		} else if start == len(path) {
-			segments = append(segments, "")
-		}
-	} else {
-		// Path already has leading slash, use normal splitPath logic but inline it
-		// to avoid an extra function call and string conversion
-
-		// Remove trailing slash if present
-		pathLen := len(path)
-		if pathLen > 1 && path[pathLen-1] == '/' {
-			pathLen--
-			path = path[:pathLen]
-		}
-
-		// Split the path manually to avoid allocations
-		start := 1 // Start after the leading slash
-		for i := 1; i < pathLen; i++ {
-			if path[i] == '/' {
-				// Add segment to the slice using unsafe for zero-allocation conversion
-				if i > start {
-					segments = append(segments, unsafe.B2S(path[start:i]))
-				} else {
-					segments = append(segments, "")
-				}
-				start = i + 1
+	variants := []string{""}
+	for _, segment := range ParsePattern(pattern) {
+		if segment.Optional {
+			path := segment.Path[:len(segment.Path)-1]
+			for i, n := 0, len(variants); i < n; i++ {
+				variants = append(variants, variants[i]+"/"+path)
 			}
+			continue
 		}
-
-		// Add the last segment
-		if start < pathLen {
-			segments = append(segments, unsafe.B2S(path[start:pathLen]))
-		} else if start == pathLen {
-			segments = append(segments, "")
-		}
-	}
-
-	// Update the context's segments
-	ctx.segments = segments
-
-	// Start at the root node
-	current := t.Root
-
-	// Traverse the tree to find the matching node
-	result, found := findStaticNode(current, segments, 0)
-
-	return result, found
-}
-
-// findStaticNode recursively searches for a matching static node
-// This is an optimization that avoids parameter extraction
-func findStaticNode(node *Node, segments []string, index int) (map[string]interface{}, bool) {
-	// If we've processed all segments, check if this is a valid endpoint
-	if index >= len(segments) {
-		if node.IsEnd {
-			return node.Handlers, true
-		}
-		return nil, false
-	}
-
-	segment := segments[index]
-	if segment == "" {
-		// Skip empty segments
-		return findStaticNode(node, segments, index+1)
-	}
-
-	// Only check static nodes for better performance
-	for _, child := range node.Children {
-		if child.Kind == Static && child.Path == segment {
-			return findStaticNode(child, segments, index+1)
-		}
-	}
-
-	// No static match found
-	return nil, false
-}
-
-// findNode recursively searches for a matching node
-func findNode(node *Node, segments []string, index int, params map[string]string) (map[string]interface{}, bool) {
-	// If we've processed all segments, check if this is a valid endpoint
-	if index >= len(segments) {
-		if node.IsEnd {
-			return node.Handlers, true
-		}
-		return nil, false
-	}
-
-	segment := segments[index]
-	if segment == "" {
-		// Skip empty segments
-		return findNode(node, segments, index+1, params)
-	}
-
-	// Single pass through children with early returns for better performance
-	for _, child := range node.Children {
-		switch child.Kind {
-		case Static:
-			// Static nodes must match the segment exactly
-			if child.Path == segment {
-				return findNode(child, segments, index+1, params)
-			}
-		case Param:
-			// Parameter nodes match any segment
-			// Store the parameter value using unsafe conversion if possible
-			if params != nil {
-				// Use direct string assignment since segment is already a string
-				// The compiler should optimize this
-				params[child.ParamName] = segment
-			}
-			return findNode(child, segments, index+1, params)
-		case Wildcard:
-			// Wildcard matches all remaining segments
-			if params != nil && child.ParamName != "" {
-				// Join remaining segments if this is a named wildcard
-				remainingPath := strings.Join(segments[index:], "/")
-				params[child.ParamName] = remainingPath
-			}
-			return child.Handlers, child.IsEnd
+		for i := range variants {
+			variants[i] += "/" + segment.Path
 		}
 	}
 
-	// No match found
-	return nil, false
-}
-
-// splitPath splits a path into segments
-func splitPath(path string) []string {
-	// Remove trailing slash if present
-	if len(path) > 1 && path[len(path)-1] == '/' {
-		path = path[:len(path)-1]
-	}
-
-	// Get a segments slice from the pool
-	segments := getSegments()
-
-	// Convert path to byte slice without allocation
-	pathBytes := unsafe.S2B(path)
-
-	// Split the path manually to avoid allocations
-	start := 0
-	for i := 0; i < len(pathBytes); i++ {
-		if pathBytes[i] == '/' {
-			// Add segment to the slice
-			if i > start {
-				// Use unsafe to avoid allocation when slicing
-				segments = append(segments, unsafe.B2S(pathBytes[start:i]))
-			} else {
-				segments = append(segments, "")
-			}
-			start = i + 1
+	for i := range variants {
+		if variants[i] == "" {
+			variants[i] = "/"
 		}
 	}
-
-	// Add the last segment
-	if start < len(pathBytes) {
-		// Use unsafe to avoid allocation when slicing
-		segments = append(segments, unsafe.B2S(pathBytes[start:]))
-	} else if start == len(pathBytes) {
-		segments = append(segments, "")
-	}
-
-	return segments
+	return variants
 }
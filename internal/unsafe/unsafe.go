@@ -17,3 +17,10 @@ func B2S(b []byte) string {
 func S2B(s string) []byte {
 	return unsafe.Slice((*byte)(unsafe.StringData(s)), len(s))
 }
+
+// FuncValue returns the address of the function value of fn, which must be a func.
+// Unlike the code pointer, it is different for each closure created by the same
+// function literal.
+func FuncValue[F any](fn F) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&fn))
+}
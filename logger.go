@@ -2,6 +2,8 @@ package ngebut
 
 import (
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/ryanbekhen/ngebut/log"
 )
@@ -39,8 +41,10 @@ func initLogger(level log.Level) {
 	log.SetLevel(logger.GetLevel())
 }
 
-// displayStartupMessage displays a startup message with server information
-func displayStartupMessage(addr string) {
+// displayStartupMessage displays a startup message with server information.
+// If routes is not empty, the route table is printed.
+// In prefork mode, the PIDs of the child processes are listed.
+func displayStartupMessage(addr string, routes []RouteInfo, childPIDs ...int) {
 	logger.Info().Msg("  _   _            _           _")
 	logger.Info().Msg(" | \\ | | __ _  ___| |__  _   _| |_ ")
 	logger.Info().Msg(" |  \\| |/ _` |/ _ \\ '_ \\| | | | __|")
@@ -49,6 +53,19 @@ func displayStartupMessage(addr string) {
 	logger.Info().Msg("        |___/")
 	logger.Info().Msg(" ")
 	logger.Info().Msgf("Server is running on %s", addr)
+	if len(childPIDs) > 0 {
+		pids := make([]string, len(childPIDs))
+		for i, pid := range childPIDs {
+			pids[i] = strconv.Itoa(pid)
+		}
+		logger.Info().Msgf("Prefork: %d child processes (PIDs: %s)", len(childPIDs), strings.Join(pids, ", "))
+	}
 	logger.Info().Msg("Press Ctrl+C to stop the server")
 	logger.Info().Msg(" ")
+	if len(routes) > 0 {
+		for _, line := range formatRouteTable(routes) {
+			logger.Info().Msg(line)
+		}
+		logger.Info().Msg(" ")
+	}
 }
@@ -97,7 +97,7 @@ func TestDisplayStartupMessage(t *testing.T) {
 
 	// Call the function with a test address
 	addr := ":8080"
-	displayStartupMessage(addr)
+	displayStartupMessage(addr, nil)
 
 	// Check that the output contains the expected messages
 	output := buf.String()
@@ -158,3 +158,44 @@ func TestLoggerIntegration(t *testing.T) {
 	assert.Contains(t, output, "This is an error message",
 		"Logger output should contain the error message")
 }
+
+// TestDisplayStartupMessagePrefork tests that child PIDs are reported in prefork mode
+func TestDisplayStartupMessagePrefork(t *testing.T) {
+	originalLogger := logger
+	defer func() {
+		logger = originalLogger
+	}()
+
+	var buf bytes.Buffer
+	console := log.DefaultConsoleWriter()
+	console.Out = &buf
+	logger = log.New(console, log.InfoLevel)
+
+	displayStartupMessage(":8080", nil, 101, 102)
+
+	assert.Contains(t, buf.String(), "Prefork: 2 child processes (PIDs: 101, 102)",
+		"Output should contain the child PIDs")
+}
+
+// TestDisplayStartupMessageRoutes tests that the route table is printed when routes are given
+func TestDisplayStartupMessageRoutes(t *testing.T) {
+	originalLogger := logger
+	defer func() {
+		logger = originalLogger
+	}()
+
+	var buf bytes.Buffer
+	console := log.DefaultConsoleWriter()
+	console.Out = &buf
+	logger = log.New(console, log.InfoLevel)
+
+	displayStartupMessage(":8080", []RouteInfo{
+		{Method: MethodGet, Pattern: "/users/:id", Name: "user", Handlers: 2},
+		{Method: MethodDelete, Pattern: "/users/:id", Handlers: 1},
+	})
+
+	output := buf.String()
+	assert.Contains(t, output, "METHOD  PATTERN     NAME  HANDLERS", "Output should contain the table header")
+	assert.Contains(t, output, "GET     /users/:id  user  2", "Output should contain the named route")
+	assert.Contains(t, output, "DELETE  /users/:id        1", "Output should contain the unnamed route")
+}
@@ -1,8 +1,14 @@
 package ngebut
 
 import (
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCompileMiddleware(t *testing.T) {
@@ -14,12 +20,12 @@ func TestCompileMiddleware(t *testing.T) {
 	// Create test middleware
 	middleware1 := func(c *Ctx) {
 		c.Set("X-Middleware-1", "true")
-		// In compiled middleware, we don't call c.Next()
+		// The chain continues without c.Next()
 	}
 
 	middleware2 := func(c *Ctx) {
 		c.Set("X-Middleware-2", "true")
-		// In compiled middleware, we don't call c.Next()
+		// The chain continues without c.Next()
 	}
 
 	// Compile the middleware and handler
@@ -51,6 +57,66 @@ func TestCompileMiddleware(t *testing.T) {
 	}
 }
 
+// TestCompileMiddlewareNext tests that middleware calling Next runs the rest of the chain
+func TestCompileMiddlewareNext(t *testing.T) {
+	assert := assert.New(t)
+
+	var trace []string
+	var statusCode int
+	router := NewRouter()
+	router.Use(func(c *Ctx) {
+		trace = append(trace, "outer")
+		c.Next()
+		statusCode = c.StatusCode()
+		trace = append(trace, "outer done")
+	})
+	router.Use(func(c *Ctx) {
+		trace = append(trace, "inner")
+	})
+	router.GET("/", func(c *Ctx) {
+		trace = append(trace, "handler")
+		c.Status(StatusCreated).String("created")
+	})
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	ctx := GetContext(w, req)
+	defer ReleaseContext(ctx)
+	router.ServeHTTP(ctx, ctx.Request)
+	ctx.Writer.Flush()
+
+	assert.Equal([]string{"outer", "inner", "handler", "outer done"}, trace, "the handler should run before Next returns")
+	assert.Equal(StatusCreated, statusCode, "the middleware should see the status after Next")
+	assert.Equal("created", w.Body.String())
+}
+
+// TestCtxAbort tests ending the middleware chain without an error
+func TestCtxAbort(t *testing.T) {
+	assert := assert.New(t)
+
+	var trace []string
+	router := NewRouter()
+	router.Use(func(c *Ctx) {
+		trace = append(trace, "abort")
+		c.String("answered")
+		c.Abort()
+		c.Next()
+	})
+	router.Use(func(c *Ctx) { trace = append(trace, "next") })
+	router.GET("/", func(c *Ctx) { trace = append(trace, "handler") })
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	ctx := GetContext(w, req)
+	defer ReleaseContext(ctx)
+	router.ServeHTTP(ctx, ctx.Request)
+	ctx.Writer.Flush()
+
+	assert.Equal([]string{"abort"}, trace, "the chain should end, even when Next is called")
+	assert.Equal(StatusOK, w.Code)
+	assert.Equal("answered", w.Body.String())
+}
+
 func BenchmarkCompileMiddleware(b *testing.B) {
 	// Create a test handler
 	handler := func(c *Ctx) {
@@ -138,3 +204,140 @@ func BenchmarkDynamicMiddleware(b *testing.B) {
 		ReleaseContext(ctx)
 	}
 }
+
+// TestRouterPathScopedMiddleware tests middleware added for a path prefix
+func TestRouterPathScopedMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "app.js"), []byte("js"), 0o644))
+	assert.NoError(os.Mkdir(filepath.Join(dir, "private"), 0o755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "private", "s.txt"), []byte("secret"), 0o644))
+
+	router := NewRouter()
+	trace := func(name string) Middleware {
+		return func(c *Ctx) {
+			c.Set("X-Trace", strings.TrimPrefix(c.Get("X-Trace")+","+name, ","))
+		}
+	}
+	router.Use(trace("global"))
+	router.Use("/admin", trace("admin"))
+	router.Use(trace("last"))
+	router.Use("assets/", trace("assets"))
+	router.Use("/assets/private", func(c *Ctx) { c.Status(StatusForbidden).String("forbidden") })
+
+	router.GET("/admin", func(c *Ctx) { c.String("admin") })
+	router.GET("/admin/users", func(c *Ctx) { c.String("users") })
+	router.GET("/administrator", func(c *Ctx) { c.String("administrator") })
+	router.GET("/", func(c *Ctx) { c.String("home") })
+	router.STATIC("/assets", dir)
+
+	admin := router.Group("/admin")
+	admin.Use("/reports", trace("reports"))
+	admin.Use(trace("group"))
+	admin.GET("/reports", func(c *Ctx) { c.String("reports") })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Middleware runs in the order it was added
+	assert.Equal("global,admin,last", serve("/admin").Header().Get("X-Trace"))
+	assert.Equal("global,admin,last", serve("/admin/users").Header().Get("X-Trace"))
+	assert.Equal("global,last", serve("/administrator").Header().Get("X-Trace"), "prefixes should match whole segments")
+	assert.Equal("global,last", serve("/").Header().Get("X-Trace"))
+
+	// Requests that match no route
+	w := serve("/admin/missing")
+	assert.Equal(StatusNotFound, w.Code)
+	assert.Equal("global,admin,last", w.Header().Get("X-Trace"))
+
+	// Static files
+	w = serve("/assets/app.js")
+	assert.Equal("js", w.Body.String())
+	assert.Equal("global,last,assets", w.Header().Get("X-Trace"))
+
+	// Repeated slashes don't skip the middleware of static files
+	assert.Equal(StatusForbidden, serve("/assets/private/s.txt").Code)
+	assert.Equal(StatusForbidden, serve("/assets//private/s.txt").Code, "repeated slashes should not skip the middleware")
+	assert.Equal(StatusForbidden, serve("//assets/private//s.txt").Code)
+
+	// Group middleware runs after the router middleware, prefixes are relative to the group
+	assert.Equal("global,admin,last,reports,group", serve("/admin/reports").Header().Get("X-Trace"))
+
+	assert.Panics(func() { router.Use("/admin") }, "a prefix without middleware should panic")
+}
+
+// TestRouterPathScopedMiddlewareResolved tests choosing the path-scoped middleware of
+// routes when the route table is built
+func TestRouterPathScopedMiddlewareResolved(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(c *Ctx) {
+			order = append(order, name)
+			c.Next()
+		}
+	}
+	handler := func(c *Ctx) {}
+
+	router.GET("/admin/users/:id", handler)
+	router.GET("/:section/settings", handler)
+	router.GET("/public", handler)
+	router.GET("/users/:id/admin", handler)
+	router.Use("/admin", trace("admin"))
+	router.Use("/users/1/admin", trace("user"))
+	router.Use(trace("global"))
+
+	serve := func(path string) []string {
+		order = nil
+		req, _ := http.NewRequest(MethodGet, "http://example.com/", nil)
+		req.URL.Path = path
+		ctx := GetContext(httptest.NewRecorder(), req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		return order
+	}
+
+	// Middleware added after the routes applies to them
+	assert.Equal([]string{"admin", "global"}, serve("/admin/users/1"))
+	assert.Equal([]string{"global"}, serve("/public"))
+
+	// The static segments of the pattern decide, whatever the path looks like
+	assert.Equal([]string{"admin", "global"}, serve("//admin/users/1"), "repeated slashes should not skip the middleware")
+
+	// Routes whose parameters decide fall back to the request path
+	assert.Equal([]string{"admin", "global"}, serve("/admin/settings"))
+	assert.Equal([]string{"global"}, serve("/account/settings"))
+	assert.Equal([]string{"admin", "global"}, serve("//admin/settings"), "repeated slashes should not skip the middleware")
+	assert.Equal([]string{"admin", "global"}, serve("/admin//settings"))
+	assert.Equal([]string{"user", "global"}, serve("/users/1/admin"))
+	assert.Equal([]string{"user", "global"}, serve("/users//1/admin"))
+	assert.Equal([]string{"user", "global"}, serve("/users/1//admin"))
+	assert.Equal([]string{"global"}, serve("/users/2/admin"))
+
+	// Case insensitive routing matches the prefix ignoring case
+	router.CaseSensitive = false
+	assert.Equal([]string{"admin", "global"}, serve("/Admin/settings"))
+	router.CaseSensitive = true
+
+	table := router.routeTable()
+	var values routeValues
+	leaf := table.trees[MethodGet].match("/admin/users/1", 0, &values)
+	if assert.NotNil(leaf) {
+		assert.True(leaf.resolved)
+		assert.Len(leaf.middleware, 2)
+	}
+	leaf = table.trees[MethodGet].match("/admin/settings", 0, &values)
+	if assert.NotNil(leaf) {
+		assert.False(leaf.resolved, "parameters under the prefix should be resolved for each request")
+	}
+}
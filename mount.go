@@ -0,0 +1,112 @@
+package ngebut
+
+import (
+	"sort"
+	"strings"
+)
+
+// mountedRouter is a router serving the requests for the paths under a prefix.
+type mountedRouter struct {
+	prefix   string // Path prefix without a trailing slash, empty when mounted at the root
+	parent   *Router
+	router   *Router
+	handlers []Handler // Serves the request with router, wrapped by the parent middleware
+}
+
+// Mount serves the requests for prefix and the paths under it with child.
+// The prefix is stripped from the path before the child routes are matched, so a route
+// "/users" of a child mounted at "/admin" serves "/admin/users". Handlers of the child
+// can read the prefix with Ctx.MountPath and Ctx.BaseURL.
+//
+// Unlike a group, the child keeps its own middleware, NotFound handler and ErrorHandler.
+// The middleware of the parent router runs before it, and errors are handled by the
+// parent's error handler when the child has no ErrorHandler.
+// The child takes the routing options of the parent, such as StrictRouting and
+// CaseSensitive, when it is mounted; options set on the child afterwards apply to it.
+// Mounted routers take precedence over the routes of the parent, and the longest
+// matching prefix is used when mounted routers are nested in each other's prefixes.
+// It panics if the prefix has parameters or wildcards.
+//
+// Example:
+//
+//	admin := ngebut.NewRouter()
+//	admin.GET("/users", listUsers)
+//	router.Mount("/admin", admin)
+func (r *Router) Mount(prefix string, child *Router) *Router {
+	if strings.ContainsAny(prefix, ":*+?") {
+		panic("mount prefix must be a static path: " + prefix)
+	}
+	if prefix == "" || prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+	prefix = strings.TrimRight(prefix, "/")
+
+	m := &mountedRouter{
+		prefix: prefix,
+		parent: r,
+		router: child,
+	}
+	m.handlers = []Handler{m.serve}
+	r.inheritOptions(child)
+
+	r.mounts = append(r.mounts, m)
+
+	// The longest prefix is tried first
+	sort.SliceStable(r.mounts, func(i, j int) bool {
+		return len(r.mounts[i].prefix) > len(r.mounts[j].prefix)
+	})
+
+	return r
+}
+
+// matchMount returns the mounted router for path, or nil if no prefix matches.
+func (r *Router) matchMount(path string) *mountedRouter {
+	for _, m := range r.mounts {
+		if !strings.HasPrefix(path, m.prefix) {
+			continue
+		}
+		if len(path) == len(m.prefix) || path[len(m.prefix)] == '/' {
+			return m
+		}
+	}
+	return nil
+}
+
+// serve calls the mounted router with the prefix stripped from the request path,
+// and restores the path for the parent middleware afterwards.
+func (m *mountedRouter) serve(c *Ctx) {
+	req := c.Request
+	path, mountPath, baseURL := req.URL.Path, c.mountPath, c.baseURL
+
+	req.URL.Path = path[len(m.prefix):]
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	c.mountPath = m.prefix
+	if c.mountPath == "" {
+		c.mountPath = "/"
+	}
+	c.baseURL = baseURL + m.prefix
+
+	m.router.ServeHTTP(c, req)
+
+	// Errors of the child are handled by its own error handlers
+	if c.GetError() != nil {
+		if handler := m.router.errorHandler(req.URL.Path); handler != nil {
+			handler(c)
+			c.err = nil
+		}
+	}
+
+	req.URL.Path, c.mountPath, c.baseURL = path, mountPath, baseURL
+	c.router = m.parent
+}
+
+// Mount serves the requests for prefix and the paths under it with the child server,
+// see Router.Mount. The error handler of the child server handles its errors.
+func (s *Server) Mount(prefix string, child *Server) {
+	if child.router.ErrorHandler == nil {
+		child.router.ErrorHandler = child.errorHandler
+	}
+	s.router.Mount(prefix, child.router)
+}
@@ -0,0 +1,192 @@
+package ngebut
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouterMount tests serving requests with mounted routers
+func TestRouterMount(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var seen []string
+	router.Use(func(c *Ctx) {
+		seen = append(seen, "parent "+c.Path())
+		c.Next()
+	})
+	router.GET("/", func(c *Ctx) { c.String("home") })
+	router.GET("/admin/legacy", func(c *Ctx) { c.String("legacy") })
+
+	admin := NewRouter()
+	admin.Use(func(c *Ctx) {
+		seen = append(seen, "admin "+c.Path())
+		c.Next()
+	})
+	admin.GET("/", func(c *Ctx) { c.String("admin home") })
+	admin.GET("/users/:id", func(c *Ctx) {
+		c.String("%s %s %s %s", c.MountPath(), c.BaseURL(), c.Path(), c.Param("id"))
+	})
+	admin.GET("/fail", func(c *Ctx) { c.Error(errors.New("boom")) })
+	admin.NotFound = func(c *Ctx) {
+		c.Status(StatusNotFound)
+		c.String("admin not found")
+	}
+	admin.ErrorHandler = func(c *Ctx) {
+		c.Status(StatusTeapot)
+		c.String("admin error: %v", c.GetError())
+	}
+
+	reports := NewRouter()
+	reports.GET("/daily", func(c *Ctx) { c.String("%s %s", c.MountPath(), c.BaseURL()) })
+	admin.Mount("/reports/", reports)
+
+	router.Mount("/admin", admin)
+
+	serve := func(path string) (*httptest.ResponseRecorder, *Ctx) {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w, ctx
+	}
+
+	w, ctx := serve("/admin/users/7")
+	assert.Equal("/admin /admin /users/7 7", w.Body.String(), "the prefix should be stripped")
+	assert.Equal("/admin/users/7", ctx.Path(), "the path should be restored afterwards")
+	assert.Empty(ctx.MountPath())
+	assert.Equal([]string{"parent /admin/users/7", "admin /users/7"}, seen, "both middleware stacks should run")
+	ReleaseContext(ctx)
+
+	w, ctx = serve("/admin")
+	assert.Equal("admin home", w.Body.String())
+	ReleaseContext(ctx)
+
+	w, ctx = serve("/admin/reports/daily")
+	assert.Equal("/reports /admin/reports", w.Body.String(), "nested mounts should accumulate the base URL")
+	ReleaseContext(ctx)
+
+	// The child keeps its own NotFound and error handlers
+	w, ctx = serve("/admin/legacy")
+	assert.Equal(StatusNotFound, w.Code)
+	assert.Equal("admin not found", w.Body.String(), "mounted routers take precedence")
+	ReleaseContext(ctx)
+
+	w, ctx = serve("/admin/fail")
+	assert.Equal(StatusTeapot, w.Code)
+	assert.Equal("admin error: boom", w.Body.String())
+	assert.NoError(ctx.GetError(), "the error should be handled by the child")
+	ReleaseContext(ctx)
+
+	// Other paths are served by the parent
+	w, ctx = serve("/administrator")
+	assert.Equal(StatusNotFound, w.Code)
+	assert.Equal("404 page not found", w.Body.String())
+	ReleaseContext(ctx)
+
+	// Mounted routes are listed with the prefix
+	patterns := make([]string, 0)
+	for _, info := range router.routeInfos() {
+		patterns = append(patterns, info.Pattern)
+	}
+	assert.Contains(patterns, "/admin/users/:id")
+	assert.Contains(patterns, "/admin/reports/daily")
+
+	assert.Panics(func() { router.Mount("/:tenant", NewRouter()) }, "mount prefixes must be static")
+}
+
+// TestServerMount tests mounting a server with its own error handler
+func TestServerMount(t *testing.T) {
+	parent := New(Config{})
+	child := New(Config{ErrorHandler: func(c *Ctx) {
+		c.Status(StatusBadRequest)
+		c.String("child")
+	}})
+	child.GET("/fail", func(c *Ctx) { c.Error(errors.New("boom")) })
+	parent.Mount("/api", child)
+
+	req := httptest.NewRequest(MethodGet, "/api/fail", nil)
+	w := httptest.NewRecorder()
+	parent.ServeHTTP(w, req)
+
+	assert.Equal(t, StatusBadRequest, w.Code)
+	assert.Equal(t, "child", w.Body.String())
+}
+
+// TestRouterMountInheritsOptions tests that mounted routers take the routing options of the parent
+func TestRouterMountInheritsOptions(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.RedirectTrailingSlash = true
+	router.CaseSensitive = false
+	router.StrictRegistration = true
+
+	admin := NewRouter()
+	admin.GET("/users", func(c *Ctx) { c.String("users") })
+	router.Mount("/admin", admin)
+	assert.True(admin.RedirectTrailingSlash)
+	assert.False(admin.CaseSensitive)
+	assert.True(admin.StrictRegistration)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	assert.Equal("users", serve("/admin/USERS").Body.String(), "routing should not be case sensitive")
+	w := serve("/admin/users/")
+	assert.Equal(StatusMovedPermanently, w.Code, "routing should be strict")
+	assert.Equal("/admin/users", w.Header().Get(HeaderLocation), "the location should keep the mount prefix")
+
+	// Conflicts of mounted routes are reported by the parent
+	assert.NotPanics(func() {
+		admin.GET("/items/:id", func(c *Ctx) {})
+		admin.GET("/items/:name", func(c *Ctx) {})
+	})
+	var conflict *RouteConflictError
+	assert.ErrorAs(router.Err(), &conflict)
+}
+
+// testAdminMiddleware is a named middleware used to check the listed middleware of mounted routes
+func testAdminMiddleware(c *Ctx) {
+	c.Next()
+}
+
+// TestRouterMountRouteInfosMiddleware tests listing the parent middleware that runs for mounted routes
+func TestRouterMountRouteInfosMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	handler := func(c *Ctx) {}
+
+	router := NewRouter()
+	router.Use(testRouteMiddleware)
+	router.Use("/admin", testAdminMiddleware)
+
+	api := NewRouter()
+	api.GET("/users", handler)
+	admin := NewRouter()
+	admin.GET("/users", handler)
+	router.Mount("/api", api)
+	router.Mount("/admin", admin)
+	router.Host("admin.example.com").GET("/admin/users", handler)
+
+	middleware := make(map[string][]string)
+	for _, info := range router.routeInfos() {
+		middleware[info.Host+info.Pattern] = info.Middleware
+	}
+
+	global := "github.com/ryanbekhen/ngebut.testRouteMiddleware"
+	scoped := "github.com/ryanbekhen/ngebut.testAdminMiddleware"
+	assert.Equal([]string{global}, middleware["/api/users"], "path-scoped middleware of the parent should only be listed under its prefix")
+	assert.Equal([]string{global, scoped}, middleware["/admin/users"])
+	assert.Equal([]string{global, scoped}, middleware["admin.example.com/admin/users"])
+}
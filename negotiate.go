@@ -0,0 +1,265 @@
+package ngebut
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptItem is an element of an Accept request header, such as "text/html;level=1;q=0.8".
+type acceptItem struct {
+	value  string   // Media range, content coding, charset or language range, in lower case
+	params []string // Media type parameters other than q, as lower case "name=value"
+	q      float64  // Quality value, 1 by default
+}
+
+// Accepts returns the offer that best matches the Accept header of the request, or an
+// empty string when none is acceptable. Offers are media types, such as "application/json",
+// or file extensions, such as "json" or "html", with "text" for plain text.
+//
+// Offers are ranked by the quality value of the most specific media range matching them,
+// as defined by RFC 9110, then by how specific that range is and by its position in the
+// header. Media ranges with q=0 make the offers they match unacceptable. When the request
+// has no Accept header, the first offer is returned. The media type parameter selecting
+// the API version, see Versioning.AcceptParam, is ignored.
+//
+// Example:
+//
+//	switch c.Accepts("json", "html") {
+//	case "json":
+//		c.JSON(user)
+//	case "html":
+//		c.HTML(renderUser(user))
+//	default:
+//		c.Status(ngebut.StatusNotAcceptable)
+//	}
+func (c *Ctx) Accepts(offers ...string) string {
+	versionParam := "version="
+	if c.router != nil && c.router.Versioning.AcceptParam != "" {
+		versionParam = strings.ToLower(c.router.Versioning.AcceptParam) + "="
+	}
+
+	return c.negotiate(HeaderAccept, offers, func(item acceptItem, offer string) int {
+		mediaType, params := parseMediaType(offerMediaType(offer))
+		rangeType, rangeSub, _ := strings.Cut(item.value, "/")
+		offerType, offerSub, _ := strings.Cut(mediaType, "/")
+
+		specificity := 0
+		switch {
+		case rangeType == "*" && rangeSub == "*":
+		case rangeType == offerType && rangeSub == "*":
+			specificity = 1
+		case rangeType == offerType && rangeSub == offerSub:
+			specificity = 2
+		default:
+			return -1
+		}
+
+		// Media ranges with parameters only match offers with the same parameters
+		for _, param := range item.params {
+			if strings.HasPrefix(param, versionParam) {
+				continue
+			}
+			if !slices.Contains(params, param) {
+				return -1
+			}
+			specificity++
+		}
+		return specificity
+	})
+}
+
+// AcceptsEncodings returns the offered content coding, such as "gzip" or "br", that best
+// matches the Accept-Encoding header of the request, or an empty string when none is
+// acceptable. The "identity" coding is acceptable unless the header excludes it.
+func (c *Ctx) AcceptsEncodings(offers ...string) string {
+	return c.negotiate(HeaderAcceptEncoding, offers, matchToken)
+}
+
+// AcceptsCharsets returns the offered charset, such as "utf-8", that best matches the
+// Accept-Charset header of the request, or an empty string when none is acceptable.
+func (c *Ctx) AcceptsCharsets(offers ...string) string {
+	return c.negotiate(HeaderAcceptCharset, offers, matchToken)
+}
+
+// AcceptsLanguages returns the offered language tag, such as "en-US", that best matches
+// the Accept-Language header of the request, or an empty string when none is acceptable.
+// Language ranges match the tags they are a prefix of, so "en" matches "en-US".
+func (c *Ctx) AcceptsLanguages(offers ...string) string {
+	return c.negotiate(HeaderAcceptLanguage, offers, func(item acceptItem, offer string) int {
+		offer = strings.ToLower(offer)
+		switch {
+		case item.value == "*":
+			return 0
+		case item.value == offer:
+			return 2
+		case strings.HasPrefix(offer, item.value) && offer[len(item.value)] == '-':
+			return 1
+		}
+		return -1
+	})
+}
+
+// Format serves the request with the handler of the offer that best matches the Accept
+// header, as selected by Accepts. The keys of handlers are media types or file extensions,
+// such as "json", "html", "xml" and "text". Offers that are equally acceptable are tried in
+// the order of their keys. The "default" key holds the handler for requests that accept
+// none of the offers, which get 406 Not Acceptable without it.
+//
+// The response varies on the Accept header, which is added to the Vary header.
+//
+// Example:
+//
+//	c.Format(map[string]ngebut.Handler{
+//		"json": func(c *ngebut.Ctx) { c.JSON(user) },
+//		"html": func(c *ngebut.Ctx) { c.HTML(renderUser(user)) },
+//		"text": func(c *ngebut.Ctx) { c.String("%s", user.Name) },
+//	})
+func (c *Ctx) Format(handlers map[string]Handler) {
+	addVary(c, HeaderAccept)
+
+	offers := make([]string, 0, len(handlers))
+	for offer := range handlers {
+		if offer != "default" {
+			offers = append(offers, offer)
+		}
+	}
+	sort.Strings(offers)
+
+	if offer := c.Accepts(offers...); offer != "" {
+		handlers[offer](c)
+		return
+	}
+	if handler, ok := handlers["default"]; ok {
+		handler(c)
+		return
+	}
+	notAcceptableHandler(c)
+}
+
+// negotiate returns the offer that best matches the elements of a request header.
+// match returns how specific an element matching an offer is, or -1 if it doesn't match.
+func (c *Ctx) negotiate(header string, offers []string, match func(item acceptItem, offer string) int) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	var items []acceptItem
+	if c.Request != nil {
+		items = parseAccept(c.Request.Header.Get(header))
+	}
+	if len(items) == 0 {
+		return offers[0]
+	}
+
+	best, bestQ, bestSpecificity, bestIndex := "", 0.0, -1, 0
+	for _, offer := range offers {
+		q, specificity, index := -1.0, -1, len(items)
+		for i, item := range items {
+			if s := match(item, offer); s > specificity {
+				q, specificity, index = item.q, s, i
+			}
+		}
+
+		// The identity coding is acceptable unless excluded, with the lowest preference
+		if q < 0 && header == HeaderAcceptEncoding && strings.EqualFold(offer, "identity") {
+			q = 1
+			for _, item := range items {
+				if item.q > 0 {
+					q = min(q, item.q)
+				}
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || q == bestQ && (specificity > bestSpecificity || specificity == bestSpecificity && index < bestIndex) {
+			best, bestQ, bestSpecificity, bestIndex = offer, q, specificity, index
+		}
+	}
+	return best
+}
+
+// parseAccept parses the elements of an Accept request header.
+// Elements with an invalid quality value are skipped.
+func parseAccept(header string) []acceptItem {
+	if header == "" {
+		return nil
+	}
+
+	items := make([]acceptItem, 0, strings.Count(header, ",")+1)
+	for _, element := range strings.Split(header, ",") {
+		value, params := parseMediaType(element)
+		if value == "" {
+			continue
+		}
+
+		item := acceptItem{value: value, q: 1}
+		valid := true
+		for i, param := range params {
+			if name, qvalue, _ := strings.Cut(param, "="); name == "q" {
+				q, err := strconv.ParseFloat(qvalue, 64)
+				if err != nil || q < 0 || q > 1 {
+					valid = false
+				}
+				// Parameters after q are accept extensions, not media type parameters
+				item.q, params = q, params[:i]
+				break
+			}
+		}
+		if valid {
+			item.params = params
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseMediaType returns the lower case value and "name=value" parameters of a header
+// element such as "text/html; charset=UTF-8", with quotes removed from the values.
+func parseMediaType(element string) (string, []string) {
+	value, rest, _ := strings.Cut(element, ";")
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	var params []string
+	for rest != "" {
+		var param string
+		param, rest, _ = strings.Cut(rest, ";")
+		name, paramValue, _ := strings.Cut(param, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		paramValue = strings.TrimSpace(paramValue)
+		if unquoted, err := strconv.Unquote(paramValue); err == nil {
+			paramValue = unquoted
+		}
+		params = append(params, name+"="+strings.ToLower(paramValue))
+	}
+	return value, params
+}
+
+// offerMediaType returns the media type of an offer of Accepts, which is a media type
+// or a file extension.
+func offerMediaType(offer string) string {
+	if strings.Contains(offer, "/") {
+		return offer
+	}
+	if offer == "text" {
+		return MIMETextPlain
+	}
+	return getMimeType("." + strings.TrimPrefix(offer, "."))
+}
+
+// matchToken matches the elements of Accept-Encoding and Accept-Charset with offers.
+func matchToken(item acceptItem, offer string) int {
+	switch {
+	case item.value == "*":
+		return 0
+	case strings.EqualFold(item.value, offer):
+		return 1
+	}
+	return -1
+}
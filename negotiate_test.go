@@ -0,0 +1,113 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCtxAccepts tests selecting the offers that best match the Accept headers
+func TestCtxAccepts(t *testing.T) {
+	assert := assert.New(t)
+
+	ctxWith := func(header, value string) *Ctx {
+		req, _ := http.NewRequest(MethodGet, "http://example.com/", nil)
+		if value != "" {
+			req.Header.Set(header, value)
+		}
+		return GetContext(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		header string
+		value  string
+		offers []string
+		want   string
+	}{
+		{HeaderAccept, "", []string{"json", "html"}, "json"},
+		{HeaderAccept, "text/html", []string{"json", "html"}, "html"},
+		{HeaderAccept, "application/json;q=0.5, text/html", []string{"json", "html"}, "html"},
+		{HeaderAccept, "text/*, application/json;q=0.9", []string{"application/json", "text/plain"}, "text/plain"},
+		{HeaderAccept, "*/*;q=0.1, application/xml", []string{"json", "xml"}, "xml"},
+		{HeaderAccept, "text/*;q=0.3, text/html;q=0.7, */*;q=0.5", []string{"text/plain", "text/html", "image/png"}, "text/html"},
+		{HeaderAccept, "text/*, text/plain;q=0", []string{"text", "html"}, "html"},
+		{HeaderAccept, "text/html;level=1, text/html;q=0.2", []string{"text/html"}, "text/html"},
+		{HeaderAccept, "text/html;level=1", []string{"text/html"}, ""},
+		{HeaderAccept, `application/json; version="2"`, []string{"json"}, "json"},
+		{HeaderAccept, "image/png", []string{"json", "html"}, ""},
+		{HeaderAccept, "application/json, text/html", []string{"html", "json"}, "json"},
+		{HeaderAcceptEncoding, "gzip, br;q=0.8", []string{"br", "gzip"}, "gzip"},
+		{HeaderAcceptEncoding, "gzip;q=0.5", []string{"identity", "gzip"}, "gzip"},
+		{HeaderAcceptEncoding, "gzip;q=0", []string{"gzip", "identity"}, "identity"},
+		{HeaderAcceptEncoding, "*;q=0", []string{"identity"}, ""},
+		{HeaderAcceptCharset, "iso-8859-1;q=0.5, UTF-8", []string{"iso-8859-1", "utf-8"}, "utf-8"},
+		{HeaderAcceptLanguage, "en-US, en;q=0.9, id;q=0.8", []string{"id", "en-GB"}, "en-GB"},
+		{HeaderAcceptLanguage, "id, en;q=0.9", []string{"en-US", "id-ID"}, "id-ID"},
+		{HeaderAcceptLanguage, "fr", []string{"en", "id"}, ""},
+		{HeaderAcceptLanguage, "*;q=0.1, fr", []string{"en", "fr-CA"}, "fr-CA"},
+	}
+
+	for _, tt := range tests {
+		c := ctxWith(tt.header, tt.value)
+		var got string
+		switch tt.header {
+		case HeaderAccept:
+			got = c.Accepts(tt.offers...)
+		case HeaderAcceptEncoding:
+			got = c.AcceptsEncodings(tt.offers...)
+		case HeaderAcceptCharset:
+			got = c.AcceptsCharsets(tt.offers...)
+		case HeaderAcceptLanguage:
+			got = c.AcceptsLanguages(tt.offers...)
+		}
+		assert.Equal(tt.want, got, "%s: %s %v", tt.header, tt.value, tt.offers)
+		ReleaseContext(c)
+	}
+}
+
+// TestCtxFormat tests dispatching to the handler of the accepted media type
+func TestCtxFormat(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	handlers := map[string]Handler{
+		"json": func(c *Ctx) { c.JSON(map[string]string{"name": "ngebut"}) },
+		"html": func(c *Ctx) { c.HTML("<b>ngebut</b>") },
+		"text": func(c *Ctx) { c.String("ngebut") },
+	}
+	router.GET("/", func(c *Ctx) { c.Format(handlers) })
+	router.GET("/default", func(c *Ctx) {
+		c.Format(map[string]Handler{
+			"json":    handlers["json"],
+			"default": handlers["text"],
+		})
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		if accept != "" {
+			req.Header.Set(HeaderAccept, accept)
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	w := serve("/", "application/json")
+	assert.Contains(w.Body.String(), `"name":"ngebut"`)
+	assert.Equal(HeaderAccept, w.Header().Get(HeaderVary))
+	assert.Equal("<b>ngebut</b>", serve("/", "text/html, application/json;q=0.9").Body.String())
+	assert.Equal("ngebut", serve("/", "text/plain").Body.String())
+	assert.Equal("<b>ngebut</b>", serve("/", "").Body.String(), "equally acceptable offers should be tried in key order")
+
+	w = serve("/", "image/png")
+	assert.Equal(StatusNotAcceptable, w.Code)
+	assert.Equal(HeaderAccept, w.Header().Get(HeaderVary))
+
+	assert.Equal("ngebut", serve("/default", "image/png").Body.String())
+}
@@ -0,0 +1,227 @@
+package ngebut
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"syscall"
+	"time"
+)
+
+const (
+	// preforkChildEnvKey is the environment variable used to mark prefork child processes
+	preforkChildEnvKey = "NGEBUT_PREFORK_CHILD"
+	// preforkChildEnvValue is the value of preforkChildEnvKey in child processes
+	preforkChildEnvValue = "1"
+)
+
+var (
+	// preforkCommand creates the command used to start a child process.
+	// It re-executes the current binary with the same arguments.
+	preforkCommand = func() (*exec.Cmd, error) {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		return exec.Command(executable, os.Args[1:]...), nil
+	}
+
+	// preforkRestartDelay is the delay before a crashed child process is restarted.
+	// It doubles with each consecutive restart, up to preforkMaxRestartDelay.
+	preforkRestartDelay = time.Second
+
+	// preforkMaxRestartDelay is the longest delay before a crashed child process is restarted
+	preforkMaxRestartDelay = 30 * time.Second
+
+	// preforkMaxRestarts is the number of consecutive restarts after which the master gives up,
+	// stops the other children and returns an error
+	preforkMaxRestarts = 10
+
+	// preforkStableDuration is how long a child must run for its exit to reset the restart count
+	preforkStableDuration = time.Minute
+
+	// preforkStopTimeout is how long children have to exit after the shutdown signal,
+	// before they are killed
+	preforkStopTimeout = 10 * time.Second
+
+	// preforkMasterCheckInterval is how often a child checks that its master is still alive
+	preforkMasterCheckInterval = 500 * time.Millisecond
+)
+
+// IsChild reports whether the current process is a child started in prefork mode.
+func IsChild() bool {
+	return os.Getenv(preforkChildEnvKey) == preforkChildEnvValue
+}
+
+// preforkChild is a running child process of the prefork master.
+type preforkChild struct {
+	cmd     *exec.Cmd
+	pid     int
+	started time.Time
+}
+
+// preforkExit reports the exit of a child process.
+type preforkExit struct {
+	pid int
+	err error
+}
+
+// preforkProcesses returns the number of child processes to start.
+func (s *Server) preforkProcesses() int {
+	if s.preforkCount > 0 {
+		return s.preforkCount
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// startPreforkChild starts a new child process and reports its exit on the exits channel.
+func startPreforkChild(exits chan<- preforkExit) (*preforkChild, error) {
+	cmd, err := preforkCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, preforkChildEnvKey+"="+preforkChildEnvValue)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	child := &preforkChild{cmd: cmd, pid: cmd.Process.Pid, started: time.Now()}
+	go func() {
+		exits <- preforkExit{pid: child.pid, err: cmd.Wait()}
+	}()
+
+	return child, nil
+}
+
+// preforkMaster starts the child processes and supervises them until the server is shut down
+// or a shutdown signal is received. Children that exit unexpectedly are restarted with an
+// increasing delay, and the master returns an error when they keep exiting.
+// Shutdown signals are forwarded to all children.
+func (s *Server) preforkMaster(addr string) error {
+	n := s.preforkProcesses()
+	exits := make(chan preforkExit, n)
+	children := make(map[int]*preforkChild, n)
+
+	for i := 0; i < n; i++ {
+		child, err := startPreforkChild(exits)
+		if err != nil {
+			stopPreforkChildren(children, exits, syscall.SIGTERM)
+			return fmt.Errorf("prefork: failed to start child process: %w", err)
+		}
+		children[child.pid] = child
+	}
+
+	if !s.disableStartupMessage {
+		pids := make([]int, 0, len(children))
+		for pid := range children {
+			pids = append(pids, pid)
+		}
+		sort.Ints(pids)
+		displayStartupMessage(addr, s.startupRoutes(), pids...)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	restarts := make(chan struct{}, n)
+
+	// Consecutive restarts, reset when a child exits after running for preforkStableDuration
+	restartCount := 0
+
+	for {
+		select {
+		case sig := <-signals:
+			stopPreforkChildren(children, exits, sig)
+			return nil
+		case <-s.preforkStop:
+			stopPreforkChildren(children, exits, syscall.SIGTERM)
+			return nil
+		case exit := <-exits:
+			if child, ok := children[exit.pid]; ok && time.Since(child.started) >= preforkStableDuration {
+				restartCount = 0
+			}
+			delete(children, exit.pid)
+
+			restartCount++
+			if restartCount > preforkMaxRestarts {
+				stopPreforkChildren(children, exits, syscall.SIGTERM)
+				return fmt.Errorf("prefork: child process %d exited after %d restarts: %v", exit.pid, preforkMaxRestarts, exit.err)
+			}
+
+			// Delay the restart so a crashing child does not spin the master
+			delay := preforkBackoff(restartCount)
+			if logger != nil {
+				logger.Warn().Msgf("Child process %d exited: %v, restarting in %s", exit.pid, exit.err, delay)
+			}
+			time.AfterFunc(delay, func() {
+				restarts <- struct{}{}
+			})
+		case <-restarts:
+			child, err := startPreforkChild(exits)
+			if err != nil {
+				stopPreforkChildren(children, exits, syscall.SIGTERM)
+				return fmt.Errorf("prefork: failed to restart child process: %w", err)
+			}
+			children[child.pid] = child
+		}
+	}
+}
+
+// preforkBackoff returns the delay before the given consecutive restart, starting at
+// preforkRestartDelay and doubling up to preforkMaxRestartDelay.
+func preforkBackoff(restart int) time.Duration {
+	delay := preforkRestartDelay
+	for i := 1; i < restart && delay < preforkMaxRestartDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, preforkMaxRestartDelay)
+}
+
+// stopPreforkChildren sends sig to all children and waits for them to exit.
+// Children still running after preforkStopTimeout are killed.
+func stopPreforkChildren(children map[int]*preforkChild, exits <-chan preforkExit, sig os.Signal) {
+	for _, child := range children {
+		_ = child.cmd.Process.Signal(sig)
+	}
+
+	timeout := time.NewTimer(preforkStopTimeout)
+	defer timeout.Stop()
+
+	for len(children) > 0 {
+		select {
+		case exit := <-exits:
+			delete(children, exit.pid)
+		case <-timeout.C:
+			for pid, child := range children {
+				if logger != nil {
+					logger.Warn().Msgf("Child process %d did not exit within %s, killing it", pid, preforkStopTimeout)
+				}
+				_ = child.cmd.Process.Kill()
+			}
+		}
+	}
+}
+
+// watchPreforkMaster exits the child process when its master process is gone.
+func watchPreforkMaster() {
+	ppid := os.Getppid()
+	ticker := time.NewTicker(preforkMasterCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if os.Getppid() != ppid {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ngebut
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPreforkHelperProcess is not a real test. It is run as a child process by the prefork tests.
+func TestPreforkHelperProcess(t *testing.T) {
+	if !IsChild() {
+		return
+	}
+
+	switch os.Getenv("NGEBUT_PREFORK_HELPER") {
+	case "crash":
+		os.Exit(1)
+	case "ignore":
+		// Ignore the shutdown signal, so that the master has to kill the process
+		signal.Ignore(syscall.SIGTERM, syscall.SIGINT)
+		_ = os.WriteFile(os.Getenv("NGEBUT_PREFORK_READY"), nil, 0o600)
+	}
+
+	// Block until the master signals the process
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// usePreforkHelper makes the master start the helper test process in the given mode
+// and returns a counter of started children.
+func usePreforkHelper(t *testing.T, mode string) *int32 {
+	var started int32
+
+	originalCommand := preforkCommand
+	originalDelay, originalMaxDelay := preforkRestartDelay, preforkMaxRestartDelay
+	originalMaxRestarts, originalStopTimeout := preforkMaxRestarts, preforkStopTimeout
+	t.Cleanup(func() {
+		preforkCommand = originalCommand
+		preforkRestartDelay, preforkMaxRestartDelay = originalDelay, originalMaxDelay
+		preforkMaxRestarts, preforkStopTimeout = originalMaxRestarts, originalStopTimeout
+	})
+
+	preforkRestartDelay = 10 * time.Millisecond
+	preforkMaxRestartDelay = 40 * time.Millisecond
+	preforkCommand = func() (*exec.Cmd, error) {
+		atomic.AddInt32(&started, 1)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestPreforkHelperProcess$")
+		cmd.Env = append(os.Environ(), "NGEBUT_PREFORK_HELPER="+mode)
+		return cmd, nil
+	}
+
+	return &started
+}
+
+// runPreforkMaster runs the prefork master in the background and returns a channel with its result
+func runPreforkMaster(server *Server) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- server.preforkMaster(":0")
+	}()
+	return done
+}
+
+// TestIsChild tests the detection of prefork child processes
+func TestIsChild(t *testing.T) {
+	t.Setenv(preforkChildEnvKey, "")
+	assert.False(t, IsChild())
+
+	t.Setenv(preforkChildEnvKey, preforkChildEnvValue)
+	assert.True(t, IsChild())
+}
+
+// TestPreforkProcesses tests the number of child processes
+func TestPreforkProcesses(t *testing.T) {
+	server := New(Config{Prefork: true, PreforkProcesses: 3})
+	assert.Equal(t, 3, server.preforkProcesses())
+
+	server = New(Config{Prefork: true})
+	assert.Greater(t, server.preforkProcesses(), 0, "should default to GOMAXPROCS")
+}
+
+// TestPreforkMasterShutdown tests that the master starts the children and stops them on shutdown
+func TestPreforkMasterShutdown(t *testing.T) {
+	started := usePreforkHelper(t, "sleep")
+
+	server := New(Config{Prefork: true, PreforkProcesses: 2, DisableStartupMessage: true})
+	done := runPreforkMaster(server)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(started) == 2
+	}, 5*time.Second, 10*time.Millisecond, "all children should be started")
+
+	assert.NoError(t, server.Shutdown(context.Background()))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("master should return after shutdown")
+	}
+
+	// Shutting down twice is safe
+	assert.NoError(t, server.Shutdown(context.Background()))
+}
+
+// TestPreforkMasterRestart tests that crashed children are restarted
+func TestPreforkMasterRestart(t *testing.T) {
+	started := usePreforkHelper(t, "crash")
+
+	server := New(Config{Prefork: true, PreforkProcesses: 1, DisableStartupMessage: true})
+	done := runPreforkMaster(server)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(started) >= 3
+	}, 10*time.Second, 10*time.Millisecond, "crashed children should be restarted")
+
+	assert.NoError(t, server.Shutdown(context.Background()))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("master should return after shutdown")
+	}
+}
+
+// TestPreforkMasterMaxRestarts tests that the master gives up on children that keep crashing
+func TestPreforkMasterMaxRestarts(t *testing.T) {
+	started := usePreforkHelper(t, "crash")
+	preforkMaxRestarts = 2
+
+	server := New(Config{Prefork: true, PreforkProcesses: 1, DisableStartupMessage: true})
+	done := runPreforkMaster(server)
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "after 2 restarts")
+	case <-time.After(10 * time.Second):
+		t.Fatal("master should return after the maximum number of restarts")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(started), "the child should be started once and restarted twice")
+}
+
+// TestPreforkBackoff tests that the restart delay doubles up to the maximum delay
+func TestPreforkBackoff(t *testing.T) {
+	usePreforkHelper(t, "sleep")
+	preforkMaxRestartDelay = 100 * time.Millisecond
+
+	assert.Equal(t, 10*time.Millisecond, preforkBackoff(1))
+	assert.Equal(t, 20*time.Millisecond, preforkBackoff(2))
+	assert.Equal(t, 80*time.Millisecond, preforkBackoff(4))
+	assert.Equal(t, 100*time.Millisecond, preforkBackoff(5), "the delay should not exceed the maximum")
+	assert.Equal(t, 100*time.Millisecond, preforkBackoff(100))
+}
+
+// TestPreforkMasterKill tests that children that ignore the shutdown signal are killed
+// after the grace period
+func TestPreforkMasterKill(t *testing.T) {
+	started := usePreforkHelper(t, "ignore")
+	preforkStopTimeout = 100 * time.Millisecond
+	ready := filepath.Join(t.TempDir(), "ready")
+	t.Setenv("NGEBUT_PREFORK_READY", ready)
+
+	server := New(Config{Prefork: true, PreforkProcesses: 1, DisableStartupMessage: true})
+	done := runPreforkMaster(server)
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(ready)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "the child should ignore the shutdown signal")
+
+	assert.NoError(t, server.Shutdown(context.Background()))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("master should kill the child and return after the grace period")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(started), "the killed child should not be restarted")
+}
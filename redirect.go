@@ -0,0 +1,134 @@
+package ngebut
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsafeRedirect is returned by Ctx.Redirect when Router.SafeRedirects is enabled
+// and the location is on a host redirects are not allowed to.
+var ErrUnsafeRedirect = errors.New("unsafe redirect")
+
+// Redirect redirects the client to location. Relative locations, such as "edit" or
+// "../users", are resolved against the path of the request. Absolute paths, such as
+// "/login", and absolute URLs are kept as they are.
+//
+// The status defaults to 302 Found for GET and HEAD requests, and to 303 See Other for
+// other methods, such as the POST of a form, so that the client follows it with a GET.
+//
+// When the router has SafeRedirects enabled, locations on another host than the host of
+// the request are refused with ErrUnsafeRedirect, unless the host is listed in
+// RedirectHosts, and nothing is written.
+//
+// Example:
+//
+//	router.POST("/login", func(c *ngebut.Ctx) {
+//		if err := c.Redirect(c.Query("next")); err != nil {
+//			c.Redirect("/")
+//		}
+//	})
+func (c *Ctx) Redirect(location string, status ...int) error {
+	target, err := c.resolveRedirect(location)
+	if err != nil {
+		return err
+	}
+
+	c.redirect(target, c.redirectStatus(status))
+	return nil
+}
+
+// RedirectBack redirects the client to the page it came from, given by the Referer header,
+// or to fallback when the request has no Referer. When the router has SafeRedirects enabled,
+// a Referer on a host redirects are not allowed to is replaced by fallback as well.
+// The status is chosen as with Redirect.
+func (c *Ctx) RedirectBack(fallback string, status ...int) error {
+	if referer := c.Referer(); referer != "" {
+		if target, err := c.resolveRedirect(referer); err == nil {
+			c.redirect(target, c.redirectStatus(status))
+			return nil
+		}
+	}
+	return c.Redirect(fallback, status...)
+}
+
+// redirect sets the status and the Location header of a redirect and writes the status.
+func (c *Ctx) redirect(location string, status int) {
+	c.Status(status)
+	c.Set(HeaderLocation, location)
+	c.prepareResponse("")
+}
+
+// redirectStatus returns the given redirect status, or the default status for the request method.
+func (c *Ctx) redirectStatus(status []int) int {
+	if len(status) > 0 {
+		return status[0]
+	}
+	if c.Request != nil && c.Request.Method != MethodGet && c.Request.Method != MethodHead {
+		return StatusSeeOther
+	}
+	return StatusFound
+}
+
+// resolveRedirect resolves a redirect location against the path of the request,
+// and checks that the router allows redirects to it.
+func (c *Ctx) resolveRedirect(location string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+
+	if policy := c.redirectPolicy(); policy != nil && !policy.redirectAllowed(location, u, c.Host()) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeRedirect, location)
+	}
+
+	// Relative references, including a query or a fragment alone
+	if u.Scheme == "" && u.Host == "" && !strings.HasPrefix(u.Path, "/") {
+		base := &url.URL{Path: c.BaseURL() + c.Path()}
+		return base.ResolveReference(u).String(), nil
+	}
+	return location, nil
+}
+
+// redirectPolicy returns the router whose SafeRedirects and RedirectHosts apply to the
+// request, or nil when redirects are not checked. The options of the root router apply
+// to the host, mounted and version routers serving the request, which may enable them too.
+func (c *Ctx) redirectPolicy() *Router {
+	if c.rootRouter != nil && c.rootRouter.SafeRedirects {
+		return c.rootRouter
+	}
+	if c.router != nil && c.router.SafeRedirects {
+		return c.router
+	}
+	return nil
+}
+
+// redirectAllowed reports whether a redirect location is on the host of the request,
+// or on one of the RedirectHosts.
+func (r *Router) redirectAllowed(location string, u *url.URL, host string) bool {
+	// Browsers treat backslashes as slashes, so that "/\example.com" is on another host
+	if strings.Contains(location, `\`) {
+		return false
+	}
+
+	if u.Scheme != "" && !strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https") {
+		return false
+	}
+	if u.Host == "" {
+		// Browsers read "///example.com" as a URL of another host, like "//example.com"
+		return u.Scheme == "" && (!strings.HasPrefix(location, "/") || isLocalPath(location))
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+
+	hostname := strings.ToLower(u.Hostname())
+	for _, allowed := range r.RedirectHosts {
+		allowed = strings.ToLower(allowed)
+		if hostname == allowed || strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
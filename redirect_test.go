@@ -0,0 +1,164 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCtxRedirect tests redirecting with default statuses and relative locations
+func TestCtxRedirect(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var redirectErr error
+	redirect := func(c *Ctx) { redirectErr = c.Redirect(c.Query("to")) }
+	router.GET("/users/:id/", redirect)
+	router.POST("/form", redirect)
+	router.GET("/moved", func(c *Ctx) { redirectErr = c.Redirect("/new", StatusMovedPermanently) })
+	router.POST("/back", func(c *Ctx) { redirectErr = c.RedirectBack("/home") })
+
+	admin := NewRouter()
+	admin.GET("/settings/", redirect)
+	router.Mount("/admin", admin)
+
+	serve := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com"+target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		redirectErr = nil
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Default statuses
+	w := serve(MethodGet, "/users/7/?to=/login", nil)
+	assert.Equal(StatusFound, w.Code)
+	assert.Equal("/login", w.Header().Get(HeaderLocation))
+	assert.Equal(StatusSeeOther, serve(MethodPost, "/form?to=/done", nil).Code)
+	w = serve(MethodGet, "/moved", nil)
+	assert.Equal(StatusMovedPermanently, w.Code)
+	assert.Equal("/new", w.Header().Get(HeaderLocation))
+
+	// Relative locations
+	assert.Equal("/users/7/edit", serve(MethodGet, "/users/7/?to=edit", nil).Header().Get(HeaderLocation))
+	assert.Equal("/users/8", serve(MethodGet, "/users/7/?to=../8", nil).Header().Get(HeaderLocation))
+	assert.Equal("/users/7/?page=2", serve(MethodGet, "/users/7/?to=%3Fpage%3D2", nil).Header().Get(HeaderLocation))
+	assert.Equal("/admin/settings/profile", serve(MethodGet, "/admin/settings/?to=profile", nil).Header().Get(HeaderLocation))
+	assert.Equal("https://other.com/x", serve(MethodGet, "/users/7/?to=https://other.com/x", nil).Header().Get(HeaderLocation))
+
+	// Redirecting back
+	w = serve(MethodPost, "/back", http.Header{"Referer": {"http://example.com/cart"}})
+	assert.Equal(StatusSeeOther, w.Code)
+	assert.Equal("http://example.com/cart", w.Header().Get(HeaderLocation))
+	assert.Equal("/home", serve(MethodPost, "/back", nil).Header().Get(HeaderLocation))
+	assert.NoError(redirectErr)
+}
+
+// TestCtxRedirectSafe tests refusing redirects to other hosts with SafeRedirects
+func TestCtxRedirectSafe(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.SafeRedirects = true
+	router.RedirectHosts = []string{"partner.com", "*.example.org"}
+
+	var redirectErr error
+	router.GET("/go", func(c *Ctx) { redirectErr = c.Redirect(c.Query("to")) })
+	router.GET("/back", func(c *Ctx) { redirectErr = c.RedirectBack("/home") })
+
+	serve := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		redirectErr = nil
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	for _, location := range []string{"/account", "next", "http://example.com/a", "https://PARTNER.com/b", "https://api.example.org/c"} {
+		w := serve("/go?to="+location, nil)
+		assert.Equal(StatusFound, w.Code, location)
+		assert.NoError(redirectErr, location)
+	}
+
+	for _, location := range []string{"https://evil.com", "//evil.com", "/%5Cevil.com", "javascript:alert(1)", "https://example.org", "https://evilpartner.com", "///evil.com", "////evil.com/x", "/%5C/evil.com"} {
+		w := serve("/go?to="+location, nil)
+		assert.ErrorIs(redirectErr, ErrUnsafeRedirect, location)
+		assert.Empty(w.Header().Get(HeaderLocation), location)
+	}
+
+	// Referers on other hosts fall back
+	assert.Equal("/home", serve("/back", http.Header{"Referer": {"https://evil.com/"}}).Header().Get(HeaderLocation))
+	assert.Equal("/home", serve("/back", http.Header{"Referer": {"///evil.com"}}).Header().Get(HeaderLocation))
+	assert.Equal("/home", serve("/back", http.Header{"Referer": {"////evil.com/x"}}).Header().Get(HeaderLocation))
+	assert.Equal("http://example.com/cart", serve("/back", http.Header{"Referer": {"http://example.com/cart"}}).Header().Get(HeaderLocation))
+}
+
+// TestCtxRedirectSafeSubRouters tests that SafeRedirects of the root router applies
+// to version, host and mounted routers
+func TestCtxRedirectSafeSubRouters(t *testing.T) {
+	router := NewRouter()
+	router.SafeRedirects = true
+
+	var redirectErr error
+	redirect := func(c *Ctx) { redirectErr = c.Redirect(c.Query("to")) }
+
+	router.Version("1", func(g *Group) {
+		g.GET("/ver", redirect)
+	})
+	router.Host("api.example.com").GET("/host", redirect)
+	mounted := NewRouter()
+	mounted.GET("/m", redirect)
+	router.Mount("/mnt", mounted)
+
+	serve := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		redirectErr = nil
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header http.Header
+	}{
+		{"version", "http://example.com/ver", http.Header{"Accept-Version": {"1"}}},
+		{"host", "http://api.example.com/host", nil},
+		{"mount", "http://example.com/mnt/m", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := serve(test.target+"?to=https://evil.com", test.header)
+			assert.ErrorIs(redirectErr, ErrUnsafeRedirect)
+			assert.Empty(w.Header().Get(HeaderLocation))
+
+			w = serve(test.target+"?to=/home", test.header)
+			assert.NoError(redirectErr)
+			assert.Equal(StatusFound, w.Code)
+			assert.Equal("/home", w.Header().Get(HeaderLocation))
+		})
+	}
+}
@@ -49,6 +49,10 @@ type Request struct {
 	// to a server.
 	RequestURI string
 
+	// Trailer contains the trailer fields sent after a chunked request body.
+	// It is nil if the request had no trailers.
+	Trailer *Header
+
 	// ctx is the request's context.
 	ctx context.Context
 }
@@ -0,0 +1,112 @@
+package ngebut
+
+// RouteBuilder registers handlers for several methods of the same route pattern.
+// It is returned by Router.Route.
+type RouteBuilder struct {
+	router      *Router
+	pattern     string
+	handlers    []Handler // Route-local middleware, run before the handlers of each method
+	name        string    // Name of the route, assigned to the first registered method
+	namedMethod string    // Method of the route the name is assigned to, empty until one is registered
+}
+
+// Route returns a builder registering routes with the given pattern, so the pattern
+// is written once for all of its methods.
+//
+// Example:
+//
+//	router.Route("/users/:id").
+//		Use(loadUser).
+//		Name("user").
+//		GET(getUser).
+//		PUT(updateUser).
+//		DELETE(deleteUser)
+func (r *Router) Route(pattern string) *RouteBuilder {
+	return &RouteBuilder{
+		router:  r,
+		pattern: pattern,
+	}
+}
+
+// Use adds route-local middleware, which runs before the handlers of the methods
+// registered after it, and only for requests matching the route.
+// It accepts middleware functions that take a context parameter.
+func (b *RouteBuilder) Use(middleware ...interface{}) *RouteBuilder {
+	for _, m := range middleware {
+		switch m := m.(type) {
+		case Middleware:
+			b.handlers = append(b.handlers, Handler(m))
+		case Handler:
+			b.handlers = append(b.handlers, m)
+		case func(*Ctx):
+			b.handlers = append(b.handlers, m)
+		default:
+			panic("middleware must be a function that takes a *Ctx parameter")
+		}
+	}
+	return b
+}
+
+// Name assigns a name to the route, used to build its URL with Router.URL.
+// The name can be assigned before or after registering the methods of the route.
+// It panics if the name is already used.
+func (b *RouteBuilder) Name(name string) *RouteBuilder {
+	if b.namedMethod == "" {
+		b.name = name
+		return b
+	}
+
+	b.router.nameRoute(b.namedMethod, b.pattern, name)
+	return b
+}
+
+// Handle registers handlers for the route with the given method.
+func (b *RouteBuilder) Handle(method string, handlers ...Handler) *RouteBuilder {
+	all := make([]Handler, 0, len(b.handlers)+len(handlers))
+	all = append(all, b.handlers...)
+	all = append(all, handlers...)
+
+	// A conflicting route is not registered with StrictRegistration
+	if b.router.handle(b.pattern, method, all) && b.namedMethod == "" {
+		b.namedMethod = method
+		if b.name != "" {
+			b.Name(b.name)
+		}
+	}
+	return b
+}
+
+// GET registers handlers for the route with the GET method.
+func (b *RouteBuilder) GET(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodGet, handlers...)
+}
+
+// HEAD registers handlers for the route with the HEAD method.
+func (b *RouteBuilder) HEAD(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodHead, handlers...)
+}
+
+// POST registers handlers for the route with the POST method.
+func (b *RouteBuilder) POST(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodPost, handlers...)
+}
+
+// PUT registers handlers for the route with the PUT method.
+func (b *RouteBuilder) PUT(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodPut, handlers...)
+}
+
+// PATCH registers handlers for the route with the PATCH method.
+func (b *RouteBuilder) PATCH(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodPatch, handlers...)
+}
+
+// DELETE registers handlers for the route with the DELETE method.
+func (b *RouteBuilder) DELETE(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodDelete, handlers...)
+}
+
+// OPTIONS registers handlers for the route with the OPTIONS method.
+func (b *RouteBuilder) OPTIONS(handlers ...Handler) *RouteBuilder {
+	return b.Handle(MethodOptions, handlers...)
+}
@@ -0,0 +1,96 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouterRoute tests registering several methods for one pattern with the route builder
+func TestRouterRoute(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	router.Route("/users/:id").
+		Name("user").
+		Use(func(c *Ctx) {
+			c.Set("X-User", c.Param("id"))
+			c.Next()
+		}).
+		GET(func(c *Ctx) { c.String("get %s", c.Param("id")) }).
+		PUT(func(c *Ctx) { c.String("put %s", c.Param("id")) }).
+		PATCH(func(c *Ctx) { c.String("patch %s", c.Param("id")) }).
+		DELETE(func(c *Ctx) { c.String("delete %s", c.Param("id")) })
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	assert.Len(router.Routes, 4)
+
+	w := serve(MethodGet, "/users/7")
+	assert.Equal("get 7", w.Body.String())
+	assert.Equal("7", w.Header().Get("X-User"), "route-local middleware should run")
+	assert.Equal("put 7", serve(MethodPut, "/users/7").Body.String())
+	assert.Equal("patch 7", serve(MethodPatch, "/users/7").Body.String())
+
+	w = serve(MethodDelete, "/users/7")
+	assert.Equal("delete 7", w.Body.String())
+	assert.Equal("7", w.Header().Get("X-User"))
+
+	assert.Equal(StatusMethodNotAllowed, serve(MethodPost, "/users/7").Code)
+
+	u, err := router.URL("user", map[string]string{"id": "9"}, nil)
+	assert.NoError(err)
+	assert.Equal("/users/9", u, "name set before the methods should apply to the route")
+
+	// Middleware of a builder only applies to its own routes
+	router.GET("/other", func(c *Ctx) { c.String("other") })
+	assert.Empty(serve(MethodGet, "/other").Header().Get("X-User"))
+
+	// Naming after registering the methods
+	router.Route("/posts/:slug").GET(func(c *Ctx) {}).Name("post")
+	u, err = router.URL("post", map[string]string{"slug": "hello"}, nil)
+	assert.NoError(err)
+	assert.Equal("/posts/hello", u)
+}
+
+// TestGroupRoute tests defining nested groups in closures
+func TestGroupRoute(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	api := router.Group("/api")
+	result := api.Route("/users", func(users *Group) {
+		users.GET("", func(c *Ctx) { c.String("list") })
+		users.Route("/:id", func(user *Group) {
+			user.GET("", func(c *Ctx) { c.String("get %s", c.Param("id")) })
+			user.DELETE("", func(c *Ctx) { c.String("delete %s", c.Param("id")) })
+		})
+	})
+	assert.Equal(api, result, "Route should return the group itself")
+	api.GET("/health", func(c *Ctx) { c.String("ok") })
+
+	serve := func(method, path string) string {
+		req, _ := http.NewRequest(method, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w.Body.String()
+	}
+
+	assert.Equal("list", serve(MethodGet, "/api/users"))
+	assert.Equal("get 3", serve(MethodGet, "/api/users/3"))
+	assert.Equal("delete 3", serve(MethodDelete, "/api/users/3"))
+	assert.Equal("ok", serve(MethodGet, "/api/health"))
+}
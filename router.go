@@ -1,6 +1,8 @@
 package ngebut
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/ryanbekhen/ngebut/internal/filebuffer"
 	"github.com/ryanbekhen/ngebut/internal/filecache"
@@ -8,45 +10,32 @@ import (
 	"github.com/ryanbekhen/ngebut/internal/radix"
 	"github.com/ryanbekhen/ngebut/internal/unsafe"
 	"io"
+	"io/fs"
 	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
 	"os"
 	"path/filepath"
-	"reflect"
-	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // route represents a route with a pattern, method, and handlers.
 type route struct {
-	Pattern    string
-	Method     string
-	Handlers   []Handler
-	Regex      *regexp.Regexp
-	HasParams  bool     // Precomputed flag indicating if the route has parameters
-	ParamCount int      // Precomputed count of parameters in the route
-	ParamNames []string // Precomputed parameter names
-
-	// Fields for optimized byte scanning
-	Segments   []string // Pattern segments for optimized matching
-	IsWildcard []bool   // Whether each segment is a wildcard
-	IsParam    []bool   // Whether each segment is a parameter
-}
-
-// middlewareStackPool is a pool of middleware stacks for reuse
-// This pool helps reduce memory allocations by reusing middleware stacks
-// instead of creating new ones for each request.
-var middlewareStackPool = pool.New(func() []MiddlewareFunc {
-	// Create a middleware stack with a larger initial capacity to reduce allocations
-	return make([]MiddlewareFunc, 0, 32)
-})
+	Pattern  string
+	Method   string
+	Handlers []Handler
+	Name     string // Name of the route, used for URL generation
 
-// routeSegmentPool is a pool for route segments to reduce allocations
-var routeSegmentPool = pool.New(func() []string {
-	return make([]string, 0, 8)
-})
+	// Source is the file:line where the route was registered
+	Source string
+}
 
 // stringBuilderPool is a pool for string builders to reduce allocations
 var stringBuilderPool = pool.New(func() *strings.Builder {
@@ -60,12 +49,81 @@ var allowedMethodsPool = pool.New(func() []string {
 
 // Router is an HTTP request router.
 type Router struct {
-	Routes          []route
-	routesByMethod  map[string][]route              // Routes indexed by method for faster lookup
-	routeTrees      map[string]*radix.Tree          // Radix trees indexed by method for faster lookup
-	staticRoutes    map[string]map[string][]Handler // Static routes indexed by method and path for O(1) lookup
-	middlewareFuncs []MiddlewareFunc
-	NotFound        Handler
+	Routes           []route
+	table            atomic.Pointer[routeTable] // Lookup structures of Routes, nil until built after a change
+	mu               sync.Mutex                 // Serializes changes to the routes
+	middlewareFuncs  []MiddlewareFunc
+	middlewarePaths  []string                  // Path prefix of each of middlewareFuncs, empty for global middleware
+	scopedMiddleware bool                      // Whether some middleware is path-scoped
+	namedRoutes      map[string]string         // Route patterns indexed by route name
+	hosts            []*hostRouter             // Routers for host patterns, see Host
+	mounts           []*mountedRouter          // Routers mounted at path prefixes, see Mount
+	groupScopes      []*groupScope             // NotFound and error handlers of group prefixes, longest first
+	routeShapes      map[string]map[string]int // Indexes in Routes by method and route shape, for conflict detection
+	registrationErrs []error                   // Route conflicts found with StrictRegistration
+	lastMethod       string                    // Method of the last route registered, empty if it was not registered
+	lastPattern      string                    // Pattern of the last route registered
+	versions         []*APIVersion             // API versions, see Version
+	NotFound         Handler
+
+	// ErrorHandler handles the errors of requests served by the router, taking precedence
+	// over the server error handler. When nil, errors are handled by the server error handler,
+	// or by the parent's error handler if the router is mounted with Mount.
+	ErrorHandler Handler
+
+	// MethodNotAllowed is called when the path matches a route registered for other methods.
+	// The Allow header listing those methods is set before it is called.
+	MethodNotAllowed Handler
+
+	// HandleMethodNotAllowed enables 405 Method Not Allowed responses.
+	// When disabled, such requests are handled by NotFound. Enabled by default.
+	HandleMethodNotAllowed bool
+
+	// HandleOPTIONS enables automatic responses to OPTIONS requests for paths without
+	// an OPTIONS route, with an Allow header listing the allowed methods. Enabled by default.
+	HandleOPTIONS bool
+
+	// HandleHEAD serves HEAD requests for paths without a HEAD route with the matching
+	// GET route. The response has the headers the GET response would have, without the body.
+	// Enabled by default.
+	HandleHEAD bool
+
+	// StrictRegistration makes Handle skip conflicting routes and record the conflicts,
+	// returned by Err, instead of panicking. Disabled by default.
+	StrictRegistration bool
+
+	// StrictRouting treats "/users" and "/users/" as different paths.
+	// When disabled, a path matches routes with or without its trailing slash. Enabled by default.
+	StrictRouting bool
+
+	// CaseSensitive treats "/Users" and "/users" as different paths.
+	// When disabled, the static segments of routes are matched ignoring case. Enabled by default.
+	CaseSensitive bool
+
+	// RedirectTrailingSlash redirects, with strict routing, requests for a path without a route
+	// to the same path with or without the trailing slash if it has one. Disabled by default.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath redirects requests for a path without a route to its cleaned path,
+	// with "." and ".." elements and repeated slashes removed, if it has one. Letter case is
+	// ignored in the lookup. Disabled by default.
+	//
+	// Redirects use 301 Moved Permanently for GET requests and 308 Permanent Redirect
+	// for other methods.
+	RedirectFixedPath bool
+
+	// SafeRedirects makes Ctx.Redirect and Ctx.RedirectBack refuse locations on another
+	// host than the host of the request, unless it is listed in RedirectHosts. This guards
+	// against open redirects to locations taken from the request. Enabling it on a router
+	// also applies it to its host, mounted and version routers. Disabled by default.
+	SafeRedirects bool
+
+	// RedirectHosts lists the other hosts redirects may go to with SafeRedirects,
+	// such as "example.com", or "*.example.com" for its subdomains.
+	RedirectHosts []string
+
+	// Versioning configures how requests select the API versions registered with Version.
+	Versioning Versioning
 
 	// Cache for compiled middleware chains to avoid repeated compilation
 	// The key is a hash of the middleware chain and the handler
@@ -76,20 +134,65 @@ type Router struct {
 func NewRouter() *Router {
 	return &Router{
 		Routes:          []route{},
-		routesByMethod:  make(map[string][]route),
-		routeTrees:      make(map[string]*radix.Tree),
-		staticRoutes:    make(map[string]map[string][]Handler),
 		middlewareFuncs: []MiddlewareFunc{},
+		namedRoutes:     make(map[string]string),
+		routeShapes:     make(map[string]map[string]int),
 		NotFound: func(c *Ctx) {
 			c.Status(StatusNotFound)
 			c.String("404 page not found")
 		},
+		MethodNotAllowed:       methodNotAllowedHandler,
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
+		HandleHEAD:             true,
+		StrictRouting:          true,
+		CaseSensitive:          true,
 	}
 }
 
+// inheritOptions copies the routing options of the router, such as StrictRouting and
+// CaseSensitive, to a child router serving some of its requests.
+func (r *Router) inheritOptions(child *Router) {
+	child.HandleMethodNotAllowed = r.HandleMethodNotAllowed
+	child.HandleOPTIONS = r.HandleOPTIONS
+	child.HandleHEAD = r.HandleHEAD
+	child.StrictRegistration = r.StrictRegistration
+	child.StrictRouting = r.StrictRouting
+	child.CaseSensitive = r.CaseSensitive
+	child.RedirectTrailingSlash = r.RedirectTrailingSlash
+	child.RedirectFixedPath = r.RedirectFixedPath
+	child.SafeRedirects = r.SafeRedirects
+	child.RedirectHosts = r.RedirectHosts
+	child.Versioning = r.Versioning
+}
+
 // Use adds middleware to the router.
 // It accepts middleware functions that take a context parameter.
+//
+// A path prefix can be given as the first argument to run the middleware only for
+// requests whose path is the prefix or is under it. Path-scoped middleware runs for
+// every such request, including requests that match no route and static files.
+// Global and path-scoped middleware run in the order they were added.
+//
+// Example:
+//
+//	router.Use(logger)
+//	router.Use("/admin", auth) // runs after logger, for /admin and /admin/*
 func (r *Router) Use(middleware ...interface{}) {
+	prefix := ""
+	if len(middleware) > 0 {
+		if p, ok := middleware[0].(string); ok {
+			if len(middleware) == 1 {
+				panic("middleware must be given after the path prefix")
+			}
+			prefix = strings.TrimRight(p, "/")
+			if prefix != "" && prefix[0] != '/' {
+				prefix = "/" + prefix
+			}
+			middleware = middleware[1:]
+		}
+	}
+
 	for _, m := range middleware {
 		switch m := m.(type) {
 		case Middleware:
@@ -99,200 +202,164 @@ func (r *Router) Use(middleware ...interface{}) {
 		default:
 			panic("middleware must be a function that takes a *Ctx parameter")
 		}
+		r.middlewarePaths = append(r.middlewarePaths, prefix)
+	}
+
+	if prefix != "" {
+		r.scopedMiddleware = true
 	}
+
+	// The middleware of the routes is chosen again when the table is rebuilt
+	r.table.Store(nil)
 }
 
-// Handle registers a new route with the given pattern and method.
-func (r *Router) Handle(pattern, method string, handlers ...Handler) *Router {
-	// Convert URL parameters like :id and wildcards * to regex patterns
-	var regexPattern string
-
-	if strings.Contains(pattern, ":") || strings.Contains(pattern, "*") {
-		// Get a string builder from the pool
-		sb := stringBuilderPool.Get()
-		sb.Reset()
-		defer stringBuilderPool.Put(sb)
-
-		// Get a segments slice from the pool
-		segments := routeSegmentPool.Get()
-		segments = segments[:0]
-		defer routeSegmentPool.Put(segments)
-
-		// Split the pattern into segments
-		start := 0
-		for i := 0; i < len(pattern); i++ {
-			if pattern[i] == '/' {
-				if i > start {
-					segments = append(segments, pattern[start:i])
-				}
-				start = i + 1
-			}
-		}
-		if start < len(pattern) {
-			segments = append(segments, pattern[start:])
+// middlewareFor returns the router middleware that runs for a request with the path.
+// The path is also compared cleaned, as the route tree skips repeated slashes, so that
+// path-scoped middleware can't be bypassed with another spelling of the path.
+func (r *Router) middlewareFor(path string) []MiddlewareFunc {
+	if !r.scopedMiddleware {
+		return r.middlewareFuncs
+	}
+
+	cleaned := cleanPath(path)
+	middleware := make([]MiddlewareFunc, 0, len(r.middlewareFuncs))
+	for i, m := range r.middlewareFuncs {
+		if prefix := r.middlewarePaths[i]; prefix == "" || r.pathHasPrefix(path, prefix) || r.pathHasPrefix(cleaned, prefix) {
+			middleware = append(middleware, m)
 		}
+	}
+	return middleware
+}
 
-		// Build the regex pattern
-		sb.WriteString("^")
-		// Add leading slash
-		sb.WriteString("/")
-		for i, segment := range segments {
-			if i > 0 {
-				sb.WriteString("/")
+// patternMiddleware returns the router middleware that runs for the paths matching the
+// segments of a route pattern variant. It reports false when the path-scoped middleware
+// depends on the values of the parameters and wildcards, and is chosen for each request.
+func (r *Router) patternMiddleware(segments []radix.Segment) ([]MiddlewareFunc, bool) {
+	middleware := make([]MiddlewareFunc, 0, len(r.middlewareFuncs))
+	for i, m := range r.middlewareFuncs {
+		if prefix := r.middlewarePaths[i]; prefix != "" {
+			under, known := patternHasPrefix(segments, radix.ParsePattern(prefix))
+			if !known {
+				return nil, false
 			}
-			if len(segment) > 0 && segment[0] == ':' {
-				// Parameter segment like :id
-				sb.WriteString("([^/]+)")
-			} else if segment == "*" {
-				// Wildcard segment - matches everything including slashes
-				sb.WriteString("(.*)")
-			} else {
-				// Regular segment - escape special regex characters
-				escaped := regexp.QuoteMeta(segment)
-				sb.WriteString(escaped)
+			if !under {
+				continue
 			}
 		}
-		sb.WriteString("$")
-		regexPattern = sb.String()
-	} else {
-		// Simple case, just add ^ and $ and escape special regex characters
-		regexPattern = "^" + regexp.QuoteMeta(pattern) + "$"
-	}
-
-	// Precompute parameter information
-	hasParams := strings.Contains(pattern, ":")
-	paramCount := strings.Count(pattern, ":")
-
-	// Extract parameter names
-	var paramNames []string
-	if hasParams || strings.Contains(pattern, "*") {
-		paramNames = make([]string, 0, paramCount+strings.Count(pattern, "*"))
-		start := 0
-		for i := 0; i < len(pattern); i++ {
-			if pattern[i] == ':' {
-				// Found a parameter
-				start = i + 1 // Skip the colon
-
-				// Find the end of the parameter (next slash or end of string)
-				end := strings.IndexByte(pattern[start:], '/')
-				if end == -1 {
-					// Parameter extends to the end of the pattern
-					paramNames = append(paramNames, pattern[start:])
-				} else {
-					// Parameter ends at a slash
-					paramNames = append(paramNames, pattern[start:start+end])
-				}
-			} else if pattern[i] == '*' {
-				// Found a wildcard parameter
-				// Check if it's a standalone * or part of another string
-				if (i == 0 || pattern[i-1] == '/') && (i == len(pattern)-1 || pattern[i+1] == '/') {
-					// Standalone wildcard parameter
-					paramNames = append(paramNames, "*")
-				}
-			}
+		middleware = append(middleware, m)
+	}
+	return middleware, true
+}
+
+// patternHasPrefix reports whether the paths matching the segments of a pattern are under
+// the segments of a path prefix. It reports known false when the static segments of the
+// pattern don't decide it.
+func patternHasPrefix(segments, prefix []radix.Segment) (under, known bool) {
+	for i, part := range prefix {
+		if i >= len(segments) {
+			return false, true
+		}
+		if segments[i].Kind != radix.Static {
+			return false, false
+		}
+		if segments[i].Path != part.Path {
+			return false, true
 		}
 	}
+	return true, true
+}
+
+// hasPathPrefix reports whether the path is the prefix, without a trailing slash, or a path under it.
+func hasPathPrefix(path, prefix string) bool {
+	return strings.HasPrefix(path, prefix) && (len(path) == len(prefix) || path[len(prefix)] == '/')
+}
+
+// pathHasPrefix is hasPathPrefix ignoring case when the routing is case insensitive.
+func (r *Router) pathHasPrefix(path, prefix string) bool {
+	if r.CaseSensitive {
+		return hasPathPrefix(path, prefix)
+	}
+	return len(path) >= len(prefix) && strings.EqualFold(path[:len(prefix)], prefix) &&
+		(len(path) == len(prefix) || path[len(prefix)] == '/')
+}
 
-	regex := regexp.MustCompile(regexPattern)
+// Handle registers a new route with the given pattern and method.
+//
+// It panics if the route conflicts with a route registered for the same method, that is
+// if both match the same paths, like "/users/:id" and "/users/:name". The panic value is a
+// *RouteConflictError naming both registrations. With StrictRegistration enabled, the
+// route is not registered and the conflict is returned by Err instead.
+//
+// When routes with different patterns match a path, static segments take precedence over
+// parameters, which take precedence over wildcards, from the first segment to the last.
+// For example, "/users/new" is served by "/users/new", then by "/users/:id", then by "/users/*".
+//
+// Routes can be added and removed with Remove while the server is running. Requests look
+// up routes in an immutable table that is replaced after a change, so they don't lock.
+func (r *Router) Handle(pattern, method string, handlers ...Handler) *Router {
+	r.handle(pattern, method, handlers)
+	return r
+}
 
-	// Parse pattern into segments for optimized byte scanning
-	segments := make([]string, 0, 8)
-	isWildcard := make([]bool, 0, 8)
-	isParam := make([]bool, 0, 8)
+// handle registers a route as Handle does, and reports whether it was registered.
+func (r *Router) handle(pattern, method string, handlers []Handler) bool {
+	source := callerSource()
 
-	// Skip leading slash if present
-	startIndex := 0
-	if len(pattern) > 0 && pattern[0] == '/' {
-		startIndex = 1
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if conflict := r.findConflict(method, pattern, source); conflict != nil {
+		if r.StrictRegistration {
+			r.registrationErrs = append(r.registrationErrs, conflict)
+			r.lastMethod, r.lastPattern = "", ""
+			return false
+		}
+		panic(conflict)
 	}
 
-	// Split pattern into segments
-	start := startIndex
-	for i := startIndex; i < len(pattern); i++ {
-		if pattern[i] == '/' {
-			if i > start {
-				segment := pattern[start:i]
-				segments = append(segments, segment)
-				isWildcard = append(isWildcard, segment == "*")
-				isParam = append(isParam, len(segment) > 0 && segment[0] == ':')
+	// Parse the pattern into parameters, wildcards and static segments
+	paramCount := 0
+	for _, segment := range radix.ParsePattern(pattern) {
+		switch segment.Kind {
+		case radix.Param:
+			// Parameter segment like :id, :id<int> or :id?
+			paramCount++
+
+			// Invalid constraints panic here rather than when the route tree is built
+			if segment.Constraint != "" {
+				compileConstraint(segment.Constraint)
 			}
-			start = i + 1
+		case radix.Wildcard:
+			// Wildcard segment like *, *path or +
+			paramCount++
 		}
 	}
 
-	// Add the last segment if there is one
-	if start < len(pattern) {
-		segment := pattern[start:]
-		segments = append(segments, segment)
-		isWildcard = append(isWildcard, segment == "*")
-		isParam = append(isParam, len(segment) > 0 && segment[0] == ':')
+	if paramCount > maxRouteParams {
+		panic(fmt.Sprintf("route %s has more than %d parameters", pattern, maxRouteParams))
 	}
 
 	newRoute := route{
-		Pattern:    pattern,
-		Method:     method,
-		Handlers:   handlers,
-		Regex:      regex,
-		HasParams:  hasParams,
-		ParamCount: paramCount,
-		ParamNames: paramNames,
-		Segments:   segments,
-		IsWildcard: isWildcard,
-		IsParam:    isParam,
+		Pattern:  pattern,
+		Method:   method,
+		Handlers: handlers,
+		Source:   source,
 	}
 
 	// Add to the main routes slice
 	r.Routes = append(r.Routes, newRoute)
+	r.addRouteShapes(len(r.Routes) - 1)
+	r.lastMethod, r.lastPattern = method, pattern
 
-	// Add to the method-specific routes map for faster lookup
-	r.routesByMethod[method] = append(r.routesByMethod[method], newRoute)
-
-	// For HEAD requests, we can also use GET handlers (HTTP spec)
-	if method == MethodGet {
-		r.routesByMethod[MethodHead] = append(r.routesByMethod[MethodHead], newRoute)
-	}
-
-	// Add to the radix tree for faster lookup
-	// Get or create the tree for this method
-	tree, exists := r.routeTrees[method]
-	if !exists {
-		tree = radix.NewTree()
-		r.routeTrees[method] = tree
-	}
-
-	// Insert the route into the tree
-	tree.Insert(pattern, method, handlers)
+	// The lookup structures are rebuilt for the next request
+	r.table.Store(nil)
 
-	// For HEAD requests, we can also use GET handlers (HTTP spec)
-	if method == MethodGet {
-		headTree, exists := r.routeTrees[MethodHead]
-		if !exists {
-			headTree = radix.NewTree()
-			r.routeTrees[MethodHead] = headTree
-		}
-		headTree.Insert(pattern, MethodHead, handlers)
-	}
-
-	// Add static routes to the staticRoutes map for O(1) lookup
-	if !hasParams && !strings.Contains(pattern, "*") {
-		// Initialize the method map if it doesn't exist
-		if _, exists := r.staticRoutes[method]; !exists {
-			r.staticRoutes[method] = make(map[string][]Handler)
-		}
-
-		// Add the route to the staticRoutes map
-		r.staticRoutes[method][pattern] = handlers
-
-		// For HEAD requests, we can also use GET handlers (HTTP spec)
-		if method == MethodGet {
-			if _, exists := r.staticRoutes[MethodHead]; !exists {
-				r.staticRoutes[MethodHead] = make(map[string][]Handler)
-			}
-			r.staticRoutes[MethodHead][pattern] = handlers
-		}
-	}
+	return true
+}
 
-	return r
+// hasTrailingSlash reports whether a path other than the root ends with a slash.
+func hasTrailingSlash(path string) bool {
+	return len(path) > 1 && path[len(path)-1] == '/'
 }
 
 // HandleStatic registers a new route for serving static files.
@@ -310,14 +377,19 @@ func (r *Router) HandleStatic(prefix, root string, config ...Static) *Router {
 	pattern := prefix + "*"
 
 	// Create the static file handler
-	handler := createStaticHandler(prefix, root, cfg)
+	handler := createStaticHandler(root, cfg)
 
 	// Register the route
 	return r.Handle(pattern, MethodGet, handler)
 }
 
 // createStaticHandler creates a handler function for serving static files
-func createStaticHandler(prefix, root string, config Static) Handler {
+func createStaticHandler(root string, config Static) Handler {
+	// Files of a file system such as embed.FS
+	if config.FS != nil {
+		return createFSStaticHandler(root, config)
+	}
+
 	// Ensure root path is absolute and clean
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
@@ -376,11 +448,9 @@ func createStaticHandler(prefix, root string, config Static) Handler {
 			return
 		}
 
-		// Get the file path from the URL
-		filePath := strings.TrimPrefix(c.Path(), strings.TrimSuffix(prefix, "/"))
-
-		// Remove leading slash if present
-		filePath = strings.TrimPrefix(filePath, "/")
+		// The file path is the path matched by the wildcard of the route, which may be
+		// spelled differently in the request, with another case or repeated slashes
+		filePath := strings.TrimPrefix(c.Param("*"), "/")
 
 		if filePath == "" {
 			filePath = config.Index
@@ -440,13 +510,7 @@ func createStaticHandler(prefix, root string, config Static) Handler {
 			}
 		}
 
-		// Handle byte range requests
-		if config.ByteRange && c.Get("Range") != "" {
-			serveFileWithRange(c, fullPath, fileInfo, config)
-			return
-		}
-
-		// Serve the file
+		// Serve the file, or the requested byte ranges of it
 		serveFile(c, fullPath, fileInfo, config)
 	}
 }
@@ -590,274 +654,109 @@ func getFDCacheInstance(maxSize int, expiration time.Duration) *filecache.FDCach
 	return cache
 }
 
-// serveFile serves a single file
+// serveFile serves a file of the OS file system, or the requested byte ranges of it
 func serveFile(c *Ctx, filePath string, fileInfo os.FileInfo, config Static) {
-	// Determine content type using the cache - do this once and reuse
-	contentType := getMimeType(filepath.Ext(filePath))
-
-	// Check if in-memory caching is enabled
-	if config.InMemoryCache {
-		// Get or create a cache instance
-		cache := getCacheInstance(config.MaxCacheSize, config.MaxCacheItems)
-
-		// Fast path: try to get the file from cache first without checking modification time
-		if cachedFile, exists := cache.Get(filePath); exists {
-			// Only check modification time if the file exists in cache
-			// This avoids an unnecessary stat call for cache misses
-			if !fileInfo.ModTime().After(cachedFile.ModTime) {
-				// Set headers
-				setFileHeaders(c, filePath, fileInfo, config)
-
-				// Call ModifyResponse if provided
-				if config.ModifyResponse != nil {
-					config.ModifyResponse(c)
-				}
-
-				// Set content type header directly from cached value
-				c.Set("Content-Type", cachedFile.ContentType)
-
-				// Serve from cache - write directly to avoid allocation
-				c.Writer.WriteHeader(c.statusCode)
-				_, _ = c.Writer.Write(cachedFile.Data)
-				return
-			}
-		}
-
-		// Skip caching for large files (> 1MB) to avoid memory pressure
-		// Large files are better served directly from disk
-		if fileInfo.Size() > 1024*1024 {
-			// Set headers
-			setFileHeaders(c, filePath, fileInfo, config)
-
-			// Call ModifyResponse if provided
-			if config.ModifyResponse != nil {
-				config.ModifyResponse(c)
-			}
-
-			// Set content type header
-			c.Set("Content-Type", contentType)
-			c.Writer.WriteHeader(c.statusCode)
-
-			// Try to get a cached file descriptor first
-			fdCache := getFDCacheInstance(100, 5*time.Minute)
-			var file *os.File
-			var err error
-
-			if fd, exists := fdCache.Get(filePath); exists && !fdCache.IsModified(filePath, fileInfo) {
-				// Use the cached file descriptor
-				file = fd.File
-
-				// Seek to the beginning of the file
-				if _, err = file.Seek(0, 0); err == nil {
-					// Get a read buffer from the pool for more efficient copying
-					buf := filebuffer.GetReadBuffer()
-					_, err = io.CopyBuffer(c.Writer, file, buf)
-					filebuffer.ReleaseReadBuffer(buf)
-
-					if err == nil {
-						return
-					}
-				}
-				// If seeking or copying fails, we'll fall through to opening a new file
-				fdCache.Remove(filePath)
-			}
-
-			// Open the file if we couldn't use a cached descriptor
-			file, err = os.Open(filePath)
-			if err != nil {
-				c.Status(StatusInternalServerError)
-				c.String("Error opening file")
-				return
-			}
-			defer file.Close()
-
-			// Get a read buffer from the pool for more efficient copying
-			buf := filebuffer.GetReadBuffer()
-			_, err = io.CopyBuffer(c.Writer, file, buf)
-			filebuffer.ReleaseReadBuffer(buf)
-
-			if err != nil {
-				logger.Error().Err(err).Msg("Error streaming file to response")
-			}
-			return
-		}
-
-		// Get a file descriptor from the cache or open the file
-		var file *os.File
-		var err error
-
-		// Try to get a cached file descriptor
+	serveContent(c, filePath, filePath, fileInfo, config, func() (io.Reader, func(), error) {
+		// Cached descriptors are shared by requests, which read them at their own offsets
 		fdCache := getFDCacheInstance(100, 5*time.Minute)
-		if fd, exists := fdCache.Get(filePath); exists && !fdCache.IsModified(filePath, fileInfo) {
-			// Use the cached file descriptor
-			file = fd.File
-
-			// Seek to the beginning of the file
-			if _, err = file.Seek(0, 0); err != nil {
-				// If seeking fails, close and reopen the file
-				fdCache.Remove(filePath)
-				file, err = os.Open(filePath)
-				if err != nil {
-					c.Status(StatusInternalServerError)
-					c.String("Error opening file")
-					return
-				}
-				// Cache the new file descriptor
-				fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
-			}
-		} else {
-			// Open the file
-			file, err = os.Open(filePath)
-			if err != nil {
-				c.Status(StatusInternalServerError)
-				c.String("Error opening file")
-				return
+		if fd, exists := fdCache.Get(filePath); exists {
+			if !fdCache.IsModified(filePath, fileInfo) {
+				return io.NewSectionReader(fd.File, 0, fileInfo.Size()), nil, nil
 			}
-			// Cache the file descriptor
-			fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
+			fdCache.Remove(filePath)
 		}
 
-		// No need to close the file as it's managed by the cache
-
-		// Get a buffer from the pool
-		buf := filebuffer.GetBuffer()
-		defer filebuffer.ReleaseBuffer(buf)
-
-		// Reset the buffer to ensure it's empty
-		buf.Reset()
-
-		// Use io.Copy with a read buffer from the pool for more efficient copying
-		readBuf := filebuffer.GetReadBuffer()
-		_, err = io.CopyBuffer(buf, file, readBuf)
-		filebuffer.ReleaseReadBuffer(readBuf)
-
+		file, err := os.Open(filePath)
 		if err != nil {
-			c.Status(StatusInternalServerError)
-			c.String("Error reading file")
-			return
+			return nil, nil, err
 		}
+		fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
+		return io.NewSectionReader(file, 0, fileInfo.Size()), nil, nil
+	})
+}
 
-		// Cache the file content directly from buffer
-		// This avoids an extra allocation and copy
-		cache.Set(filePath, buf.Bytes(), fileInfo.ModTime(), fileInfo.Size(), contentType)
-
-		// Set headers
-		setFileHeaders(c, filePath, fileInfo, config)
-
-		// Call ModifyResponse if provided
-		if config.ModifyResponse != nil {
-			config.ModifyResponse(c)
-		}
-
-		// Set content type header
-		c.Set("Content-Type", contentType)
-		c.Writer.WriteHeader(c.statusCode)
-
-		// Stream the buffer directly to the response writer
-		// This avoids an extra allocation and copy
-		_, _ = c.Writer.Write(buf.Bytes())
+// serveContent serves a file, or the requested byte ranges of it, for the OS and fs.FS
+// file serving. Conditional GET requests with If-Modified-Since get 304 Not Modified,
+// and the content type is derived from the extension of name.
+//
+// With InMemoryCache, files up to 1MB are served from the cache under key, and key ""
+// disables the cache. Otherwise the content is read from the reader returned by open,
+// seeking to the ranges when it is an io.Seeker; release, when not nil, is called once
+// the response is written.
+func serveContent(c *Ctx, key, name string, fileInfo fs.FileInfo, config Static, open func() (content io.Reader, release func(), err error)) {
+	if notModified(c, fileInfo.ModTime()) {
 		return
 	}
 
-	// In-memory caching disabled, use file descriptor cache
-
-	// For large files (> 1MB), use a more efficient approach
-	if fileInfo.Size() > 1024*1024 {
-		// Set headers
-		setFileHeaders(c, filePath, fileInfo, config)
-
-		// Call ModifyResponse if provided
-		if config.ModifyResponse != nil {
-			config.ModifyResponse(c)
-		}
-
-		// Set the content type header
-		c.Set("Content-Type", contentType)
-		c.Writer.WriteHeader(c.statusCode)
-
-		// Try to get a cached file descriptor first
-		fdCache := getFDCacheInstance(100, 5*time.Minute)
-		var file *os.File
-		var err error
-
-		if fd, exists := fdCache.Get(filePath); exists && !fdCache.IsModified(filePath, fileInfo) {
-			// Use the cached file descriptor
-			file = fd.File
-
-			// Seek to the beginning of the file
-			if _, err = file.Seek(0, 0); err == nil {
-				// Get a read buffer from the pool for more efficient copying
-				buf := filebuffer.GetReadBuffer()
-				_, err = io.CopyBuffer(c.Writer, file, buf)
-				filebuffer.ReleaseReadBuffer(buf)
-
-				if err == nil {
-					return
-				}
-			}
-			// If seeking or copying fails, we'll fall through to opening a new file
-			fdCache.Remove(filePath)
-		}
+	contentType := getMimeType(filepath.Ext(name))
+	fileSize := fileInfo.Size()
 
-		// Open the file if we couldn't use a cached descriptor
-		file, err = os.Open(filePath)
-		if err != nil {
-			c.Status(StatusInternalServerError)
-			c.String("Error opening file")
+	// Handle byte range requests
+	var ranges []httpRange
+	if rangeHeader := c.Get(HeaderRange); config.ByteRange && strings.HasPrefix(rangeHeader, "bytes=") {
+		ranges = parseRangeHeader(rangeHeader[6:], fileSize) // Remove "bytes=" prefix
+		if len(ranges) == 0 {
+			// Invalid range, return 416 Range Not Satisfiable
+			c.Status(StatusRequestedRangeNotSatisfiable)
+			c.Set(HeaderContentRange, fmt.Sprintf("bytes */%d", fileSize))
+			c.Writer.WriteHeader(c.statusCode)
 			return
 		}
-		defer file.Close()
-
-		// Get a read buffer from the pool for more efficient copying
-		buf := filebuffer.GetReadBuffer()
-		_, err = io.CopyBuffer(c.Writer, file, buf)
-		filebuffer.ReleaseReadBuffer(buf)
-
-		if err != nil {
-			logger.Error().Err(err).Msg("Error streaming file to response")
-		}
-		return
 	}
 
-	// For smaller files, use the file descriptor cache
-	var file *os.File
-	var err error
-
-	// Try to get a cached file descriptor
-	fdCache := getFDCacheInstance(100, 5*time.Minute)
-	if fd, exists := fdCache.Get(filePath); exists && !fdCache.IsModified(filePath, fileInfo) {
-		// Use the cached file descriptor
-		file = fd.File
-
-		// Seek to the beginning of the file
-		if _, err = file.Seek(0, 0); err != nil {
-			// If seeking fails, close and reopen the file
-			fdCache.Remove(filePath)
-			file, err = os.Open(filePath)
+	// Small files are served from the in-memory cache
+	var content io.Reader
+	if config.InMemoryCache && key != "" && fileSize <= 1024*1024 {
+		cache := getCacheInstance(config.MaxCacheSize, config.MaxCacheItems)
+		if cachedFile, exists := cache.Get(key); exists && !fileInfo.ModTime().After(cachedFile.ModTime) {
+			content = bytes.NewReader(cachedFile.Data)
+		} else {
+			reader, release, err := open()
 			if err != nil {
 				c.Status(StatusInternalServerError)
 				c.String("Error opening file")
 				return
 			}
-			// Cache the new file descriptor
-			fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
+			data, err := io.ReadAll(reader)
+			if release != nil {
+				release()
+			}
+			if err != nil {
+				c.Status(StatusInternalServerError)
+				c.String("Error reading file")
+				return
+			}
+			cache.Set(key, data, fileInfo.ModTime(), fileSize, contentType)
+			content = bytes.NewReader(data)
 		}
 	} else {
-		// Open the file
-		file, err = os.Open(filePath)
+		reader, release, err := open()
 		if err != nil {
 			c.Status(StatusInternalServerError)
 			c.String("Error opening file")
 			return
 		}
-		// Cache the file descriptor
-		fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
+		if release != nil {
+			defer release()
+		}
+		content = reader
 	}
 
-	// No need to close the file as it's managed by the cache
-
-	// Set headers
-	setFileHeaders(c, filePath, fileInfo, config)
+	// Set headers, the range headers override the length of the whole file
+	setFileHeaders(c, name, fileInfo, config)
+	var boundary string
+	switch {
+	case len(ranges) == 1:
+		c.Status(StatusPartialContent)
+		c.Set(HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", ranges[0].start, ranges[0].end, fileSize))
+		c.Set(HeaderContentLength, strconv.FormatInt(ranges[0].end-ranges[0].start+1, 10))
+	case len(ranges) > 1:
+		// Several ranges are sent as the parts of a multipart/byteranges body
+		var length int64
+		boundary, length = byteRangesBoundary(ranges, contentType, fileSize)
+		c.Status(StatusPartialContent)
+		c.Set(HeaderContentLength, strconv.FormatInt(length, 10))
+	}
 
 	// Call ModifyResponse if provided
 	if config.ModifyResponse != nil {
@@ -865,265 +764,98 @@ func serveFile(c *Ctx, filePath string, fileInfo os.FileInfo, config Static) {
 	}
 
 	// Set content type header
-	c.Set("Content-Type", contentType)
-	c.Writer.WriteHeader(c.statusCode)
-
-	// Use io.CopyBuffer with a read buffer from the pool for more efficient copying
-	buf := filebuffer.GetReadBuffer()
-	_, err = io.CopyBuffer(c.Writer, file, buf)
-	filebuffer.ReleaseReadBuffer(buf)
-
-	if err != nil {
-		logger.Error().Err(err).Msg("Error streaming file to response")
-	}
-}
-
-// serveFileWithRange serves a file with HTTP range support
-func serveFileWithRange(c *Ctx, filePath string, fileInfo os.FileInfo, config Static) {
-	rangeHeader := c.Get("Range")
-	if !strings.HasPrefix(rangeHeader, "bytes=") {
-		// Invalid range header, serve the whole file
-		serveFile(c, filePath, fileInfo, config)
-		return
+	if boundary != "" {
+		c.Set(HeaderContentType, "multipart/byteranges; boundary="+boundary)
+	} else {
+		c.Set(HeaderContentType, contentType)
 	}
-
-	fileSize := fileInfo.Size()
-	ranges := parseRangeHeader(rangeHeader[6:], fileSize) // Remove "bytes=" prefix
+	c.Writer.WriteHeader(c.statusCode)
 
 	if len(ranges) == 0 {
-		// Invalid range, return 416 Range Not Satisfiable
-		c.Status(StatusRequestedRangeNotSatisfiable)
-		c.Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
-		return
+		ranges = []httpRange{{start: 0, end: fileSize - 1}}
 	}
 
-	// For simplicity, only handle single range requests
-	if len(ranges) > 1 {
-		serveFile(c, filePath, fileInfo, config)
-		return
+	var parts *multipart.Writer
+	if boundary != "" {
+		parts = multipart.NewWriter(c.Writer)
+		_ = parts.SetBoundary(boundary)
 	}
 
-	r := ranges[0]
-
-	// Determine content type using the cache
-	contentType := getMimeType(filepath.Ext(filePath))
-
-	// Check if in-memory caching is enabled
-	if config.InMemoryCache {
-		// Get or create a cache instance
-		cache := getCacheInstance(config.MaxCacheSize, config.MaxCacheItems)
-
-		// Fast path: try to get the file from cache first without checking modification time
-		if cachedFile, exists := cache.Get(filePath); exists {
-			// Only check modification time if the file exists in cache
-			// This avoids an unnecessary stat call for cache misses
-			if !fileInfo.ModTime().After(cachedFile.ModTime) {
-				// Extract the requested range from the file data
-				if r.end >= int64(len(cachedFile.Data)) {
-					r.end = int64(len(cachedFile.Data)) - 1
-				}
-				if r.start >= int64(len(cachedFile.Data)) {
-					c.Status(StatusRequestedRangeNotSatisfiable)
-					c.Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
-					return
-				}
-
-				rangeLength := r.end - r.start + 1
-				rangeData := cachedFile.Data[r.start : r.end+1]
-
-				// Set range headers
-				c.Status(StatusPartialContent)
-				c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, fileSize))
-				c.Set("Accept-Ranges", "bytes")
-				c.Set("Content-Length", strconv.FormatInt(rangeLength, 10))
-
-				// Set other headers
-				setFileHeaders(c, filePath, fileInfo, config)
-
-				// Call ModifyResponse if provided
-				if config.ModifyResponse != nil {
-					config.ModifyResponse(c)
-				}
-
-				// Set content type header
-				c.Set("Content-Type", cachedFile.ContentType)
-
-				// Send the range content
-				c.Data(cachedFile.ContentType, rangeData)
-				return
-			}
-		}
-
-		// Get a file descriptor from the cache or open the file
-		var file *os.File
-		var err error
+	// Get a read buffer from the pool for more efficient copying
+	buf := filebuffer.GetReadBuffer()
+	defer filebuffer.ReleaseReadBuffer(buf)
 
-		// Try to get a cached file descriptor
-		fdCache := getFDCacheInstance(100, 5*time.Minute)
-		if fd, exists := fdCache.Get(filePath); exists && !fdCache.IsModified(filePath, fileInfo) {
-			// Use the cached file descriptor
-			file = fd.File
-
-			// Seek to the beginning of the file
-			if _, err = file.Seek(0, 0); err != nil {
-				// If seeking fails, close and reopen the file
-				fdCache.Remove(filePath)
-				file, err = os.Open(filePath)
-				if err != nil {
-					c.Status(StatusInternalServerError)
-					c.String("Error opening file")
-					return
-				}
-				// Cache the new file descriptor
-				fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
-			}
-		} else {
-			// Open the file
-			file, err = os.Open(filePath)
+	var offset int64
+	for _, r := range ranges {
+		var w io.Writer = c.Writer
+		if parts != nil {
+			part, err := parts.CreatePart(r.mimeHeader(contentType, fileSize))
 			if err != nil {
-				c.Status(StatusInternalServerError)
-				c.String("Error opening file")
+				logger.Error().Err(err).Msg("Error writing byte range part")
 				return
 			}
-			// Cache the file descriptor
-			fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
-		}
-
-		// No need to close the file as it's managed by the cache
-
-		// Get a buffer from the pool
-		buf := filebuffer.GetBuffer()
-		defer filebuffer.ReleaseBuffer(buf)
-
-		// Reset the buffer to ensure it's empty
-		buf.Reset()
-
-		// Use io.Copy to efficiently stream the file to the buffer
-		// This avoids manual read/write loops and is more efficient
-		_, err = io.Copy(buf, file)
-		if err != nil {
-			c.Status(StatusInternalServerError)
-			c.String("Error reading file")
-			return
+			w = part
 		}
 
-		// Get the data from the buffer
-		fileData := buf.Bytes()
-
-		// Cache the file content directly from buffer
-		// The Set method will make a copy of the data
-		cache.Set(filePath, fileData, fileInfo.ModTime(), fileInfo.Size(), contentType)
-
-		// Extract the requested range from the file data
-		if r.end >= int64(len(fileData)) {
-			r.end = int64(len(fileData)) - 1
-		}
-		if r.start >= int64(len(fileData)) {
-			c.Status(StatusRequestedRangeNotSatisfiable)
-			c.Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
+		if err := skipTo(content, offset, r.start); err != nil {
+			logger.Error().Err(err).Msg("Error seeking file")
 			return
 		}
-
-		rangeLength := r.end - r.start + 1
-		rangeData := fileData[r.start : r.end+1]
-
-		// Set range headers
-		c.Status(StatusPartialContent)
-		c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, fileSize))
-		c.Set("Accept-Ranges", "bytes")
-		c.Set("Content-Length", strconv.FormatInt(rangeLength, 10))
-
-		// Set other headers
-		setFileHeaders(c, filePath, fileInfo, config)
-
-		// Call ModifyResponse if provided
-		if config.ModifyResponse != nil {
-			config.ModifyResponse(c)
-		}
-
-		// Set content type header
-		c.Set("Content-Type", contentType)
-
-		// Send the range content
-		c.Data(contentType, rangeData)
-		return
-	}
-
-	// In-memory caching disabled, use file descriptor cache
-	var file *os.File
-	var err error
-
-	// Try to get a cached file descriptor
-	fdCache := getFDCacheInstance(100, 5*time.Minute)
-	if fd, exists := fdCache.Get(filePath); exists && !fdCache.IsModified(filePath, fileInfo) {
-		// Use the cached file descriptor
-		file = fd.File
-
-		// Seek to the requested position in the file
-		if _, err = file.Seek(r.start, 0); err != nil {
-			// If seeking fails, close and reopen the file
-			fdCache.Remove(filePath)
-			file, err = os.Open(filePath)
-			if err != nil {
-				c.Status(StatusInternalServerError)
-				c.String("Error opening file")
-				return
-			}
-			// Seek to the requested position
-			if _, err = file.Seek(r.start, 0); err != nil {
-				c.Status(StatusInternalServerError)
-				c.String("Error seeking file")
-				return
-			}
-			// Cache the new file descriptor
-			fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
-		}
-	} else {
-		// Open the file
-		file, err = os.Open(filePath)
+		n, err := io.CopyBuffer(w, io.LimitReader(content, r.end-r.start+1), buf)
+		offset = r.start + n
 		if err != nil {
-			c.Status(StatusInternalServerError)
-			c.String("Error opening file")
-			return
-		}
-		// Seek to the requested position
-		if _, err = file.Seek(r.start, 0); err != nil {
-			c.Status(StatusInternalServerError)
-			c.String("Error seeking file")
+			logger.Error().Err(err).Msg("Error streaming file to response")
 			return
 		}
-		// Cache the file descriptor
-		fdCache.Set(filePath, file, fileInfo.ModTime(), fileInfo.Size())
 	}
 
-	// No need to close the file as it's managed by the cache
-
-	// Calculate the range length
-	rangeLength := r.end - r.start + 1
-
-	// Set range headers
-	c.Status(StatusPartialContent)
-	c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, fileSize))
-	c.Set("Accept-Ranges", "bytes")
-	c.Set("Content-Length", strconv.FormatInt(rangeLength, 10))
+	if parts != nil {
+		_ = parts.Close()
+	}
+}
 
-	// Set other headers
-	setFileHeaders(c, filePath, fileInfo, config)
+// skipTo moves the content, read up to offset, to the start of a range. Readers that
+// are not an io.Seeker can only move forward.
+func skipTo(content io.Reader, offset, start int64) error {
+	if seeker, ok := content.(io.Seeker); ok {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}
+	if start < offset {
+		return errors.New("file does not support seeking back to a byte range")
+	}
+	_, err := io.CopyN(io.Discard, content, start-offset)
+	return err
+}
 
-	// Call ModifyResponse if provided
-	if config.ModifyResponse != nil {
-		config.ModifyResponse(c)
+// mimeHeader returns the header of the multipart/byteranges part of the range.
+func (r httpRange) mimeHeader(contentType string, fileSize int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		HeaderContentRange: {fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, fileSize)},
+		HeaderContentType:  {contentType},
 	}
+}
 
-	// Set content type header
-	c.Set("Content-Type", contentType)
+// byteRangesBoundary returns a boundary for the multipart/byteranges body of the ranges,
+// and the length of the body.
+func byteRangesBoundary(ranges []httpRange, contentType string, fileSize int64) (string, int64) {
+	var counter byteCounter
+	parts := multipart.NewWriter(&counter)
+	var length int64
+	for _, r := range ranges {
+		_, _ = parts.CreatePart(r.mimeHeader(contentType, fileSize))
+		length += r.end - r.start + 1
+	}
+	_ = parts.Close()
+	return parts.Boundary(), length + int64(counter)
+}
 
-	// Use io.CopyN to efficiently stream the range directly to the response writer
-	// This avoids buffer allocations and manual read/write loops
-	_, err = io.CopyN(c.Writer, file, rangeLength)
-	if err != nil && err != io.EOF {
-		logger.Error().Err(err).Msg("Error streaming file range to response")
-	}
+// byteCounter is an io.Writer counting the bytes written to it
+type byteCounter int64
+
+func (w *byteCounter) Write(p []byte) (int, error) {
+	*w += byteCounter(len(p))
+	return len(p), nil
 }
 
 // serveDirectoryListing serves a directory listing
@@ -1135,6 +867,11 @@ func serveDirectoryListing(c *Ctx, dirPath, urlPath string) {
 		return
 	}
 
+	writeDirectoryListing(c, entries, urlPath)
+}
+
+// writeDirectoryListing writes the HTML listing of the directory entries
+func writeDirectoryListing(c *Ctx, entries []fs.DirEntry, urlPath string) {
 	// Build HTML directory listing
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
@@ -1248,8 +985,10 @@ func getMimeType(ext string) string {
 // This optimized version reduces allocations by using pre-allocated header names
 // and combining multiple header settings where possible
 func setFileHeaders(c *Ctx, filePath string, fileInfo os.FileInfo, config Static) {
-	// Set Last-Modified header
-	c.Set(HeaderLastModified, fileInfo.ModTime().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+	// Set Last-Modified header, unless the modification time is unknown as for embedded files
+	if !fileInfo.ModTime().IsZero() {
+		c.Set(HeaderLastModified, fileInfo.ModTime().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+	}
 
 	// Set Cache-Control header
 	if config.MaxAge > 0 {
@@ -1261,8 +1000,7 @@ func setFileHeaders(c *Ctx, filePath string, fileInfo os.FileInfo, config Static
 
 	// Set Content-Disposition for downloads
 	if config.Download {
-		filename := filepath.Base(filePath)
-		c.Set(HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		c.Set(HeaderContentDisposition, contentDisposition("attachment", filepath.Base(filePath)))
 	}
 
 	// Set Accept-Ranges header if byte range is supported
@@ -1404,202 +1142,139 @@ func (r *Router) STATIC(prefix, root string, config ...Static) *Router {
 	return r.HandleStatic(prefix, root, config...)
 }
 
-// matchRouteByteScanning matches a path against a route using byte scanning
-// This is more efficient than regex-based matching for parameter extraction
-// Returns true if the path matches the route and populates the matches slice with parameter values
-func matchRouteByteScanning(route *route, path string, matches *[]string) bool {
-	// Skip leading slash if present
-	pathStartIndex := 0
-	if len(path) > 0 && path[0] == '/' {
-		pathStartIndex = 1
-	}
-
-	// Split path into segments
-	pathSegments := make([]string, 0, 8)
-	start := pathStartIndex
-	for i := pathStartIndex; i < len(path); i++ {
-		if path[i] == '/' {
-			if i > start {
-				pathSegments = append(pathSegments, path[start:i])
-			}
-			start = i + 1
+// ErrRouteNotFound is returned by URL when no route has the given name.
+var ErrRouteNotFound = errors.New("route not found")
+
+// Name assigns a name to the most recently registered route.
+// Named routes can be turned into URLs with URL and Ctx.RedirectToRoute.
+// It panics if no route has been registered or if the name is already used.
+// When the last route was not registered because of a conflict with StrictRegistration,
+// no route is named, and the conflict is returned by Err.
+func (r *Router) Name(name string) *Router {
+	r.mu.Lock()
+	method, pattern, skipped := r.lastMethod, r.lastPattern, len(r.registrationErrs) > 0
+	r.mu.Unlock()
+
+	if method == "" {
+		if skipped {
+			return r
 		}
+		panic("route name must be set after registering a route")
 	}
 
-	// Add the last segment if there is one
-	if start < len(path) {
-		pathSegments = append(pathSegments, path[start:])
+	r.nameRoute(method, pattern, name)
+	return r
+}
+
+// nameRoute assigns a name to the route registered with the given method and pattern.
+// The routes are copied before the change, since the old slice may be in use by requests.
+func (r *Router) nameRoute(method, pattern, name string) {
+	if name == "" {
+		panic("route name must not be empty")
 	}
 
-	// Quick check: if segment counts don't match and there's no wildcard, it can't match
-	if len(pathSegments) != len(route.Segments) {
-		// Check if the route has a wildcard segment that could match multiple path segments
-		hasWildcard := false
-		for _, isWild := range route.IsWildcard {
-			if isWild {
-				hasWildcard = true
-				break
-			}
-		}
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		if !hasWildcard {
-			return false
-		}
+	if named, exists := r.namedRoutes[name]; exists {
+		panic(fmt.Sprintf("route name %q is already used by %s", name, named))
 	}
 
-	// Reset matches slice and add full path as first match (to mimic regex behavior)
-	*matches = (*matches)[:0]
-	*matches = append(*matches, path)
-
-	// Match segments
-	pathIndex := 0
-	for i, segment := range route.Segments {
-		// Special case for wildcard at the end of the route
-		if route.IsWildcard[i] && i == len(route.Segments)-1 {
-			// Last segment is wildcard - capture all remaining path segments
-			// This works even if there are no more path segments (empty wildcard)
-			wildValue := ""
-			if pathIndex < len(pathSegments) {
-				wildValue = strings.Join(pathSegments[pathIndex:], "/")
-			}
-			*matches = append(*matches, wildValue)
-			return true
+	for i := range r.Routes {
+		if r.Routes[i].Method != method || r.Routes[i].Pattern != pattern {
+			continue
 		}
 
-		// If we've run out of path segments, this can only match if all remaining route segments are optional
-		if pathIndex >= len(pathSegments) {
-			return false
+		routes := make([]route, len(r.Routes))
+		copy(routes, r.Routes)
+		rt := &routes[i]
+		if rt.Name != "" {
+			delete(r.namedRoutes, rt.Name)
 		}
+		rt.Name = name
+		r.namedRoutes[name] = rt.Pattern
+		r.Routes = routes
 
-		if route.IsParam[i] {
-			// Parameter segment - capture the value
-			*matches = append(*matches, pathSegments[pathIndex])
-			pathIndex++
-		} else if route.IsWildcard[i] {
-			// Non-terminal wildcard - this is complex and rare, fall back to regex
-			return false
-		} else {
-			// Static segment - must match exactly
-			if segment != pathSegments[pathIndex] {
-				return false
-			}
-			pathIndex++
-		}
+		// The lookup structures are rebuilt with the new name
+		r.table.Store(nil)
+		return
 	}
-
-	// All segments matched and we've consumed all path segments
-	return pathIndex == len(pathSegments)
+	panic(fmt.Sprintf("route %s %s is not registered", method, pattern))
 }
 
-// handleMatchedRoute handles a route that matched the path and method
-func (r *Router) handleMatchedRoute(ctx *Ctx, req *Request, route route, matches []string) {
-	// Extract URL parameters using precomputed values
-	if route.HasParams {
-		// Get a routeParams struct from the pool (new optimized approach)
-		// Only if we don't already have one in the context
-		var routeParams *routeParams
-		if ctx.paramCache.routeParams != nil {
-			// Reuse the existing routeParams to avoid allocation
-			routeParams = ctx.paramCache.routeParams
-			routeParams.Reset()
-		} else {
-			routeParams = getRouteParams()
-			// Store parameters directly in the context's paramCache
-			// This avoids the expensive context.WithValue and req.WithContext operations
-			ctx.paramCache.routeParams = routeParams
-		}
-		ctx.paramCache.valid = true
-
-		// Extract parameters directly from regex matches using precomputed parameter names
-		// The regex pattern is created to capture each parameter in order
-		paramIndex := 1 // Start from 1 to skip the full match
-
-		// Use the precomputed parameter names to avoid string slicing at runtime
-		// First try to use fixed-size arrays for parameters (zero allocation path)
-		paramCount := len(route.ParamNames)
-		useFixedArrays := paramCount <= len(routeParams.fixedKeys)
-
-		// Fast path for common case of 1-2 parameters
-		if useFixedArrays && paramCount <= 2 && paramCount > 0 {
-			// Unrolled loop for 1-2 parameters (most common case)
-			// This avoids the loop overhead and bounds checking
-			if paramIndex < len(matches) {
-				// Store parameter name directly (it's already a string, no allocation)
-				routeParams.fixedKeys[0] = route.ParamNames[0]
-
-				// Store parameter value directly (it's already a string, no allocation)
-				routeParams.fixedValues[0] = matches[paramIndex]
-
-				// Compute and store hash code for faster lookups
-				routeParams.fixedHashes[0] = stringHash(route.ParamNames[0])
-
-				routeParams.count = 1
-				paramIndex++
-
-				if paramCount == 2 && paramIndex < len(matches) {
-					// Store parameter name directly (it's already a string, no allocation)
-					routeParams.fixedKeys[1] = route.ParamNames[1]
+// URL builds the path of the named route.
+// Parameters in the pattern are replaced with the escaped values from params,
+// the wildcard value "*" keeps its slashes, and query is appended as the query string.
+//
+// Example:
+//
+//	router.GET("/users/:id", showUser).Name("user.show")
+//	url, err := router.URL("user.show", map[string]string{"id": "42"}, nil) // "/users/42"
+func (r *Router) URL(name string, params map[string]string, query url.Values) (string, error) {
+	pattern, exists := r.routeTable().namedRoutes[name]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
 
-					// Store parameter value directly (it's already a string, no allocation)
-					routeParams.fixedValues[1] = matches[paramIndex]
+	path, err := buildRoutePath(pattern, params)
+	if err != nil {
+		return "", fmt.Errorf("route %s: %w", name, err)
+	}
 
-					// Compute and store hash code for faster lookups
-					routeParams.fixedHashes[1] = stringHash(route.ParamNames[1])
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
 
-					routeParams.count = 2
+// buildRoutePath replaces the parameters of a route pattern with the given values.
+// Omitted optional parameters and empty * wildcards are left out of the path.
+func buildRoutePath(pattern string, params map[string]string) (string, error) {
+	sb := stringBuilderPool.Get()
+	sb.Reset()
+	defer stringBuilderPool.Put(sb)
+
+	for _, segment := range radix.ParsePattern(pattern) {
+		switch segment.Kind {
+		case radix.Param:
+			value := params[segment.Name]
+			if value == "" {
+				if segment.Optional {
+					continue
 				}
+				return "", fmt.Errorf("missing parameter %q", segment.Name)
 			}
-		} else {
-			// General case for any number of parameters
-			for i, paramName := range route.ParamNames {
-				if paramIndex < len(matches) {
-					if useFixedArrays {
-						// Use fixed-size arrays for small number of parameters (zero allocation)
-						// Store parameter name directly (it's already a string, no allocation)
-						routeParams.fixedKeys[i] = paramName
-
-						// Store parameter value directly (it's already a string, no allocation)
-						routeParams.fixedValues[i] = matches[paramIndex]
-
-						// Compute and store hash code for faster lookups
-						routeParams.fixedHashes[i] = stringHash(paramName)
-
-						routeParams.count++
-					} else {
-						// Fall back to dynamic slices for routes with many parameters
-						// Avoid append if possible by pre-allocating slices
-						if i < cap(routeParams.keys) {
-							// If we have enough capacity, just set the values directly
-							if i >= len(routeParams.keys) {
-								// Extend the slices without allocation
-								routeParams.keys = routeParams.keys[:i+1]
-								routeParams.values = routeParams.values[:i+1]
-								routeParams.hashes = routeParams.hashes[:i+1]
-							}
-							routeParams.keys[i] = paramName
-							routeParams.values[i] = matches[paramIndex]
-							routeParams.hashes[i] = stringHash(paramName)
-						} else {
-							// If we don't have enough capacity, append (this will allocate)
-							routeParams.keys = append(routeParams.keys, paramName)
-							routeParams.values = append(routeParams.values, matches[paramIndex])
-							routeParams.hashes = append(routeParams.hashes, stringHash(paramName))
-						}
-					}
-					paramIndex++
+			sb.WriteByte('/')
+			sb.WriteString(url.PathEscape(value))
+		case radix.Wildcard:
+			value, ok := params[segment.Name]
+			if !ok && (segment.Name == "*" || segment.Name == "+") {
+				value = params[segment.Name+"1"]
+			}
+			if value == "" {
+				if segment.OneOrMore {
+					return "", fmt.Errorf("missing parameter %q", segment.Name)
 				}
+				continue
 			}
-		}
 
-		// We don't need to store the parameter context in UserData anymore
-		// It's already stored in ctx.paramCache.routeParams
+			// Wildcards may span several segments, escape each of them
+			for _, part := range strings.Split(value, "/") {
+				sb.WriteByte('/')
+				sb.WriteString(url.PathEscape(part))
+			}
+		default:
+			sb.WriteByte('/')
+			sb.WriteString(segment.Path)
+		}
 	}
 
-	// Update the request in the context
-	ctx.Request = req
+	// Keep the trailing slash of the pattern
+	if sb.Len() == 0 || (len(pattern) > 1 && pattern[len(pattern)-1] == '/') {
+		sb.WriteByte('/')
+	}
 
-	// Set up the middleware stack with both global middleware and route handlers
-	r.setupMiddleware(ctx, route.Handlers)
+	return sb.String(), nil
 }
 
 // generateMiddlewareHash generates a hash for a middleware chain and handler
@@ -1610,8 +1285,8 @@ func (r *Router) generateMiddlewareHash(middleware []Middleware, handler Handler
 
 	// Hash the global middleware functions
 	for _, m := range middleware {
-		// Get the pointer value of the middleware function
-		ptr := reflect.ValueOf(m).Pointer()
+		// Get the pointer value of the middleware function, distinct for each closure
+		ptr := unsafe.FuncValue(m)
 
 		// Mix the pointer into the hash
 		h ^= uint64(ptr)
@@ -1619,7 +1294,7 @@ func (r *Router) generateMiddlewareHash(middleware []Middleware, handler Handler
 	}
 
 	// Hash the handler function
-	handlerPtr := reflect.ValueOf(handler).Pointer()
+	handlerPtr := unsafe.FuncValue(handler)
 	h ^= uint64(handlerPtr)
 	h *= 1099511628211 // FNV prime
 
@@ -1628,6 +1303,21 @@ func (r *Router) generateMiddlewareHash(middleware []Middleware, handler Handler
 
 // setupMiddleware sets up the middleware stack for a request
 func (r *Router) setupMiddleware(ctx *Ctx, handlers []Handler) {
+	r.runHandlers(ctx, r.middlewareFor(ctx.Request.URL.Path), handlers)
+}
+
+// serveLeaf calls the handlers of a matched route, with the router middleware chosen
+// when the route table was built if it could be.
+func (r *Router) serveLeaf(ctx *Ctx, leaf *routeLeaf) {
+	if leaf.resolved {
+		r.runHandlers(ctx, leaf.middleware, leaf.handlers)
+		return
+	}
+	r.setupMiddleware(ctx, leaf.handlers)
+}
+
+// runHandlers calls the router middleware and the handlers of a request
+func (r *Router) runHandlers(ctx *Ctx, globalMiddleware []MiddlewareFunc, handlers []Handler) {
 	// Pre-calculate counts to avoid repeated len() calls
 	handlerCount := len(handlers)
 	if handlerCount == 0 {
@@ -1635,7 +1325,7 @@ func (r *Router) setupMiddleware(ctx *Ctx, handlers []Handler) {
 	}
 
 	// Fast path: if we have no middleware and only one handler, call it directly
-	globalMiddlewareCount := len(r.middlewareFuncs)
+	globalMiddlewareCount := len(globalMiddleware)
 	if globalMiddlewareCount == 0 && handlerCount == 1 {
 		handlers[0](ctx)
 		return
@@ -1651,7 +1341,7 @@ func (r *Router) setupMiddleware(ctx *Ctx, handlers []Handler) {
 		allMiddleware := make([]Middleware, 0, globalMiddlewareCount+handlerCount-1)
 
 		// Add global middleware
-		for _, m := range r.middlewareFuncs {
+		for _, m := range globalMiddleware {
 			allMiddleware = append(allMiddleware, m)
 		}
 
@@ -1719,100 +1409,6 @@ func (r *Router) setupMiddleware(ctx *Ctx, handlers []Handler) {
 		// Execute the compiled handler
 		compiledHandler(ctx)
 	}
-
-	// Legacy path: fall back to dynamic middleware for backward compatibility
-	// This should never be reached with the new implementation but kept for safety
-
-	// Calculate the total middleware size
-	totalMiddleware := globalMiddlewareCount + handlerCount - 1
-	if totalMiddleware <= 0 {
-		return
-	}
-
-	// Ultra-fast path: use fixed-size buffer if the total middleware count fits
-	// This avoids all allocations and slice manipulations
-	if totalMiddleware <= len(ctx.fixedMiddleware) {
-		// Reset the fixed middleware count
-		ctx.fixedCount = totalMiddleware
-
-		// Add all but the last handler as middleware directly to the fixed buffer
-		if handlerCount > 1 {
-			for i := 0; i < handlerCount-1; i++ {
-				// We must use type conversion here as Handler and MiddlewareFunc are not directly compatible
-				ctx.fixedMiddleware[globalMiddlewareCount+i] = MiddlewareFunc(handlers[i])
-			}
-		}
-
-		// Set the last handler as the final handler
-		ctx.middlewareIndex = -1
-		ctx.handler = handlers[handlerCount-1]
-
-		// Call the first middleware function
-		ctx.Next()
-		return
-	}
-
-	// Legacy path: use dynamic middleware stack for larger middleware chains
-	// Prepare the middleware stack
-	// Try to reuse the existing slice first
-	if cap(ctx.middlewareStack) >= totalMiddleware {
-		// Reuse the existing slice
-		ctx.middlewareStack = ctx.middlewareStack[:totalMiddleware]
-	} else {
-		// Get a middleware stack from the pool
-		stack := middlewareStackPool.Get()
-
-		if cap(stack) >= totalMiddleware {
-			// Reuse the stack with the right size
-			ctx.middlewareStack = stack[:totalMiddleware]
-		} else {
-			// Return the too-small stack to the pool
-			middlewareStackPool.Put(stack)
-
-			// Get another stack from the pool - we've increased the default capacity,
-			// so this should be rare
-			stack = middlewareStackPool.Get()
-
-			if cap(stack) >= totalMiddleware {
-				// Use this stack if it's big enough
-				ctx.middlewareStack = stack[:totalMiddleware]
-			} else {
-				// Return this stack too
-				middlewareStackPool.Put(stack)
-
-				// Create a new stack with sufficient capacity
-				// Add some extra capacity to reduce future allocations
-				// Use a larger minimum extra capacity
-				extraCapacity := 16
-				if totalMiddleware > 32 {
-					extraCapacity = totalMiddleware / 2 // 50% extra for large stacks
-				}
-
-				// Create the new stack and add it to the pool for future reuse
-				newStack := make([]MiddlewareFunc, totalMiddleware, totalMiddleware+extraCapacity)
-				ctx.middlewareStack = newStack
-			}
-		}
-	}
-
-	// Reset the fixed middleware count to indicate we're using the dynamic stack
-	ctx.fixedCount = 0
-
-	// Add all but the last handler as middleware
-	// Use direct indexing for better performance
-	if handlerCount > 1 {
-		for i := 0; i < handlerCount-1; i++ {
-			// We must use type conversion here as Handler and MiddlewareFunc are not directly compatible
-			ctx.middlewareStack[globalMiddlewareCount+i] = MiddlewareFunc(handlers[i])
-		}
-	}
-
-	// Set the last handler as the final handler
-	ctx.middlewareIndex = -1
-	ctx.handler = handlers[handlerCount-1]
-
-	// Call the first middleware function
-	ctx.Next()
 }
 
 // Pre-allocated handler for method not allowed responses
@@ -1822,64 +1418,50 @@ var methodNotAllowedHandler = func(c *Ctx) {
 	c.String("Method Not Allowed")
 }
 
-// pathMatchContext is a reusable context for path matching operations
-// It pre-allocates memory for common operations to reduce allocations
-type pathMatchContext struct {
-	// Segments for path matching
-	segments []string
-
-	// Temporary byte slice for path operations
-	pathBytes []byte
-
-	// Reusable parameter map
-	params map[string]string
-
-	// Pre-allocated slices for parameter keys and values
-	// These are used to avoid allocations when copying parameters
-	paramKeys   []string
-	paramValues []string
+// Pre-allocated handler for automatic OPTIONS responses
+var optionsHandler = func(c *Ctx) {
+	// The Allow header will be set before this handler is called
+	c.Status(StatusNoContent)
+	c.prepareResponse("")
 }
 
-// Reset resets the context for reuse
-func (c *pathMatchContext) Reset() {
-	// Clear segments without deallocating
-	c.segments = c.segments[:0]
-
-	// Clear pathBytes without deallocating
-	c.pathBytes = c.pathBytes[:0]
+// allowedMethods appends the methods, other than skip, that have a route matching path
+// to the methods already in allowed. The methods are sorted, and HEAD is added when GET is
+// allowed, as HEAD requests are served by the GET routes with HandleHEAD.
+// The path "*" matches every registered method, as used by "OPTIONS *" requests.
+func (r *Router) allowedMethods(path, skip string, allowed []string) []string {
+	for method := range r.routeTable().trees {
+		if method == skip || slices.Contains(allowed, method) {
+			continue
+		}
+		if path == "*" || r.methodMatchesPath(method, path) {
+			allowed = append(allowed, method)
+		}
+	}
 
-	// Clear params without deallocating
-	for k := range c.params {
-		delete(c.params, k)
+	// HEAD requests are served by the GET routes
+	if r.HandleHEAD && skip != MethodHead && slices.Contains(allowed, MethodGet) && !slices.Contains(allowed, MethodHead) {
+		allowed = append(allowed, MethodHead)
 	}
 
-	// Clear parameter keys and values without deallocating
-	c.paramKeys = c.paramKeys[:0]
-	c.paramValues = c.paramValues[:0]
+	sort.Strings(allowed)
+	return allowed
 }
 
-// pathMatchContextPool is a pool of pathMatchContext objects
-var pathMatchContextPool = sync.Pool{
-	New: func() interface{} {
-		return &pathMatchContext{
-			segments:    make([]string, 0, 16),      // Pre-allocate for common path depth
-			pathBytes:   make([]byte, 0, 128),       // Pre-allocate for common path length
-			params:      make(map[string]string, 8), // Pre-allocate for common number of params
-			paramKeys:   make([]string, 0, 16),      // Pre-allocate for common number of parameters
-			paramValues: make([]string, 0, 16),      // Pre-allocate for common number of parameters
-		}
-	},
-}
+// methodMatchesPath reports whether a route registered for method matches path.
+func (r *Router) methodMatchesPath(method, path string) bool {
+	t := r.routeTable()
+	if t.staticLeaf(method, path) != nil {
+		return true
+	}
 
-// getPathMatchContext gets a pathMatchContext from the pool
-func getPathMatchContext() *pathMatchContext {
-	return pathMatchContextPool.Get().(*pathMatchContext)
-}
+	root := t.trees[method]
+	if root == nil {
+		return false
+	}
 
-// releasePathMatchContext returns a pathMatchContext to the pool
-func releasePathMatchContext(ctx *pathMatchContext) {
-	ctx.Reset()
-	pathMatchContextPool.Put(ctx)
+	var values routeValues
+	return root.match(path, 0, &values) != nil
 }
 
 // ServeHTTP implements a modified http.Handler interface that accepts a Ctx.
@@ -1887,283 +1469,133 @@ func (r *Router) ServeHTTP(ctx *Ctx, req *Request) {
 	path := req.URL.Path
 	method := req.Method
 
-	// O(1) lookup for static routes using hash map
-	if methodRoutes, exists := r.staticRoutes[method]; exists {
-		if handlers, found := methodRoutes[path]; found {
-			// We found a static match in the hash map, handle it without parameter processing
-			// Set up middleware and call the handler
-			r.setupMiddleware(ctx, handlers)
-			return
-		}
+	// Remember the router the request was given to, whose options apply to its sub-routers
+	if ctx.rootRouter == nil {
+		ctx.rootRouter = r
 	}
 
-	// Convert path to byte slice without allocation using unsafe
-	pathBytes := unsafe.S2B(path)
-
-	// Ultra-fast path: try to find a static match using the radix tree
-	// This avoids allocating a params map for static routes
-	if tree, exists := r.routeTrees[method]; exists {
-		// First try to find a static match (no parameters)
-		if handlers, found := tree.FindStaticBytes(pathBytes); found {
-			if handlerSlice, ok := handlers[method].([]Handler); ok {
-				// We found a static match, handle it without parameter processing
-				// Set up middleware and call the handler
-				r.setupMiddleware(ctx, handlerSlice)
-				return
-			}
+	// Requests for a host with its own router are served by it
+	if len(r.hosts) > 0 {
+		if h := r.matchHost(ctx, req.Host); h != nil {
+			r.setupMiddleware(ctx, h.handlers)
+			return
 		}
+	}
 
-		// If no static match, try with parameters
-		// Get a path match context from the pool for optimized path matching
-		pathCtx := getPathMatchContext()
-		defer releasePathMatchContext(pathCtx)
-
-		// Try to find a match in the radix tree using byte slice path
-		if handlers, found := tree.FindBytes(pathBytes, pathCtx.params); found {
-			if handlerSlice, ok := handlers[method].([]Handler); ok {
-				// We found a match, handle it
-				// Create a context with the parameters
-				if len(pathCtx.params) > 0 {
-					// Get a routeParams struct from the pool (new optimized approach)
-					routeParams := getRouteParams()
-
-					// Store parameters directly in the context's paramCache
-					// This avoids the expensive context.WithValue call
-					ctx.paramCache.routeParams = routeParams
-					ctx.paramCache.valid = true
-
-					// Reset the keys and values slices without allocating
-					// This is safe because we've pre-allocated the slices with capacity for common routes
-					routeParams.Reset()
-
-					// Copy parameters from the radix tree match
-					// First try to use fixed-size arrays for parameters (zero allocation path)
-					paramCount := len(pathCtx.params)
-					useFixedArrays := paramCount <= len(routeParams.fixedKeys)
-
-					// Extract parameter keys and values directly without map iteration
-					// This is much faster than iterating over the map
-					pathCtx.paramKeys = pathCtx.paramKeys[:0]
-					pathCtx.paramValues = pathCtx.paramValues[:0]
-
-					// Pre-allocate slices to avoid append allocations
-					if cap(pathCtx.paramKeys) < len(pathCtx.params) {
-						pathCtx.paramKeys = make([]string, 0, len(pathCtx.params))
-						pathCtx.paramValues = make([]string, 0, len(pathCtx.params))
-					}
-
-					// Process all parameters without assuming specific parameter names
-					// This is more appropriate for a framework that should work with any parameter names
-					for k, v := range pathCtx.params {
-						pathCtx.paramKeys = append(pathCtx.paramKeys, k)
-						pathCtx.paramValues = append(pathCtx.paramValues, v)
-					}
-
-					// Fast path for common case of 1-2 parameters
-					if useFixedArrays && paramCount <= 2 && paramCount > 0 {
-						// Unrolled loop for 1-2 parameters (most common case)
-						// This avoids the loop overhead and bounds checking
-						routeParams.fixedKeys[0] = pathCtx.paramKeys[0]
-						routeParams.fixedValues[0] = pathCtx.paramValues[0]
-						routeParams.fixedHashes[0] = stringHash(pathCtx.paramKeys[0])
-						routeParams.count = 1
-
-						// If there's a second parameter, add it
-						if paramCount == 2 {
-							routeParams.fixedKeys[1] = pathCtx.paramKeys[1]
-							routeParams.fixedValues[1] = pathCtx.paramValues[1]
-							routeParams.fixedHashes[1] = stringHash(pathCtx.paramKeys[1])
-							routeParams.count = 2
-						}
-					} else {
-						// General case for any number of parameters
-						for i := 0; i < paramCount; i++ {
-							if useFixedArrays {
-								// Use fixed-size arrays for small number of parameters (zero allocation)
-								routeParams.fixedKeys[i] = pathCtx.paramKeys[i]
-								routeParams.fixedValues[i] = pathCtx.paramValues[i]
-								routeParams.fixedHashes[i] = stringHash(pathCtx.paramKeys[i])
-								routeParams.count++
-							} else {
-								// Fall back to dynamic slices for routes with many parameters
-								routeParams.keys = append(routeParams.keys, pathCtx.paramKeys[i])
-								routeParams.values = append(routeParams.values, pathCtx.paramValues[i])
-								routeParams.hashes = append(routeParams.hashes, stringHash(pathCtx.paramKeys[i]))
-							}
-						}
-					}
-
-					// We don't need to store the parameter context in UserData anymore
-					// It's already stored in ctx.paramCache.routeParams
-				}
-
-				// Set up middleware and call the handler
-				r.setupMiddleware(ctx, handlerSlice)
-				return
-			}
+	// Requests under the prefix of a mounted router are served by it
+	if len(r.mounts) > 0 {
+		if m := r.matchMount(req.URL.Path); m != nil {
+			r.setupMiddleware(ctx, m.handlers)
+			return
 		}
 	}
 
-	// Fallback to optimized byte scanning for routes with parameters
-	methodRoutes, hasMethodRoutes := r.routesByMethod[method]
-	if hasMethodRoutes {
-		// Create a reusable matches slice to avoid allocations
-		matchesSlice := make([]string, 0, 8)
-
-		// Use a more efficient loop with index for better performance
-		for i := 0; i < len(methodRoutes); i++ {
-			route := &methodRoutes[i]
+	// Remember the router so handlers can build URLs of named routes
+	ctx.router = r
 
-			// Try byte scanning first for better performance
-			if matchRouteByteScanning(route, path, &matchesSlice) {
-				// We found a match, handle it
-				r.handleMatchedRoute(ctx, req, *route, matchesSlice)
-				return
-			}
-
-			// Fallback to regex for complex cases or backward compatibility
-			if matchesSlice == nil || len(matchesSlice) == 0 {
-				matches := route.Regex.FindStringSubmatch(path)
-				if len(matches) > 0 {
-					// We found a match, handle it
-					r.handleMatchedRoute(ctx, req, *route, matches)
-					return
-				}
-			}
-		}
+	// Requests for a path of a versioned route are served by the router of the version
+	if len(r.versions) > 0 && r.serveVersion(ctx, req) {
+		return
 	}
 
-	// If we didn't find a match, check for method not allowed
-	// Get allowed methods from the pool
-	allowedMethods := allowedMethodsPool.Get()
-	allowedMethods = allowedMethods[:0]
-	methodNotAllowed := false
+	if r.serve(ctx, req, method, path) {
+		return
+	}
 
-	// Check for method not allowed using radix trees first
-	for treeMethod, tree := range r.routeTrees {
-		if treeMethod == method {
-			continue
+	// Retry ignoring the trailing slash or letter case, unless routing is strict or case sensitive
+	if !r.StrictRouting || !r.CaseSensitive {
+		if fixed, ok := r.lookupPath(method, path, !r.StrictRouting, !r.CaseSensitive); ok && r.serve(ctx, req, method, fixed) {
+			return
 		}
+	}
 
-		// Try to find a match in the radix tree for other methods
-		// Use byte slice path to avoid allocations
-		if _, found := tree.FindBytes(pathBytes, nil); found {
-			methodNotAllowed = true
-			allowedMethods = append(allowedMethods, treeMethod)
+	// Redirect to the canonical path of a matching route
+	if location, ok := r.redirectPath(method, path); ok {
+		// Paths of mounted routers are under the prefixes they are mounted at
+		location = ctx.baseURL + location
+		if req.URL.RawQuery != "" {
+			location += "?" + req.URL.RawQuery
 		}
+		ctx.Set(HeaderLocation, location)
+		r.setupMiddleware(ctx, []Handler{redirectHandler})
+		return
 	}
 
-	// If we didn't find method not allowed using radix trees, fall back to regex
-	if !methodNotAllowed {
-		// Create a reusable matches slice to avoid allocations
-		matchesSlice := make([]string, 0, 8)
-
-		// Use a more efficient approach to find allowed methods
-		// Pre-allocate a map to track methods we've already seen
-		methodSeen := make(map[string]bool, 8)
-
-		// Check all routes for path matches with different methods
-		for i := 0; i < len(r.Routes); i++ {
-			route := &r.Routes[i]
-			// Skip routes we already checked
-			if route.Method == method {
-				continue
-			}
-
-			// Skip methods we've already seen
-			if methodSeen[route.Method] {
-				continue
-			}
-
-			// Try byte scanning first for better performance
-			if matchRouteByteScanning(route, path, &matchesSlice) {
-				// Path matches but method doesn't match
-				methodNotAllowed = true
-				methodSeen[route.Method] = true
-				allowedMethods = append(allowedMethods, route.Method)
-			} else {
-				// Fallback to regex for complex cases or backward compatibility
-				matches := route.Regex.FindStringSubmatch(path)
-				if len(matches) > 0 {
-					// Path matches but method doesn't match
-					methodNotAllowed = true
-					methodSeen[route.Method] = true
-					allowedMethods = append(allowedMethods, route.Method)
-				}
-			}
+	// If we didn't find a match, check whether the path exists for other methods
+	if r.HandleOPTIONS || r.HandleMethodNotAllowed {
+		// Get allowed methods from the pool
+		allowedMethods := allowedMethodsPool.Get()[:0]
+		if len(r.versions) > 0 {
+			allowedMethods = r.versionAllowedMethods(ctx, req, path, method, allowedMethods)
 		}
-	}
+		allowedMethods = r.allowedMethods(path, method, allowedMethods)
 
-	// If we found a matching path but method was not allowed, return 405 Method Not Allowed
-	if methodNotAllowed {
-		// Filter out HEAD method if GET is already present to match test expectations
-		// This is because HEAD is automatically added for GET routes
-		if len(allowedMethods) > 1 {
-			hasGet := false
-			hasHead := false
-			for _, m := range allowedMethods {
-				if m == MethodGet {
-					hasGet = true
-				} else if m == MethodHead {
-					hasHead = true
-				}
+		if len(allowedMethods) > 0 {
+			var handler Handler
+			if method == MethodOptions && r.HandleOPTIONS {
+				// Automatic OPTIONS response listing the allowed methods
+				allowedMethods = append(allowedMethods, MethodOptions)
+				handler = optionsHandler
+			} else if r.HandleMethodNotAllowed {
+				handler = r.MethodNotAllowed
 			}
 
-			// If both GET and HEAD are present, and HEAD was automatically added for GET,
-			// filter out HEAD to match test expectations
-			if hasGet && hasHead {
-				filteredMethods := make([]string, 0, len(allowedMethods)-1)
-				for _, m := range allowedMethods {
-					if m != MethodHead {
-						filteredMethods = append(filteredMethods, m)
-					}
-				}
-				allowedMethods = filteredMethods
-			}
-		}
+			if handler != nil {
+				ctx.Set(HeaderAllow, strings.Join(allowedMethods, ", "))
 
-		// Set the Allow header
-		// Use a string builder to avoid allocations when joining allowed methods
-		var allowHeader string
-		if len(allowedMethods) == 1 {
-			// Fast path for single method
-			allowHeader = allowedMethods[0]
-		} else {
-			// Use a string builder for multiple methods
-			sb := stringBuilderPool.Get()
-			sb.Reset()
+				// Return allowed methods to the pool
+				allowedMethodsPool.Put(allowedMethods)
 
-			for i, m := range allowedMethods {
-				if i > 0 {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(m)
+				// Set up middleware and call the handler
+				r.setupMiddleware(ctx, []Handler{handler})
+				return
 			}
-
-			allowHeader = sb.String()
-			stringBuilderPool.Put(sb)
 		}
 
-		ctx.Set(HeaderAllow, allowHeader)
-
-		// Return allowed methods to the pool
+		// Return allowed methods to the pool if we didn't use them
 		allowedMethodsPool.Put(allowedMethods)
-
-		// Set up middleware and call the handler
-		r.setupMiddleware(ctx, []Handler{methodNotAllowedHandler})
-		return
 	}
 
-	// Return allowed methods to the pool if we didn't use them
-	allowedMethodsPool.Put(allowedMethods)
+	// No route matched, use the NotFound handler of the group or the router
+	notFound := r.NotFound
+	if len(r.groupScopes) > 0 {
+		notFound = r.notFoundHandler(path)
+	}
 
-	// No route matched, use the NotFound handler
 	// Fast path: directly call NotFound handler without middleware if possible
 	if len(r.middlewareFuncs) == 0 {
 		// No middleware, just call the handler directly
-		r.NotFound(ctx)
+		notFound(ctx)
 	} else {
 		// Use setupMiddleware with a pre-allocated slice to avoid allocation
-		r.setupMiddleware(ctx, []Handler{r.NotFound})
+		r.setupMiddleware(ctx, []Handler{notFound})
+	}
+}
+
+// serveMethod looks up the route registered for method that matches path and calls it.
+// It reports whether a route was found.
+func (r *Router) serveMethod(ctx *Ctx, req *Request, method, path string) bool {
+	// Routes added or removed while the request is served don't affect it
+	t := r.routeTable()
+
+	// Paths of static routes are found without walking the tree or capturing values
+	if leaf := t.staticLeaf(method, path); leaf != nil {
+		r.serveLeaf(ctx, leaf)
+		return true
+	}
+
+	root := t.trees[method]
+	if root == nil {
+		return false
 	}
+
+	var values routeValues
+	leaf := root.match(path, 0, &values)
+	if leaf == nil {
+		return false
+	}
+
+	ctx.setRouteParams(leaf, &values)
+	r.serveLeaf(ctx, leaf)
+	return true
 }
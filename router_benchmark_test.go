@@ -1,8 +1,13 @@
 package ngebut
 
 import (
+	"fmt"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+
+	"github.com/ryanbekhen/ngebut/internal/radix"
 )
 
 // BenchmarkRouterStatic benchmarks the router with static routes
@@ -217,3 +222,168 @@ func BenchmarkRouterNotFound(b *testing.B) {
 		ReleaseContext(ctx)
 	}
 }
+
+// legacyLookup is the route lookup used before the compiled route tree: a map of the
+// static routes, then a linear scan matching a regex built for each route.
+// It is kept as a baseline for the route tree benchmarks.
+type legacyLookup struct {
+	static map[string][]Handler
+	routes []legacyRoute
+}
+
+// legacyRoute is a route with the regex matching its paths and the names of its parameters.
+type legacyRoute struct {
+	handlers   []Handler
+	regex      *regexp.Regexp
+	paramNames []string
+}
+
+func newLegacyLookup(routes []route) *legacyLookup {
+	l := &legacyLookup{static: make(map[string][]Handler)}
+	for _, rt := range routes {
+		regex, paramNames := legacyRouteRegex(rt.Pattern)
+		if len(paramNames) == 0 {
+			l.static[rt.Pattern] = rt.Handlers
+			continue
+		}
+		l.routes = append(l.routes, legacyRoute{handlers: rt.Handlers, regex: regex, paramNames: paramNames})
+	}
+	return l
+}
+
+// legacyRouteRegex converts the parameters and wildcards of a pattern to a regex.
+// Parameter constraints are not checked.
+func legacyRouteRegex(pattern string) (*regexp.Regexp, []string) {
+	var sb strings.Builder
+	var paramNames []string
+
+	sb.WriteString("^")
+	for _, segment := range radix.ParsePattern(pattern) {
+		switch {
+		case segment.Kind == radix.Param && segment.Optional:
+			sb.WriteString("(?:/([^/]+))?")
+		case segment.Kind == radix.Param:
+			sb.WriteString("/([^/]+)")
+		case segment.Kind == radix.Wildcard && segment.OneOrMore:
+			sb.WriteString("/(.+)")
+		case segment.Kind == radix.Wildcard:
+			sb.WriteString("(?:/(.*))?")
+		default:
+			sb.WriteString("/")
+			sb.WriteString(regexp.QuoteMeta(segment.Path))
+		}
+		if segment.Kind != radix.Static {
+			paramNames = append(paramNames, segment.Name)
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String()), paramNames
+}
+
+func (l *legacyLookup) lookup(method, path string, rp *routeParams) []Handler {
+	if handlers, found := l.static[path]; found {
+		return handlers
+	}
+
+	for i := range l.routes {
+		rt := &l.routes[i]
+		if matches := rt.regex.FindStringSubmatch(path); matches != nil {
+			rp.Reset()
+			for j, name := range rt.paramNames {
+				rp.Set(name, matches[j+1])
+			}
+			return rt.handlers
+		}
+	}
+	return nil
+}
+
+// largeRouteSet registers 1000 routes: for each of 200 resources a static route,
+// one and two parameter routes, a constrained parameter route and a wildcard route.
+func largeRouteSet() *Router {
+	router := NewRouter()
+	handler := func(c *Ctx) {}
+	for i := 0; i < 200; i++ {
+		prefix := fmt.Sprintf("/api/v1/resource%d", i)
+		router.GET(prefix, handler)
+		router.GET(prefix+"/:id", handler)
+		router.GET(prefix+"/:id/items/:item", handler)
+		router.GET(prefix+"/by-number/:n<int>", handler)
+		router.GET(prefix+"/files/*path", handler)
+	}
+	return router
+}
+
+// largeRouteSetPaths are the paths looked up in the large route set benchmarks
+var largeRouteSetPaths = []struct {
+	name string
+	path string
+}{
+	{"Static", "/api/v1/resource150"},
+	{"Param", "/api/v1/resource150/42"},
+	{"TwoParams", "/api/v1/resource150/42/items/7"},
+	{"Constraint", "/api/v1/resource150/by-number/99"},
+	{"Wildcard", "/api/v1/resource150/files/css/site.css"},
+	{"NotFound", "/api/v2/resource150"},
+}
+
+// BenchmarkRouteLookupLarge compares the compiled route tree with the legacy lookup
+// on a set of 1000 routes, without the middleware and handler calls.
+func BenchmarkRouteLookupLarge(b *testing.B) {
+	router := largeRouteSet()
+	table := router.routeTable()
+	legacy := newLegacyLookup(router.Routes)
+
+	for _, p := range largeRouteSetPaths {
+		b.Run("Tree/"+p.name, func(b *testing.B) {
+			rp := getRouteParams()
+			defer releaseRouteParams(rp)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if table.staticLeaf(MethodGet, p.path) != nil {
+					continue
+				}
+				var values routeValues
+				if leaf := table.trees[MethodGet].match(p.path, 0, &values); leaf != nil {
+					rp.Reset()
+					for j, name := range leaf.names {
+						rp.Set(name, values.values[j])
+					}
+				}
+			}
+		})
+
+		b.Run("Legacy/"+p.name, func(b *testing.B) {
+			rp := getRouteParams()
+			defer releaseRouteParams(rp)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				legacy.lookup(MethodGet, p.path, rp)
+			}
+		})
+	}
+}
+
+// BenchmarkRouterLarge benchmarks the router serving requests with a set of 1000 routes
+func BenchmarkRouterLarge(b *testing.B) {
+	router := largeRouteSet()
+	w := httptest.NewRecorder()
+
+	for _, p := range largeRouteSetPaths {
+		b.Run(p.name, func(b *testing.B) {
+			req := httptest.NewRequest(MethodGet, "http://example.com"+p.path, nil)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ctx := GetContext(w, req)
+				router.ServeHTTP(ctx, ctx.Request)
+				ReleaseContext(ctx)
+			}
+		})
+	}
+}
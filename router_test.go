@@ -3,6 +3,7 @@ package ngebut
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -70,7 +71,6 @@ func TestRouterHandle(t *testing.T) {
 	assert.Equal("/users", route.Pattern, "route pattern should match")
 	assert.Equal(MethodGet, route.Method, "route method should match")
 	assert.Len(route.Handlers, 1, "should have 1 handler")
-	assert.NotNil(route.Regex, "route.Regex should not be nil")
 
 	// Test with pattern containing parameters
 	router.Handle("/users/:id", MethodPost, handler)
@@ -79,7 +79,6 @@ func TestRouterHandle(t *testing.T) {
 	route = router.Routes[1]
 	assert.Equal("/users/:id", route.Pattern, "route pattern should match")
 	assert.Equal(MethodPost, route.Method, "route method should match")
-	assert.NotNil(route.Regex, "route.Regex should not be nil")
 
 	// Test with multiple handlers
 	handler2 := func(c *Ctx) {
@@ -246,7 +245,7 @@ func TestRouterServeHTTPMethodNotAllowed(t *testing.T) {
 	// Check the response
 	assert.Equal(StatusMethodNotAllowed, w.Code, "status code should be StatusMethodNotAllowed")
 	assert.Equal("Method Not Allowed", w.Body.String(), "response body should match")
-	assert.Equal(MethodGet, w.Header().Get(HeaderAllow), "Allow header should be GET")
+	assert.Equal("GET, HEAD", w.Header().Get(HeaderAllow), "Allow header should be GET and the implied HEAD")
 }
 
 // TestRouterServeHTTPWithMiddleware tests the ServeHTTP method of Router with middleware
@@ -288,46 +287,6 @@ func TestRouterServeHTTPWithMiddleware(t *testing.T) {
 	assert.Equal("OK", w.Body.String(), "response body should match")
 }
 
-// TestMiddlewareStackPool tests the middlewareStackPool
-func TestMiddlewareStackPool(t *testing.T) {
-	assert := assert.New(t)
-
-	// Get a stack from the pool
-	stack := middlewareStackPool.Get()
-	assert.NotNil(stack, "middlewareStackPool.Get() returned nil")
-
-	// Reset the stack to ensure it's empty
-	stack = stack[:0]
-
-	// Check that the stack is empty
-	assert.Empty(stack, "stack should be empty")
-
-	// Add a middleware function to the stack
-	middleware := func(c *Ctx) {
-		c.Next()
-	}
-	stack = append(stack, middleware)
-
-	// Check that the middleware was added
-	assert.Len(stack, 1, "stack should have 1 middleware function")
-
-	// Put the stack back in the pool
-	middlewareStackPool.Put(stack)
-
-	// Get another stack from the pool (might be the same one)
-	stack2 := middlewareStackPool.Get()
-	assert.NotNil(stack2, "middlewareStackPool.Get() returned nil on second call")
-
-	// Reset the stack to ensure it's empty
-	stack2 = stack2[:0]
-
-	// Check that the stack is empty after resetting
-	assert.Empty(stack2, "stack2 should be empty after resetting")
-
-	// Put the stack back in the pool
-	middlewareStackPool.Put(stack2)
-}
-
 // TestRouterSTATIC tests the STATIC method of Router
 func TestRouterSTATIC(t *testing.T) {
 	assert := assert.New(t)
@@ -788,3 +747,359 @@ func TestStaticPrefixHandling(t *testing.T) {
 	assert.Equal("/assets/*", router.Routes[0].Pattern, "first route should have wildcard pattern")
 	assert.Equal("/files/*", router.Routes[1].Pattern, "second route should have wildcard pattern")
 }
+
+// TestRouterNamedRoutes tests naming routes and building their URLs
+func TestRouterNamedRoutes(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) {}
+
+	router.GET("/users", handler).Name("users.list")
+	router.GET("/users/:id/posts/:post", handler).Name("users.post")
+	router.STATIC("/assets", "./examples").Name("assets")
+
+	assert.Equal("users.list", router.Routes[0].Name, "route name should be stored")
+
+	u, err := router.URL("users.list", nil, nil)
+	assert.NoError(err)
+	assert.Equal("/users", u)
+
+	u, err = router.URL("users.post", map[string]string{"id": "a b", "post": "7"}, url.Values{"page": {"2"}})
+	assert.NoError(err)
+	assert.Equal("/users/a%20b/posts/7?page=2", u, "params should be escaped and query appended")
+
+	u, err = router.URL("assets", map[string]string{"*": "css/site main.css"}, nil)
+	assert.NoError(err)
+	assert.Equal("/assets/css/site%20main.css", u, "wildcards should keep slashes")
+
+	_, err = router.URL("users.post", map[string]string{"id": "1"}, nil)
+	assert.Error(err, "missing params should return an error")
+
+	_, err = router.URL("missing", nil, nil)
+	assert.ErrorIs(err, ErrRouteNotFound)
+
+	assert.Panics(func() {
+		router.POST("/users", handler).Name("users.list")
+	}, "duplicate names should panic")
+	assert.Panics(func() {
+		NewRouter().Name("orphan")
+	}, "naming without a route should panic")
+}
+
+// TestRedirectToRoute tests redirecting to a named route
+func TestRedirectToRoute(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	router.GET("/users/:id", func(c *Ctx) {}).Name("user")
+	router.GET("/old/:id", func(c *Ctx) {
+		assert.NoError(c.RedirectToRoute("user", map[string]string{"id": c.Param("id")}, url.Values{"from": {"old"}}))
+	})
+	router.GET("/moved", func(c *Ctx) {
+		assert.NoError(c.RedirectToRoute("user", map[string]string{"id": "1"}, nil, StatusMovedPermanently))
+	})
+	router.GET("/broken", func(c *Ctx) {
+		assert.ErrorIs(c.RedirectToRoute("missing", nil, nil), ErrRouteNotFound)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	w := serve("/old/42")
+	assert.Equal(StatusFound, w.Code)
+	assert.Equal("/users/42?from=old", w.Header().Get(HeaderLocation))
+
+	w = serve("/moved")
+	assert.Equal(StatusMovedPermanently, w.Code)
+	assert.Equal("/users/1", w.Header().Get(HeaderLocation))
+
+	serve("/broken")
+}
+
+// TestRouterParamConstraints tests that constrained parameters fall through to other routes
+func TestRouterParamConstraints(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	router.GET("/users/:id<int;min(1)>", func(c *Ctx) {
+		c.String("id %s", c.Param("id"))
+	}).Name("user")
+	router.GET("/users/:name<regex([a-z-]+)>", func(c *Ctx) {
+		c.String("name %s", c.Param("name"))
+	})
+	router.GET("/events/:date<datetime(2006-01-02)>/:slug<maxLen(5)>", func(c *Ctx) {
+		c.String("event %s %s", c.Param("date"), c.Param("slug"))
+	})
+	router.GET("/orders/:uuid<uuid>", func(c *Ctx) {
+		c.String("order %s", c.Param("uuid"))
+	})
+	router.GET("/orders/:ref", func(c *Ctx) {
+		c.String("ref %s", c.Param("ref"))
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	assert.Equal("id 42", serve(MethodGet, "/users/42").Body.String())
+	assert.Equal("name jane-doe", serve(MethodGet, "/users/jane-doe").Body.String())
+	assert.Equal(StatusNotFound, serve(MethodGet, "/users/0").Code, "values failing every constraint should 404")
+	assert.Equal(StatusNotFound, serve(MethodGet, "/users/Jane").Code)
+
+	assert.Equal("event 2024-05-01 party", serve(MethodGet, "/events/2024-05-01/party").Body.String())
+	assert.Equal(StatusNotFound, serve(MethodGet, "/events/yesterday/party").Code)
+	assert.Equal(StatusNotFound, serve(MethodGet, "/events/2024-05-01/birthday").Code)
+
+	assert.Equal("order 123e4567-e89b-12d3-a456-426614174000", serve(MethodGet, "/orders/123e4567-e89b-12d3-a456-426614174000").Body.String())
+	assert.Equal("ref abc", serve(MethodGet, "/orders/abc").Body.String(), "unconstrained routes should catch the rest")
+
+	assert.Equal(StatusMethodNotAllowed, serve(MethodPost, "/users/42").Code)
+	assert.Equal(StatusNotFound, serve(MethodPost, "/users/0").Code, "constraints should apply to 405 detection")
+
+	u, err := router.URL("user", map[string]string{"id": "7"}, nil)
+	assert.NoError(err)
+	assert.Equal("/users/7", u, "constraints should not appear in generated URLs")
+}
+
+// TestRouteTreeConstraints tests constraint evaluation in the route tree
+func TestRouteTreeConstraints(t *testing.T) {
+	router := NewRouter()
+	router.GET("/items/:id<int>/:name<alpha>", func(c *Ctx) {})
+
+	root := router.routeTable().trees[MethodGet]
+	var values routeValues
+	leaf := root.match("/items/1/abc", 0, &values)
+	if assert.NotNil(t, leaf) {
+		assert.Equal(t, []string{"id", "name"}, leaf.names)
+		assert.Equal(t, []string{"1", "abc"}, values.values[:values.count])
+	}
+	assert.Nil(t, root.match("/items/x/abc", 0, &routeValues{}))
+	assert.Nil(t, root.match("/items/1/ab1", 0, &routeValues{}))
+}
+
+// TestRouterOptionalParamsAndWildcards tests optional parameters, named and + wildcards
+func TestRouterOptionalParamsAndWildcards(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	router.GET("/files/:name?", func(c *Ctx) {
+		c.String("file [%s]", c.Param("name"))
+	})
+	router.GET("/static/*path", func(c *Ctx) {
+		c.String("static [%s]", c.Param("path"))
+	})
+	router.GET("/plus/+", func(c *Ctx) {
+		c.String("plus [%s] [%s]", c.Param("+"), c.Param("+1"))
+	})
+	router.GET("/multi/*/to/*", func(c *Ctx) {
+		c.String("multi [%s] [%s] [%s]", c.Param("*"), c.Param("*1"), c.Param("*2"))
+	})
+	router.GET("/shop/:category?/items/:id<int>", func(c *Ctx) {
+		c.String("shop [%s] [%s]", c.Param("category"), c.Param("id"))
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Optional parameters, with the omitted variant as a static path of the tree
+	assert.Equal("file [report.pdf]", serve("/files/report.pdf").Body.String())
+	assert.Equal("file []", serve("/files").Body.String())
+	assert.NotNil(router.routeTable().trees[MethodGet].static["files"].leaf, "static variant should end at a static node")
+
+	// Named wildcards
+	assert.Equal("static [css/site.css]", serve("/static/css/site.css").Body.String())
+	assert.Equal("static []", serve("/static").Body.String())
+
+	// + wildcards need at least one segment
+	assert.Equal("plus [a/b] [a/b]", serve("/plus/a/b").Body.String())
+	assert.Equal(StatusNotFound, serve("/plus").Code)
+	assert.Equal(StatusNotFound, serve("/plus/").Code)
+
+	// Multiple wildcards backtrack until the rest of the path matches
+	assert.Equal("multi [a/b] [a/b] [c/d]", serve("/multi/a/b/to/c/d").Body.String())
+
+	// Optional parameters in the middle of the pattern
+	assert.Equal("shop [books] [7]", serve("/shop/books/items/7").Body.String())
+	assert.Equal("shop [] [7]", serve("/shop/items/7").Body.String())
+	assert.Equal(StatusNotFound, serve("/shop/books/items/x").Code)
+
+}
+
+// TestRouterURLOptionalAndWildcards tests URL generation for optional parameters and wildcards
+func TestRouterURLOptionalAndWildcards(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) {}
+
+	router.GET("/files/:name?", handler).Name("files")
+	router.GET("/static/*path", handler).Name("static")
+	router.GET("/multi/*/to/+", handler).Name("multi")
+
+	u, err := router.URL("files", nil, nil)
+	assert.NoError(err)
+	assert.Equal("/files", u)
+
+	u, err = router.URL("static", map[string]string{"path": "a/b c"}, nil)
+	assert.NoError(err)
+	assert.Equal("/static/a/b%20c", u)
+
+	u, err = router.URL("multi", map[string]string{"*1": "x", "+": "y/z"}, nil)
+	assert.NoError(err)
+	assert.Equal("/multi/x/to/y/z", u)
+
+	_, err = router.URL("multi", map[string]string{"*": "x"}, nil)
+	assert.Error(err, "+ wildcards are required")
+}
+
+// TestRouterMethodNotAllowedAndOptions tests 405 responses and automatic OPTIONS responses
+func TestRouterMethodNotAllowedAndOptions(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	handler := func(c *Ctx) { c.String("ok") }
+
+	router.GET("/users/:id", handler)
+	router.PUT("/users/:id", handler)
+	router.DELETE("/users/:id<int>", handler)
+	router.POST("/files/*path", handler)
+	router.OPTIONS("/custom", func(c *Ctx) { c.String("custom options") })
+	router.GET("/custom", handler)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	w := serve(MethodPost, "/users/1")
+	assert.Equal(StatusMethodNotAllowed, w.Code)
+	assert.Equal("DELETE, GET, HEAD, PUT", w.Header().Get(HeaderAllow), "Allow should list the matching methods in order")
+
+	w = serve(MethodPost, "/users/abc")
+	assert.Equal("GET, HEAD, PUT", w.Header().Get(HeaderAllow), "constraints should be respected")
+
+	w = serve(MethodGet, "/files/a/b")
+	assert.Equal(StatusMethodNotAllowed, w.Code)
+	assert.Equal(MethodPost, w.Header().Get(HeaderAllow))
+
+	// Automatic OPTIONS responses
+	w = serve(MethodOptions, "/users/1")
+	assert.Equal(StatusNoContent, w.Code)
+	assert.Equal("DELETE, GET, HEAD, PUT, OPTIONS", w.Header().Get(HeaderAllow))
+	assert.Empty(w.Body.String())
+
+	w = serve(MethodOptions, "/custom")
+	assert.Equal("custom options", w.Body.String(), "explicit OPTIONS routes should take precedence")
+
+	w = serve(MethodOptions, "*")
+	assert.Equal(StatusNoContent, w.Code)
+	assert.Equal("DELETE, GET, HEAD, POST, PUT, OPTIONS", w.Header().Get(HeaderAllow))
+
+	assert.Equal(StatusNotFound, serve(MethodOptions, "/missing").Code)
+
+	// Custom handler
+	router.MethodNotAllowed = func(c *Ctx) {
+		c.Status(StatusMethodNotAllowed).String("nope: %s", c.Get(HeaderAllow))
+	}
+	w = serve(MethodPatch, "/users/1")
+	assert.Equal(StatusMethodNotAllowed, w.Code)
+	assert.Equal("nope: DELETE, GET, HEAD, PUT", w.Body.String())
+
+	// Disabled behaviour falls back to NotFound
+	router.HandleMethodNotAllowed = false
+	router.HandleOPTIONS = false
+	assert.Equal(StatusNotFound, serve(MethodPatch, "/users/1").Code)
+	assert.Equal(StatusNotFound, serve(MethodOptions, "/users/1").Code)
+}
+
+// TestRouterImplicitHEAD tests that HEAD requests fall back to GET routes
+func TestRouterImplicitHEAD(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var sawHead bool
+	router.GET("/users/:id", func(c *Ctx) {
+		sawHead = c.IsHead()
+		c.String("user %s", c.Param("id"))
+	})
+	router.GET("/static", func(c *Ctx) { c.String("get") })
+	router.HEAD("/static", func(c *Ctx) { c.Set("X-Head", "explicit") })
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	w := serve(MethodHead, "/users/42")
+	assert.Equal(StatusOK, w.Code, "HEAD should be served by the GET route")
+	assert.True(sawHead, "the handler should see the HEAD method")
+
+	serve(MethodGet, "/users/42")
+	assert.False(sawHead, "GET requests should not be reported as HEAD")
+
+	w = serve(MethodHead, "/static")
+	assert.Equal("explicit", w.Header().Get("X-Head"), "an explicit HEAD route should take precedence")
+
+	w = serve(MethodHead, "/missing")
+	assert.Equal(StatusNotFound, w.Code)
+
+	// HEAD is allowed with GET, and listed once next to an explicit HEAD route
+	assert.Equal("GET, HEAD", serve(MethodPost, "/users/42").Header().Get(HeaderAllow))
+	assert.Equal("GET, HEAD", serve(MethodPost, "/static").Header().Get(HeaderAllow))
+
+	// Without the fallback, HEAD is not allowed on GET-only paths
+	router.HandleHEAD = false
+	w = serve(MethodHead, "/users/42")
+	assert.Equal(StatusMethodNotAllowed, w.Code)
+	assert.Equal(MethodGet, w.Header().Get(HeaderAllow))
+}
+
+// TestRouterMiddlewareCacheClosures tests that compiled middleware chains are not
+// shared between closures created by the same function literal
+func TestRouterMiddlewareCacheClosures(t *testing.T) {
+	router := NewRouter()
+	router.Use(func(c *Ctx) { c.Next() })
+
+	for _, name := range []string{"a", "b"} {
+		router.GET("/"+name, func(c *Ctx) { c.String("%s", name) })
+	}
+
+	for _, name := range []string{"a", "b"} {
+		req, _ := http.NewRequest(MethodGet, "http://example.com/"+name, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		ReleaseContext(ctx)
+		assert.Equal(t, name, w.Body.String(), "each route should run its own handler")
+	}
+}
@@ -0,0 +1,178 @@
+package ngebut
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/goccy/go-json"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	// Method is the HTTP method of the route.
+	Method string `json:"method"`
+
+	// Host is the host pattern of the router the route is registered on, see Router.Host.
+	// It is empty for the routes of the default router.
+	Host string `json:"host,omitempty"`
+
+	// Version is the API version the route is registered for, see Router.Version.
+	// It is empty for unversioned routes.
+	Version string `json:"version,omitempty"`
+
+	// Pattern is the path pattern of the route, including any group prefix.
+	Pattern string `json:"pattern"`
+
+	// Name is the name assigned with Name, empty if the route is unnamed.
+	Name string `json:"name,omitempty"`
+
+	// Handlers is the number of handlers registered for the route.
+	Handlers int `json:"handlers"`
+
+	// Middleware lists the functions that run before the final handler,
+	// starting with the router middleware followed by the route's own handlers.
+	Middleware []string `json:"middleware"`
+}
+
+// routeInfos returns the description of all routes registered on the router and
+// its host routers, sorted by host, pattern and method.
+func (r *Router) routeInfos() []RouteInfo {
+	infos := r.appendRouteInfos(nil, "", nil)
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Host != infos[j].Host {
+			return infos[i].Host < infos[j].Host
+		}
+		if infos[i].Pattern != infos[j].Pattern {
+			return infos[i].Pattern < infos[j].Pattern
+		}
+		if infos[i].Version != infos[j].Version {
+			return infos[i].Version < infos[j].Version
+		}
+		return infos[i].Method < infos[j].Method
+	})
+
+	return infos
+}
+
+// appendRouteInfos appends the description of the routes of the router for host.
+// parent returns the names of the parent middleware that runs for a path pattern of
+// the router, and is nil for the root router.
+func (r *Router) appendRouteInfos(infos []RouteInfo, host string, parent func(pattern string) []string) []RouteInfo {
+	// Path-scoped middleware is only listed for the routes under its prefix, of this
+	// router and of the parent routers
+	own := func(pattern string) []string {
+		var names []string
+		if parent != nil {
+			names = parent(pattern)
+		}
+		for _, m := range r.middlewareFor(pattern) {
+			names = append(names, funcName(m))
+		}
+		return names
+	}
+
+	// The routes are read from the route table, as they can be registered or removed
+	// while the server is running
+	for _, rt := range r.routeTable().routes {
+		middleware := own(rt.Pattern)
+		for i := 0; i < len(rt.Handlers)-1; i++ {
+			middleware = append(middleware, funcName(rt.Handlers[i]))
+		}
+		if middleware == nil {
+			middleware = []string{}
+		}
+
+		infos = append(infos, RouteInfo{
+			Method:     rt.Method,
+			Host:       host,
+			Pattern:    rt.Pattern,
+			Name:       rt.Name,
+			Handlers:   len(rt.Handlers),
+			Middleware: middleware,
+		})
+	}
+
+	for _, h := range r.hosts {
+		infos = h.router.appendRouteInfos(infos, h.pattern, own)
+	}
+
+	// Routes of mounted routers are listed with the mount prefix
+	for _, m := range r.mounts {
+		start := len(infos)
+		prefix := m.prefix
+		infos = m.router.appendRouteInfos(infos, host, func(pattern string) []string {
+			return own(prefix + pattern)
+		})
+		for i := start; i < len(infos); i++ {
+			if infos[i].Host == host {
+				infos[i].Pattern = m.prefix + infos[i].Pattern
+			}
+		}
+	}
+
+	// Routes of API versions are listed with their version
+	for _, v := range r.versions {
+		start := len(infos)
+		infos = v.router.appendRouteInfos(infos, host, own)
+		for i := start; i < len(infos); i++ {
+			infos[i].Version = v.version
+		}
+	}
+
+	return infos
+}
+
+// Routes returns the description of all registered routes, including static file
+// and group routes, sorted by pattern and method.
+func (s *Server) Routes() []RouteInfo {
+	return s.router.routeInfos()
+}
+
+// RoutesJSON returns the route table as indented JSON.
+// The output is stable for a given set of routes, which makes it suitable for diffing in CI.
+func (s *Server) RoutesJSON() ([]byte, error) {
+	return json.MarshalIndent(s.Routes(), "", "  ")
+}
+
+// funcName returns the name of a handler or middleware function.
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	if f := runtime.FuncForPC(v.Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// formatRouteTable formats the routes as an aligned table, one line per route.
+func formatRouteTable(routes []RouteInfo) []string {
+	methodWidth, patternWidth, nameWidth := len("METHOD"), len("PATTERN"), len("NAME")
+	for _, rt := range routes {
+		methodWidth = max(methodWidth, len(rt.Method))
+		patternWidth = max(patternWidth, len(routeTablePattern(rt)))
+		nameWidth = max(nameWidth, len(rt.Name))
+	}
+
+	format := fmt.Sprintf("%%-%ds  %%-%ds  %%-%ds  %%s", methodWidth, patternWidth, nameWidth)
+	lines := make([]string, 0, len(routes)+1)
+	lines = append(lines, fmt.Sprintf(format, "METHOD", "PATTERN", "NAME", "HANDLERS"))
+	for _, rt := range routes {
+		lines = append(lines, fmt.Sprintf(format, rt.Method, routeTablePattern(rt), rt.Name, strconv.Itoa(rt.Handlers)))
+	}
+	return lines
+}
+
+// routeTablePattern returns the pattern column of a route in the route table,
+// with its host and API version.
+func routeTablePattern(rt RouteInfo) string {
+	if rt.Version != "" {
+		return rt.Host + rt.Pattern + " (v" + rt.Version + ")"
+	}
+	return rt.Host + rt.Pattern
+}
@@ -0,0 +1,139 @@
+package ngebut
+
+import "github.com/ryanbekhen/ngebut/internal/radix"
+
+// routeTable holds the structures used to look up the registered routes.
+// A table is never modified once it is in use: adding or removing routes builds
+// a new table, which replaces the old one atomically, so requests read it without locking.
+type routeTable struct {
+	routes      []route                 // All routes, in registration order
+	trees       map[string]*routeNode   // Compiled route trees indexed by method
+	static      map[string][]methodLeaf // Leaves of the routes without parameters, by path
+	namedRoutes map[string]string       // Route patterns indexed by route name
+}
+
+// newRouteTable builds the lookup structures of the routes. When middleware is not nil,
+// it chooses the router middleware of the routes from their pattern segments.
+func newRouteTable(routes []route, middleware func(segments []radix.Segment) ([]MiddlewareFunc, bool)) *routeTable {
+	t := &routeTable{
+		routes:      routes,
+		trees:       make(map[string]*routeNode),
+		static:      make(map[string][]methodLeaf),
+		namedRoutes: make(map[string]string),
+	}
+	for i := range routes {
+		t.add(&routes[i], middleware)
+	}
+	return t
+}
+
+// add adds a route to the lookup structures.
+func (t *routeTable) add(rt *route, middleware func(segments []radix.Segment) ([]MiddlewareFunc, bool)) {
+	if rt.Name != "" {
+		t.namedRoutes[rt.Name] = rt.Pattern
+	}
+
+	// Static, parameter and wildcard routes share one tree per method, and the paths of
+	// routes without parameters are also looked up in a map
+	root, exists := t.trees[rt.Method]
+	if !exists {
+		root = &routeNode{}
+		t.trees[rt.Method] = root
+	}
+	root.addRoute(rt, func(variant string, segments []radix.Segment, leaf *routeLeaf) {
+		if middleware != nil {
+			leaf.middleware, leaf.resolved = middleware(segments)
+		}
+		if len(leaf.names) == 0 {
+			t.addStatic(rt.Method, variant, leaf)
+		}
+	})
+}
+
+// addStatic adds the leaf of a route without parameters to the static paths.
+func (t *routeTable) addStatic(method, path string, leaf *routeLeaf) {
+	leaves := t.static[path]
+	for i := range leaves {
+		if leaves[i].method == method {
+			leaves[i].leaf = leaf
+			return
+		}
+	}
+	t.static[path] = append(leaves, methodLeaf{method: method, leaf: leaf})
+}
+
+// staticLeaf returns the leaf of the route without parameters registered for method
+// whose path is path, or nil if there is none. It is checked before walking the tree.
+func (t *routeTable) staticLeaf(method, path string) *routeLeaf {
+	for _, ml := range t.static[path] {
+		if ml.method == method {
+			return ml.leaf
+		}
+	}
+	return nil
+}
+
+// methodLeaf is the leaf of a route registered for a method.
+type methodLeaf struct {
+	method string
+	leaf   *routeLeaf
+}
+
+// routeTable returns the table of the registered routes, building it if the routes
+// changed since it was last built.
+func (r *Router) routeTable() *routeTable {
+	if t := r.table.Load(); t != nil {
+		return t
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Another request may have built the table while we were waiting
+	if t := r.table.Load(); t != nil {
+		return t
+	}
+	// Path-scoped middleware is chosen for the routes once, instead of for each request
+	var middleware func(segments []radix.Segment) ([]MiddlewareFunc, bool)
+	if r.scopedMiddleware {
+		middleware = r.patternMiddleware
+	}
+	t := newRouteTable(r.Routes, middleware)
+	r.table.Store(t)
+	return t
+}
+
+// Remove unregisters the route with the given method and pattern, and reports whether
+// it was registered. Like Handle, it is safe to call while the server is running:
+// requests being served keep using the routes they started with.
+func (r *Router) Remove(method, pattern string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.Routes {
+		rt := &r.Routes[i]
+		if rt.Method != method || rt.Pattern != pattern {
+			continue
+		}
+
+		if rt.Name != "" {
+			delete(r.namedRoutes, rt.Name)
+		}
+
+		// Copy the routes, the old slice may still be in use by requests
+		routes := make([]route, 0, len(r.Routes)-1)
+		routes = append(routes, r.Routes[:i]...)
+		routes = append(routes, r.Routes[i+1:]...)
+		r.Routes = routes
+
+		// Route indexes changed, record the route shapes again
+		r.routeShapes = make(map[string]map[string]int)
+		for j := range r.Routes {
+			r.addRouteShapes(j)
+		}
+
+		r.table.Store(nil)
+		return true
+	}
+	return false
+}
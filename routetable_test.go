@@ -0,0 +1,138 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouterRemove tests removing routes
+func TestRouterRemove(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.GET("/users/:id", func(c *Ctx) { c.String("user") }).Name("user")
+	router.GET("/flag", func(c *Ctx) { c.String("flag") })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	assert.Equal("flag", serve("/flag").Body.String())
+	assert.True(router.Remove(MethodGet, "/flag"))
+	assert.Equal(StatusNotFound, serve("/flag").Code, "removed routes should not be served")
+	assert.False(router.Remove(MethodGet, "/flag"), "removing a missing route should report it")
+
+	assert.True(router.Remove(MethodGet, "/users/:id"))
+	assert.Equal(StatusNotFound, serve("/users/1").Code)
+	_, err := router.URL("user", map[string]string{"id": "1"}, nil)
+	assert.ErrorIs(err, ErrRouteNotFound, "the name of a removed route should be released")
+
+	// The pattern can be registered again
+	router.GET("/users/:name", func(c *Ctx) { c.String("again") }).Name("user")
+	assert.Equal("again", serve("/users/1").Body.String())
+}
+
+// TestRouterConcurrentChanges tests adding and removing routes while serving requests
+func TestRouterConcurrentChanges(t *testing.T) {
+	router := NewRouter()
+	router.GET("/stable", func(c *Ctx) { c.String("stable") })
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				req, _ := http.NewRequest(MethodGet, "http://example.com/stable", nil)
+				w := httptest.NewRecorder()
+				ctx := GetContext(w, req)
+				router.ServeHTTP(ctx, ctx.Request)
+				ctx.Writer.Flush()
+				ReleaseContext(ctx)
+				if w.Body.String() != "stable" {
+					t.Errorf("unexpected response %q", w.Body.String())
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		router.GET("/feature", func(c *Ctx) { c.String("feature") })
+		router.Remove(MethodGet, "/feature")
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestRouterConcurrentRouteInfos tests listing the routes while routes are added and removed
+func TestRouterConcurrentRouteInfos(t *testing.T) {
+	router := NewRouter()
+	router.GET("/stable", func(c *Ctx) {})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			infos := router.routeInfos()
+			if len(infos) == 0 || len(infos) > 2 {
+				t.Errorf("unexpected number of routes %d", len(infos))
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		router.GET("/feature", func(c *Ctx) {})
+		router.Remove(MethodGet, "/feature")
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestRouteTableStaticLeaf tests the lookup of routes without parameters by path
+func TestRouteTableStaticLeaf(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.GET("/users", func(c *Ctx) {})
+	router.POST("/users", func(c *Ctx) {})
+	router.GET("/docs/", func(c *Ctx) {})
+	router.GET("/files/:name?", func(c *Ctx) {})
+	router.GET("/users/:id", func(c *Ctx) {})
+
+	table := router.routeTable()
+	assert.NotNil(table.staticLeaf(MethodGet, "/users"))
+	assert.NotNil(table.staticLeaf(MethodPost, "/users"))
+	assert.NotSame(table.staticLeaf(MethodGet, "/users"), table.staticLeaf(MethodPost, "/users"), "methods should have their own leaves")
+	assert.Nil(table.staticLeaf(MethodPut, "/users"))
+	assert.Nil(table.staticLeaf(MethodGet, "/users/"), "the trailing slash should be part of the path")
+	assert.NotNil(table.staticLeaf(MethodGet, "/docs/"))
+	assert.NotNil(table.staticLeaf(MethodGet, "/files"), "variants without the optional parameter should be static")
+	assert.Nil(table.staticLeaf(MethodGet, "/users/:id"), "routes with parameters should not be static")
+
+	// The leaf is the one the tree matches
+	var values routeValues
+	assert.Same(table.trees[MethodGet].match("/users", 0, &values), table.staticLeaf(MethodGet, "/users"))
+}
@@ -0,0 +1,191 @@
+package ngebut
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SendFile sends a file chosen by the handler, with the file serving of STATIC:
+// the content type is derived from the file extension, conditional GET requests with
+// If-Modified-Since get 304 Not Modified, and byte ranges and in-memory caching are
+// used when enabled in the configuration.
+//
+// Relative paths are relative to the working directory, or to the root of config.FS.
+// Nothing is written when the file can't be sent: a missing file or a directory is
+// reported with an *HttpError with status 404 Not Found, which can be passed to Error.
+//
+// Example:
+//
+//	router.GET("/reports/:id", func(c *ngebut.Ctx) {
+//		if err := c.SendFile("reports/"+c.Param("id")+".pdf", ngebut.SendFile{ByteRange: true}); err != nil {
+//			c.Error(err)
+//		}
+//	})
+func (c *Ctx) SendFile(file string, config ...SendFile) error {
+	var cfg SendFile
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return c.sendFile(file, Static{
+		FS:            cfg.FS,
+		ByteRange:     cfg.ByteRange,
+		Download:      cfg.Download,
+		MaxAge:        cfg.MaxAge,
+		InMemoryCache: cfg.InMemoryCache,
+		MaxCacheSize:  cfg.MaxCacheSize,
+		MaxCacheItems: cfg.MaxCacheItems,
+	})
+}
+
+// Download sends a file as an attachment, which browsers save instead of displaying it.
+// The attachment is named filename, or after the file when filename is empty.
+// The file is sent as with SendFile, with byte ranges enabled so that downloads can resume.
+func (c *Ctx) Download(file, filename string) error {
+	if filename == "" {
+		filename = file
+	}
+
+	return c.sendFile(file, Static{
+		ByteRange: true,
+		ModifyResponse: func(c *Ctx) {
+			c.Set(HeaderContentDisposition, contentDisposition("attachment", filepath.Base(filename)))
+		},
+	})
+}
+
+// Attachment sets the Content-Disposition header of the response to attachment, so that
+// browsers save the response instead of displaying it. When filename is not empty, the
+// attachment is named after it and the content type is set from its extension.
+// Non-ASCII file names are sent as UTF-8 with the filename* parameter of RFC 6266.
+//
+// Returns:
+//   - The context itself for method chaining
+func (c *Ctx) Attachment(filename string) *Ctx {
+	if filename == "" {
+		return c.Set(HeaderContentDisposition, "attachment")
+	}
+
+	filename = filepath.Base(filename)
+	c.Set(HeaderContentType, getMimeType(filepath.Ext(filename)))
+	return c.Set(HeaderContentDisposition, contentDisposition("attachment", filename))
+}
+
+// sendFile sends a file of the OS file system or of config.FS with the static file serving.
+func (c *Ctx) sendFile(file string, config Static) error {
+	if config.FS != nil {
+		name := path.Clean("/" + filepath.ToSlash(file))[1:]
+		if name == "" {
+			name = "."
+		}
+
+		fileInfo, err := fs.Stat(config.FS, name)
+		if err != nil {
+			return sendFileError(file, err)
+		}
+		if fileInfo.IsDir() {
+			return sendFileError(file, nil)
+		}
+
+		config.InMemoryCache = false
+		serveFSFile(c, config.FS, "", name, fileInfo, config)
+		return nil
+	}
+
+	// Files are cached by absolute path, as with STATIC
+	filePath, err := filepath.Abs(file)
+	if err != nil {
+		filePath = file
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return sendFileError(file, err)
+	}
+	if fileInfo.IsDir() {
+		return sendFileError(file, nil)
+	}
+
+	serveFile(c, filePath, fileInfo, config)
+	return nil
+}
+
+// sendFileError returns the error for a file that can't be sent, a 404 Not Found
+// error when it doesn't exist or is a directory, reported with a nil err.
+func sendFileError(file string, err error) error {
+	if err == nil {
+		return NewHttpErrorWithError(StatusNotFound, "File not found", fmt.Errorf("%s is a directory", file))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewHttpErrorWithError(StatusNotFound, "File not found", err)
+	}
+	return err
+}
+
+// notModified writes a 304 Not Modified response when the request is a conditional GET
+// with an If-Modified-Since date not before modTime, and reports whether it did.
+func notModified(c *Ctx, modTime time.Time) bool {
+	if modTime.IsZero() || c.Request == nil || (c.Request.Method != MethodGet && c.Request.Method != MethodHead) {
+		return false
+	}
+
+	since, err := http.ParseTime(c.Get(HeaderIfModifiedSince))
+	if err != nil || modTime.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	c.Status(StatusNotModified)
+	c.Set(HeaderLastModified, modTime.UTC().Format(http.TimeFormat))
+	c.Writer.WriteHeader(c.statusCode)
+	return true
+}
+
+// contentDisposition returns a Content-Disposition header value naming a file, with an
+// ASCII filename parameter and, for non-ASCII names, a UTF-8 filename* parameter (RFC 6266).
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r >= 0x7f:
+			fallback.WriteByte('_')
+			ascii = false
+		case r == '"' || r == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(r)
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if !ascii {
+		value += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return value
+}
+
+// encodeExtValue percent-encodes a value for an extended parameter such as filename*,
+// keeping the attr-char characters of RFC 8187.
+func encodeExtValue(value string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0f])
+	}
+	return b.String()
+}
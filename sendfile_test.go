@@ -0,0 +1,124 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCtxSendFile tests sending files chosen by a handler
+func TestCtxSendFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.txt")
+	assert.NoError(os.WriteFile(report, []byte("0123456789"), 0o644))
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(os.Chtimes(report, modTime, modTime))
+
+	fsys := fstest.MapFS{"docs/guide.html": {Data: []byte("<p>guide</p>")}}
+
+	var sendErr error
+	router := NewRouter()
+	router.GET("/report", func(c *Ctx) { sendErr = c.SendFile(report, SendFile{ByteRange: true, MaxAge: 60}) })
+	router.GET("/cached", func(c *Ctx) { sendErr = c.SendFile(report, SendFile{ByteRange: true, InMemoryCache: true}) })
+	router.GET("/guide", func(c *Ctx) { sendErr = c.SendFile("/docs/guide.html", SendFile{FS: fsys}) })
+	router.GET("/missing", func(c *Ctx) { sendErr = c.SendFile(filepath.Join(dir, "missing.txt")) })
+	router.GET("/dir", func(c *Ctx) { sendErr = c.SendFile(dir) })
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		sendErr = nil
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	w := serve("/report", nil)
+	assert.NoError(sendErr)
+	assert.Equal(StatusOK, w.Code)
+	assert.Equal("0123456789", w.Body.String())
+	assert.Contains(w.Header().Get(HeaderContentType), "text/plain")
+	assert.Equal("public, max-age=60", w.Header().Get(HeaderCacheControl))
+	assert.Equal("Sat, 01 Mar 2025 12:00:00 GMT", w.Header().Get(HeaderLastModified))
+
+	w = serve("/guide", nil)
+	assert.NoError(sendErr)
+	assert.Equal("<p>guide</p>", w.Body.String())
+	assert.Contains(w.Header().Get(HeaderContentType), "text/html")
+
+	// Byte ranges, from the file and from the cache
+	for _, path := range []string{"/report", "/cached", "/cached"} {
+		w = serve(path, http.Header{"Range": {"bytes=2-5"}})
+		assert.Equal(StatusPartialContent, w.Code, path)
+		assert.Equal("2345", w.Body.String(), path)
+		assert.Equal("bytes 2-5/10", w.Header().Get("Content-Range"), path)
+		assert.Equal("4", w.Header().Get(HeaderContentLength), path)
+		assert.Equal(StatusRequestedRangeNotSatisfiable, serve(path, http.Header{"Range": {"bytes=20-30"}}).Code, path)
+	}
+
+	// Conditional requests
+	w = serve("/report", http.Header{"If-Modified-Since": {"Sat, 01 Mar 2025 12:00:00 GMT"}})
+	assert.Equal(StatusNotModified, w.Code)
+	assert.Empty(w.Body.String())
+	assert.Equal(StatusOK, serve("/report", http.Header{"If-Modified-Since": {"Fri, 28 Feb 2025 12:00:00 GMT"}}).Code)
+
+	// Files that can't be sent
+	for _, path := range []string{"/missing", "/dir"} {
+		w = serve(path, nil)
+		var httpErr *HttpError
+		if assert.ErrorAs(sendErr, &httpErr, path) {
+			assert.Equal(StatusNotFound, httpErr.Code, path)
+		}
+	}
+}
+
+// TestCtxDownload tests sending files as attachments
+func TestCtxDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.csv")
+	assert.NoError(os.WriteFile(report, []byte("a,b\n1,2\n"), 0o644))
+
+	router := NewRouter()
+	router.GET("/download", func(c *Ctx) { assert.NoError(c.Download(report, "")) })
+	router.GET("/named", func(c *Ctx) { assert.NoError(c.Download(report, "Übersicht 2025.csv")) })
+	router.GET("/attachment", func(c *Ctx) {
+		c.Attachment(`sub/"quoted".json`)
+		_, _ = c.Writer.Write([]byte(`{}`))
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	w := serve("/download")
+	assert.Equal("a,b\n1,2\n", w.Body.String())
+	assert.Equal(`attachment; filename="report.csv"`, w.Header().Get(HeaderContentDisposition))
+
+	w = serve("/named")
+	assert.Equal(`attachment; filename="_bersicht 2025.csv"; filename*=UTF-8''%C3%9Cbersicht%202025.csv`, w.Header().Get(HeaderContentDisposition))
+
+	w = serve("/attachment")
+	assert.Equal(`attachment; filename="\"quoted\".json"`, w.Header().Get(HeaderContentDisposition))
+	assert.Contains(w.Header().Get(HeaderContentType), "application/json")
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,7 +32,12 @@ type Server struct {
 	httpServer            *httpServer
 	router                *Router
 	disableStartupMessage bool
+	printRoutes           bool
 	errorHandler          Handler // Handler called when an error occurs during request processing
+	prefork               bool
+	preforkCount          int
+	preforkStop           chan struct{}
+	preforkStopOnce       sync.Once
 }
 
 type httpServer struct {
@@ -41,6 +48,7 @@ type httpServer struct {
 	router       *Router
 	eng          gnet.Engine
 	errorHandler Handler // Handler called when an error occurs during request processing
+	jsonCodec    Codec   // JSON codec of the configuration, nil for the registered codec
 
 	readTimeout  time.Duration // Read timeout for requests
 	writeTimeout time.Duration // Write timeout for responses
@@ -65,6 +73,23 @@ func defaultErrorHandler(c *Ctx) {
 	c.String("%v", err)
 }
 
+// handleError calls the error handler of the request error, if any.
+// The error handler of the group with the longest prefix matching the request path
+// takes precedence over the server error handler, which defaults to defaultErrorHandler.
+func handleError(ctx *Ctx, router *Router, errorHandler Handler) {
+	if ctx.GetError() == nil {
+		return
+	}
+
+	if handler := router.errorHandler(ctx.Request.URL.Path); handler != nil {
+		handler(ctx)
+	} else if errorHandler != nil {
+		errorHandler(ctx)
+	} else {
+		defaultErrorHandler(ctx)
+	}
+}
+
 // New creates a new server with the given configuration.
 // This is the main entry point for creating a ngebut server instance.
 //
@@ -82,11 +107,14 @@ func New(config ...Config) *Server {
 		cfg = config[0]
 	}
 
+	r.StrictRegistration = cfg.StrictRegistration
+
 	hs := &httpServer{
 		addr:         "",
 		multicore:    true,
 		router:       r,
 		errorHandler: cfg.ErrorHandler,
+		jsonCodec:    newJSONCodec(cfg.JSONEncoder, cfg.JSONDecoder),
 		readTimeout:  cfg.ReadTimeout,
 		writeTimeout: cfg.WriteTimeout,
 		idleTimeout:  cfg.IdleTimeout,
@@ -96,7 +124,11 @@ func New(config ...Config) *Server {
 		httpServer:            hs,
 		router:                r,
 		disableStartupMessage: cfg.DisableStartupMessage,
+		printRoutes:           cfg.PrintRoutes,
 		errorHandler:          cfg.ErrorHandler,
+		prefork:               cfg.Prefork,
+		preforkCount:          cfg.PreforkProcesses,
+		preforkStop:           make(chan struct{}),
 	}
 }
 
@@ -150,6 +182,9 @@ func getRequest(r *http.Request) *Request {
 			// Create a new ReadCloser so the body can be read again if needed
 			// Use the same buffer to avoid allocation
 			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// The trailer values of a chunked body are only known once it has been read
+			req.Trailer = requestTrailer(r.Trailer)
 		}
 
 		// Return the buffer to the pool
@@ -181,6 +216,16 @@ func getRequest(r *http.Request) *Request {
 	return req
 }
 
+// requestTrailer returns the trailer fields of a request whose body has been read,
+// leaving out the fields that were declared but not sent. It returns nil if there are none.
+func requestTrailer(trailer http.Header) *Header {
+	h := NewHeaderFromMap(trailer)
+	if len(*h) == 0 {
+		return nil
+	}
+	return h
+}
+
 // releaseRequest returns a Request to the pool
 func releaseRequest(r *Request) {
 	// Reset all fields to zero values
@@ -201,6 +246,7 @@ func releaseRequest(r *Request) {
 	r.Host = ""
 	r.RemoteAddr = ""
 	r.RequestURI = ""
+	r.Trailer = nil
 	r.ctx = nil
 
 	// Return to the pool
@@ -290,6 +336,11 @@ func (hs *httpServer) OnTraffic(c gnet.Conn) gnet.Action {
 		// Create a Request object from the *http.Request
 		req := getRequest(httpReq)
 
+		// Attach trailer fields parsed from a chunked body
+		if len(hc.Trailer) > 0 {
+			req.Trailer = NewHeaderFromMap(hc.Trailer)
+		}
+
 		// Process the request
 		processRequest(hs, hc, req, c)
 
@@ -467,6 +518,7 @@ func processRequest(hs *httpServer, hc *httpparser.Codec, req *Request, c gnet.C
 
 	ctx := getContextFromRequest(recorder, req)
 	defer ReleaseContext(ctx)
+	ctx.jsonCodec = hs.jsonCodec
 
 	// Set server header directly in context header
 	ctx.Set(HeaderServer, "ngebut")
@@ -475,13 +527,7 @@ func processRequest(hs *httpServer, hc *httpparser.Codec, req *Request, c gnet.C
 	hs.router.ServeHTTP(ctx, ctx.Request)
 
 	// Handle errors
-	if err := ctx.GetError(); err != nil {
-		if hs.errorHandler != nil {
-			hs.errorHandler(ctx)
-		} else {
-			defaultErrorHandler(ctx)
-		}
-	}
+	handleError(ctx, hs.router, hs.errorHandler)
 
 	// Ensure headers set after c.Next() in middleware are included in the response
 	if ctx.Writer != nil {
@@ -513,16 +559,56 @@ func processRequest(hs *httpServer, hc *httpparser.Codec, req *Request, c gnet.C
 		if ctx.statusCode == StatusInternalServerError {
 			ctx.statusCode = StatusOK
 		}
-		hc.WriteResponse(ctx.statusCode, parserHeaders, nil)
+		// Report the length of the body a GET request would have received, without sending it
+		hc.WriteHeadResponse(ctx.statusCode, parserHeaders, len(recorder.body))
+	} else if ctx.trailer != nil || len(parserHeaders[HeaderTrailer]) > 0 {
+		// Trailers can only be sent with a chunked body
+		var trailer httpparser.Header
+		if ctx.trailer != nil {
+			trailer = httpparser.Header(*ctx.trailer)
+			if len(parserHeaders[HeaderTrailer]) == 0 {
+				parserHeaders[HeaderTrailer] = []string{trailerNames(*ctx.trailer)}
+			}
+		}
+		hc.WriteChunkedResponse(ctx.statusCode, parserHeaders, recorder.body, trailer)
 	} else {
 		hc.WriteResponse(ctx.statusCode, parserHeaders, recorder.body)
 	}
 }
 
+// trailerNames returns the comma-separated names of the given trailer fields
+// for use in the Trailer response header.
+func trailerNames(trailer Header) string {
+	names := make([]string, 0, len(trailer))
+	for k := range trailer {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
 func (s *Server) Router() *Router {
 	return s.router
 }
 
+// ServeHTTP implements http.Handler so the server can be mounted in a net/http stack.
+// The request is routed exactly as it would be by the gnet server, including global
+// middleware and the configured error handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := GetContext(w, r)
+	defer ReleaseContext(ctx)
+	ctx.jsonCodec = s.httpServer.jsonCodec
+
+	// Process the request
+	s.router.ServeHTTP(ctx, ctx.Request)
+
+	// Handle errors
+	handleError(ctx, s.router, s.errorHandler)
+
+	// Make sure the status line is written even if the handler wrote no body
+	ctx.Writer.Flush()
+}
+
 // Listen starts the server and listens for incoming connections.
 func (s *Server) Listen(addr string) error {
 	// Clean up the address to ensure it is in the correct format
@@ -530,15 +616,29 @@ func (s *Server) Listen(addr string) error {
 		addr = ":3000" // Default address if none provided
 	}
 
+	// Refuse to start with conflicting routes
+	if err := s.router.Err(); err != nil {
+		return err
+	}
+
 	// Set the address in the httpServer struct
 	s.httpServer.addr = "tcp://" + addr
 
 	// Initialize the logger
 	initLogger(log.InfoLevel)
 
-	// Display startup message if not disabled
-	if !s.disableStartupMessage {
-		displayStartupMessage(addr)
+	// In prefork mode the master only supervises the child processes
+	if s.prefork && !IsChild() {
+		return s.preforkMaster(addr)
+	}
+
+	if IsChild() {
+		// Spread the load over the child processes and exit when the master is gone
+		runtime.GOMAXPROCS(1)
+		go watchPreforkMaster()
+	} else if !s.disableStartupMessage {
+		// Display startup message if not disabled
+		displayStartupMessage(addr, s.startupRoutes())
 	}
 
 	// Start the server directly
@@ -561,8 +661,23 @@ func (s *Server) Listen(addr string) error {
 	)
 }
 
+// startupRoutes returns the routes to print in the startup message, or nil if disabled.
+func (s *Server) startupRoutes() []RouteInfo {
+	if !s.printRoutes {
+		return nil
+	}
+	return s.Routes()
+}
+
 // Shutdown gracefully stops the server.
+// In prefork mode, the master stops all child processes.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.prefork && !IsChild() {
+		s.preforkStopOnce.Do(func() {
+			close(s.preforkStop)
+		})
+		return nil
+	}
 	return s.httpServer.eng.Stop(ctx)
 }
 
@@ -616,7 +731,8 @@ func (s *Server) STATIC(prefix, root string, config ...Static) *Router {
 	return s.router.STATIC(prefix, root, config...)
 }
 
-// Use adds middleware to the router.
+// Use adds middleware to the router, optionally scoped to a path prefix given as the
+// first argument, see Router.Use.
 func (s *Server) Use(middleware ...interface{}) {
 	s.router.Use(middleware...)
 }
@@ -626,6 +742,11 @@ func (s *Server) NotFound(handler Handler) {
 	s.router.NotFound = handler
 }
 
+// MethodNotAllowed sets the handler for requests whose path matches routes of other methods.
+func (s *Server) MethodNotAllowed(handler Handler) {
+	s.router.MethodNotAllowed = handler
+}
+
 // Group creates a new route group with the given prefix.
 func (s *Server) Group(prefix string) *Group {
 	return s.router.Group(prefix)
@@ -522,3 +522,49 @@ func TestServerStaticDefaultIndexHandling(t *testing.T) {
 	assert.True(t, w.Code == StatusOK || w.Code == StatusNotFound,
 		"Response should be either 200 (if route matches) or 404 (if route doesn't match without trailing slash)")
 }
+
+// testRouteMiddleware is a named middleware used to check route introspection
+func testRouteMiddleware(c *Ctx) {
+	c.Next()
+}
+
+// TestServerRoutes tests the route table introspection
+func TestServerRoutes(t *testing.T) {
+	server := New(DefaultConfig())
+	server.Use(testRouteMiddleware)
+
+	handler := func(c *Ctx) {}
+	server.GET("/users/:id", testRouteMiddleware, handler).Name("user")
+	server.POST("/users", handler)
+	server.Group("/api").GET("/status", handler)
+	server.STATIC("/assets", "./examples")
+
+	routes := server.Routes()
+	require.Len(t, routes, 4, "all registered routes should be listed")
+
+	assert.Equal(t, RouteInfo{
+		Method:     MethodGet,
+		Pattern:    "/api/status",
+		Handlers:   1,
+		Middleware: []string{"github.com/ryanbekhen/ngebut.testRouteMiddleware"},
+	}, routes[0], "group routes should include the prefix")
+	assert.Equal(t, "/assets/*", routes[1].Pattern, "static routes should be listed")
+	assert.Equal(t, MethodPost, routes[2].Method)
+	assert.Equal(t, "/users", routes[2].Pattern)
+
+	assert.Equal(t, "user", routes[3].Name)
+	assert.Equal(t, 2, routes[3].Handlers)
+	assert.Equal(t, []string{
+		"github.com/ryanbekhen/ngebut.testRouteMiddleware",
+		"github.com/ryanbekhen/ngebut.testRouteMiddleware",
+	}, routes[3].Middleware, "route handlers before the final one should be listed as middleware")
+
+	data, err := server.RoutesJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"pattern": "/users/:id"`)
+	assert.Contains(t, string(data), `"name": "user"`)
+
+	again, err := server.RoutesJSON()
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again), "JSON output should be stable")
+}
@@ -0,0 +1,153 @@
+package ngebut
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// fsCacheID numbers the file systems served with Static.FS,
+// to tell their files apart in the file cache
+var fsCacheID atomic.Uint64
+
+// createFSStaticHandler creates a handler function for serving the files of config.FS
+func createFSStaticHandler(root string, config Static) Handler {
+	fsys := config.FS
+	if root = strings.Trim(root, "/"); root != "" && root != "." {
+		sub, err := fs.Sub(fsys, root)
+		if err != nil {
+			panic("invalid static root " + root + ": " + err.Error())
+		}
+		fsys = sub
+	}
+
+	// Cache keys of the files, the OS files are cached by absolute path
+	keyPrefix := "fs" + strconv.FormatUint(fsCacheID.Add(1), 10) + ":"
+
+	// Pre-cache all files if in-memory caching is enabled, without blocking the handler creation
+	if config.InMemoryCache {
+		go preloadFSToCache(fsys, keyPrefix, config)
+	}
+
+	return func(c *Ctx) {
+		// Skip if Next function returns true
+		if config.Next != nil && config.Next(c) {
+			c.Next()
+			return
+		}
+
+		// The file path is the path matched by the wildcard of the route, which may be
+		// spelled differently in the request, with another case or repeated slashes
+		filePath := strings.TrimPrefix(c.Param("*"), "/")
+
+		if filePath == "" {
+			filePath = config.Index
+		}
+
+		// File system names are slash separated, without a leading slash or ".." elements
+		name := path.Clean("/" + filePath)[1:]
+		if name == "" {
+			name = "."
+		}
+		if !fs.ValidPath(name) {
+			c.Status(StatusForbidden)
+			c.String("Forbidden")
+			return
+		}
+
+		fileInfo, err := fs.Stat(fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.Status(StatusNotFound)
+				c.String("File not found")
+				return
+			}
+			c.Status(StatusInternalServerError)
+			c.String("Internal Server Error")
+			return
+		}
+
+		// Handle directory requests
+		if fileInfo.IsDir() {
+			if config.Index != "" {
+				indexName := path.Join(name, config.Index)
+				if indexInfo, err := fs.Stat(fsys, indexName); err == nil && !indexInfo.IsDir() {
+					name = indexName
+					fileInfo = indexInfo
+				} else if config.Browse {
+					serveFSDirectoryListing(c, fsys, name)
+					return
+				} else {
+					c.Status(StatusForbidden)
+					c.String("Directory listing is disabled")
+					return
+				}
+			} else if config.Browse {
+				serveFSDirectoryListing(c, fsys, name)
+				return
+			} else {
+				c.Status(StatusForbidden)
+				c.String("Directory listing is disabled")
+				return
+			}
+		}
+
+		serveFSFile(c, fsys, keyPrefix+name, name, fileInfo, config)
+	}
+}
+
+// preloadFSToCache loads the files of the file system into the cache
+func preloadFSToCache(fsys fs.FS, keyPrefix string, config Static) {
+	cache := getCacheInstance(config.MaxCacheSize, config.MaxCacheItems)
+
+	_ = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil // Skip directories and errors
+		}
+
+		// Skip files larger than 5MB to avoid caching very large files
+		info, err := d.Info()
+		if err != nil || info.Size() > 5*1024*1024 {
+			return nil
+		}
+
+		key := keyPrefix + name
+		if _, exists := cache.Get(key); exists {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil
+		}
+		cache.Set(key, data, info.ModTime(), info.Size(), getMimeType(path.Ext(name)))
+		return nil
+	})
+}
+
+// serveFSFile serves a file of a file system, or the requested byte ranges of it
+func serveFSFile(c *Ctx, fsys fs.FS, key, name string, fileInfo fs.FileInfo, config Static) {
+	serveContent(c, key, name, fileInfo, config, func() (io.Reader, func(), error) {
+		file, err := fsys.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, func() { _ = file.Close() }, nil
+	})
+}
+
+// serveFSDirectoryListing serves the listing of a directory of a file system
+func serveFSDirectoryListing(c *Ctx, fsys fs.FS, name string) {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		c.Status(StatusInternalServerError)
+		c.String("Error reading directory")
+		return
+	}
+
+	writeDirectoryListing(c, entries, name)
+}
@@ -0,0 +1,170 @@
+package ngebut
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStaticFS tests serving static files from an fs.FS
+func TestStaticFS(t *testing.T) {
+	assert := assert.New(t)
+
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"secret.txt":             {Data: []byte("secret")},
+		"public/index.html":      {Data: []byte("<!DOCTYPE html><p>home</p>"), ModTime: modTime},
+		"public/app.js":          {Data: []byte("console.log(1)")},
+		"public/docs/guide.txt":  {Data: []byte("0123456789")},
+		"public/docs/index.html": {Data: []byte("docs")},
+		"public/files/a.txt":     {Data: []byte("a")},
+		"public/files/b.txt":     {Data: []byte("b")},
+	}
+
+	router := NewRouter()
+	router.STATIC("/assets", "public", Static{FS: fsys, Index: "index.html", ByteRange: true, InMemoryCache: true})
+	router.STATIC("/nocache", "public", Static{FS: fsys, ByteRange: true})
+	router.STATIC("/browse", "public/files", Static{FS: fsys, Browse: true})
+	router.STATIC("/root", ".", Static{FS: fsys, Index: "index.html"})
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Files and index files
+	w := serve("/assets/app.js", nil)
+	assert.Equal(StatusOK, w.Code)
+	assert.Equal("console.log(1)", w.Body.String())
+	assert.Contains(w.Header().Get(HeaderContentType), "javascript")
+	assert.Empty(w.Header().Get(HeaderLastModified), "files without a modification time should have no Last-Modified")
+
+	w = serve("/assets/", nil)
+	assert.Equal("<!DOCTYPE html><p>home</p>", w.Body.String())
+	assert.Equal("Sat, 01 Mar 2025 12:00:00 GMT", w.Header().Get(HeaderLastModified))
+	assert.Equal("docs", serve("/assets/docs", nil).Body.String())
+	assert.Equal("docs", serve("/root/public/docs/", nil).Body.String())
+
+	// Missing files and paths outside the root
+	assert.Equal(StatusNotFound, serve("/assets/missing.js", nil).Code)
+	assert.Equal(StatusNotFound, serve("/assets/../secret.txt", nil).Code)
+	assert.Equal(StatusNotFound, serve("/assets/%2e%2e/secret.txt", nil).Code)
+
+	// Byte ranges, from the cache and from the file
+	for _, prefix := range []string{"/assets", "/nocache"} {
+		w = serve(prefix+"/docs/guide.txt", http.Header{"Range": {"bytes=2-5"}})
+		assert.Equal(StatusPartialContent, w.Code, prefix)
+		assert.Equal("2345", w.Body.String(), prefix)
+		assert.Equal("bytes 2-5/10", w.Header().Get("Content-Range"), prefix)
+		assert.Equal("4", w.Header().Get(HeaderContentLength), prefix)
+
+		assert.Equal("789", serve(prefix+"/docs/guide.txt", http.Header{"Range": {"bytes=-3"}}).Body.String(), prefix)
+		assert.Equal(StatusRequestedRangeNotSatisfiable, serve(prefix+"/docs/guide.txt", http.Header{"Range": {"bytes=20-30"}}).Code, prefix)
+	}
+
+	// Directory listings
+	w = serve("/browse/", nil)
+	assert.Equal(StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `<a href="a.txt">a.txt</a>`)
+	assert.Contains(w.Body.String(), `<a href="b.txt">b.txt</a>`)
+	assert.Equal(StatusForbidden, serve("/nocache/files/", nil).Code, "listing should be disabled without Browse")
+}
+
+// TestStaticOSAndFS tests that files of the OS and of an fs.FS are served alike, with
+// and without the in-memory cache
+func TestStaticOSAndFS(t *testing.T) {
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "guide.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("0123456789"), 0o600))
+	assert.NoError(t, os.Chtimes(file, modTime, modTime))
+	fsys := fstest.MapFS{"guide.txt": {Data: []byte("0123456789"), ModTime: modTime}}
+
+	router := NewRouter()
+	router.STATIC("/os", dir, Static{ByteRange: true})
+	router.STATIC("/os-cache", dir, Static{ByteRange: true, InMemoryCache: true})
+	router.STATIC("/fs", ".", Static{FS: fsys, ByteRange: true})
+	router.STATIC("/fs-cache", ".", Static{FS: fsys, ByteRange: true, InMemoryCache: true})
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	for _, prefix := range []string{"/os", "/os-cache", "/fs", "/fs-cache"} {
+		t.Run(prefix[1:], func(t *testing.T) {
+			assert := assert.New(t)
+			path := prefix + "/guide.txt"
+
+			// Twice, to serve from the cache once it is filled
+			for i := 0; i < 2; i++ {
+				w := serve(path, nil)
+				assert.Equal(StatusOK, w.Code)
+				assert.Equal("0123456789", w.Body.String())
+				assert.Equal(MIMETextPlainCharsetUTF8, w.Header().Get(HeaderContentType))
+				assert.Equal("10", w.Header().Get(HeaderContentLength))
+				assert.Equal("Sat, 01 Mar 2025 12:00:00 GMT", w.Header().Get(HeaderLastModified))
+			}
+
+			// Conditional requests
+			assert.Equal(StatusNotModified, serve(path, http.Header{"If-Modified-Since": {"Sat, 01 Mar 2025 12:00:00 GMT"}}).Code)
+			assert.Equal(StatusOK, serve(path, http.Header{"If-Modified-Since": {"Fri, 28 Feb 2025 12:00:00 GMT"}}).Code)
+
+			// A single range
+			w := serve(path, http.Header{"Range": {"bytes=2-5"}})
+			assert.Equal(StatusPartialContent, w.Code)
+			assert.Equal("2345", w.Body.String())
+			assert.Equal("bytes 2-5/10", w.Header().Get(HeaderContentRange))
+			assert.Equal(MIMETextPlainCharsetUTF8, w.Header().Get(HeaderContentType))
+			assert.Equal(StatusRequestedRangeNotSatisfiable, serve(path, http.Header{"Range": {"bytes=20-30"}}).Code)
+
+			// Several ranges, in a multipart/byteranges body
+			w = serve(path, http.Header{"Range": {"bytes=7-8, 0-1"}})
+			assert.Equal(StatusPartialContent, w.Code)
+			assert.Equal(strconv.Itoa(w.Body.Len()), w.Header().Get(HeaderContentLength))
+			mediaType, params, err := mime.ParseMediaType(w.Header().Get(HeaderContentType))
+			assert.NoError(err)
+			assert.Equal("multipart/byteranges", mediaType)
+
+			reader := multipart.NewReader(w.Body, params["boundary"])
+			for _, want := range []struct{ contentRange, body string }{{"bytes 7-8/10", "78"}, {"bytes 0-1/10", "01"}} {
+				part, err := reader.NextPart()
+				if !assert.NoError(err) {
+					return
+				}
+				body, _ := io.ReadAll(part)
+				assert.Equal(want.contentRange, part.Header.Get(HeaderContentRange))
+				assert.Equal(MIMETextPlainCharsetUTF8, part.Header.Get(HeaderContentType))
+				assert.Equal(want.body, string(body))
+			}
+			_, err = reader.NextPart()
+			assert.ErrorIs(err, io.EOF)
+		})
+	}
+}
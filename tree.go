@@ -0,0 +1,365 @@
+package ngebut
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ryanbekhen/ngebut/internal/radix"
+)
+
+// maxRouteParams is the maximum number of parameters and wildcards in a route pattern.
+const maxRouteParams = 32
+
+// routeNode is a node of the compiled route tree of a method.
+// Each node matches one path segment. Children are tried in priority order:
+// static segments, then parameters, then wildcards, backtracking when the rest
+// of the path doesn't match.
+type routeNode struct {
+	static map[string]*routeNode // Children matching a static segment, by segment
+	params []*routeNode          // Children matching any segment, constrained parameters first
+	plus   *routeNode            // Child matching one or more segments
+	star   *routeNode            // Child matching zero or more segments
+
+	constraint string              // Constraint expression of a parameter node
+	check      func(v string) bool // Constraint check of a parameter node, nil if unconstrained
+
+	leaf      *routeLeaf // Route ending at this node, for paths without a trailing slash
+	slashLeaf *routeLeaf // Route ending at this node, for paths with a trailing slash
+}
+
+// routeLeaf is a route pattern variant at the end of a path in the tree.
+type routeLeaf struct {
+	handlers []Handler
+	names    []string // Names of the parameters and wildcards, in the order they are captured
+	hashes   []uint32 // Hashes of the names, see stringHash
+
+	// Router middleware of the route, chosen when the table is built unless the path-scoped
+	// middleware depends on the parameter values, see Router.patternMiddleware
+	middleware []MiddlewareFunc
+	resolved   bool
+}
+
+// routeValues holds the parameter values captured while matching a path.
+// The values are substrings of the path, so capturing them doesn't allocate.
+type routeValues struct {
+	values [maxRouteParams]string
+	count  int
+}
+
+// insert adds a route pattern variant below the node.
+func (n *routeNode) insert(segments []radix.Segment, trailingSlash bool, leaf *routeLeaf) {
+	for _, segment := range segments {
+		switch segment.Kind {
+		case radix.Static:
+			if n.static == nil {
+				n.static = make(map[string]*routeNode)
+			}
+			child, exists := n.static[segment.Path]
+			if !exists {
+				child = &routeNode{}
+				n.static[segment.Path] = child
+			}
+			n = child
+		case radix.Param:
+			n = n.paramChild(segment.Constraint)
+		case radix.Wildcard:
+			if segment.OneOrMore {
+				if n.plus == nil {
+					n.plus = &routeNode{}
+				}
+				n = n.plus
+			} else {
+				if n.star == nil {
+					n.star = &routeNode{}
+				}
+				n = n.star
+			}
+		}
+	}
+
+	// A trailing wildcard takes the trailing slash, if any
+	if len(segments) > 0 && segments[len(segments)-1].Kind == radix.Wildcard {
+		n.leaf, n.slashLeaf = leaf, leaf
+	} else if trailingSlash {
+		n.slashLeaf = leaf
+	} else {
+		n.leaf = leaf
+	}
+}
+
+// paramChild returns the parameter child with the given constraint, creating it if needed.
+func (n *routeNode) paramChild(constraint string) *routeNode {
+	for _, child := range n.params {
+		if child.constraint == constraint {
+			return child
+		}
+	}
+
+	child := &routeNode{constraint: constraint}
+	if constraint != "" {
+		child.check = compileConstraint(constraint)
+	}
+	n.params = append(n.params, child)
+
+	// Constrained parameters are more specific, so they are tried first
+	sort.SliceStable(n.params, func(i, j int) bool {
+		return n.params[i].check != nil && n.params[j].check == nil
+	})
+	return child
+}
+
+// match returns the leaf of the route matching path from index i, capturing
+// the parameter values in values. It returns nil if no route matches.
+func (n *routeNode) match(path string, i int, values *routeValues) *routeLeaf {
+	// Skip the slashes before the segment, repeated slashes are ignored
+	for i < len(path) && path[i] == '/' {
+		i++
+	}
+
+	if i >= len(path) {
+		if leaf := n.endLeaf(path); leaf != nil {
+			return leaf
+		}
+		// A "*" wildcard matches an empty remainder
+		if n.star != nil {
+			return n.star.matchEmpty(path, i, values)
+		}
+		return nil
+	}
+
+	end := strings.IndexByte(path[i:], '/')
+	if end < 0 {
+		end = len(path)
+	} else {
+		end += i
+	}
+	segment := path[i:end]
+
+	// Static segments take precedence over parameters
+	if child := n.static[segment]; child != nil {
+		if leaf := child.match(path, end, values); leaf != nil {
+			return leaf
+		}
+	}
+
+	// Parameters take precedence over wildcards
+	if values.count < maxRouteParams {
+		for _, child := range n.params {
+			if child.check != nil && !child.check(segment) {
+				continue
+			}
+			values.values[values.count] = segment
+			values.count++
+			if leaf := child.match(path, end, values); leaf != nil {
+				return leaf
+			}
+			values.count--
+		}
+	}
+
+	if n.plus != nil {
+		if leaf := n.plus.matchWildcard(path, i, values); leaf != nil {
+			return leaf
+		}
+	}
+	if n.star != nil {
+		if leaf := n.star.matchWildcard(path, i, values); leaf != nil {
+			return leaf
+		}
+		return n.star.matchEmpty(path, i, values)
+	}
+	return nil
+}
+
+// matchWildcard matches one or more segments starting at index i with the wildcard node,
+// trying the longest value first.
+func (n *routeNode) matchWildcard(path string, i int, values *routeValues) *routeLeaf {
+	if values.count >= maxRouteParams {
+		return nil
+	}
+
+	// The value doesn't include the trailing slash
+	end := len(path)
+	for end > i && path[end-1] == '/' {
+		end--
+	}
+
+	for end > i {
+		values.values[values.count] = path[i:end]
+		values.count++
+		if leaf := n.match(path, end, values); leaf != nil {
+			return leaf
+		}
+		values.count--
+
+		// Try again without the last segment
+		end = i + strings.LastIndexByte(path[i:end], '/')
+		for end > i && path[end-1] == '/' {
+			end--
+		}
+	}
+	return nil
+}
+
+// matchEmpty matches the wildcard node with an empty value.
+func (n *routeNode) matchEmpty(path string, i int, values *routeValues) *routeLeaf {
+	if values.count >= maxRouteParams {
+		return nil
+	}
+	values.values[values.count] = ""
+	values.count++
+	if leaf := n.match(path, i, values); leaf != nil {
+		return leaf
+	}
+	values.count--
+	return nil
+}
+
+// matchFold returns the path of a route matching path from index i when the case of
+// static segments is ignored, appended to fixed with the case of the route. Parameter
+// and wildcard values keep their case. Segments of the exact case are tried first.
+func (n *routeNode) matchFold(path string, i int, fixed []byte) ([]byte, bool) {
+	// Skip the slashes before the segment, repeated slashes are ignored
+	for i < len(path) && path[i] == '/' {
+		i++
+	}
+
+	if i >= len(path) {
+		if n.endLeaf(path) != nil {
+			if len(fixed) == 0 || hasTrailingSlash(path) {
+				fixed = append(fixed, '/')
+			}
+			return fixed, true
+		}
+		// A "*" wildcard matches an empty remainder
+		if n.star != nil {
+			return n.star.matchFold(path, i, fixed)
+		}
+		return nil, false
+	}
+
+	end := strings.IndexByte(path[i:], '/')
+	if end < 0 {
+		end = len(path)
+	} else {
+		end += i
+	}
+	segment := path[i:end]
+
+	// Static segments take precedence over parameters
+	if child := n.static[segment]; child != nil {
+		if f, ok := child.matchFold(path, end, append(append(fixed, '/'), segment...)); ok {
+			return f, true
+		}
+	}
+	for key, child := range n.static {
+		if key != segment && strings.EqualFold(key, segment) {
+			if f, ok := child.matchFold(path, end, append(append(fixed, '/'), key...)); ok {
+				return f, true
+			}
+		}
+	}
+
+	// Parameters take precedence over wildcards
+	for _, child := range n.params {
+		if child.check != nil && !child.check(segment) {
+			continue
+		}
+		if f, ok := child.matchFold(path, end, append(append(fixed, '/'), segment...)); ok {
+			return f, true
+		}
+	}
+
+	if n.plus != nil {
+		if f, ok := n.plus.matchFoldWildcard(path, i, fixed); ok {
+			return f, true
+		}
+	}
+	if n.star != nil {
+		if f, ok := n.star.matchFoldWildcard(path, i, fixed); ok {
+			return f, true
+		}
+		return n.star.matchFold(path, i, fixed)
+	}
+	return nil, false
+}
+
+// matchFoldWildcard matches one or more segments starting at index i with the wildcard
+// node as matchWildcard does, ignoring the case of the static segments after it.
+func (n *routeNode) matchFoldWildcard(path string, i int, fixed []byte) ([]byte, bool) {
+	// The value doesn't include the trailing slash
+	end := len(path)
+	for end > i && path[end-1] == '/' {
+		end--
+	}
+
+	for end > i {
+		if f, ok := n.matchFold(path, end, append(append(fixed, '/'), path[i:end]...)); ok {
+			return f, true
+		}
+
+		// Try again without the last segment
+		end = i + strings.LastIndexByte(path[i:end], '/')
+		for end > i && path[end-1] == '/' {
+			end--
+		}
+	}
+	return nil, false
+}
+
+// endLeaf returns the leaf of the route ending at the node for the path.
+func (n *routeNode) endLeaf(path string) *routeLeaf {
+	if hasTrailingSlash(path) {
+		return n.slashLeaf
+	}
+	return n.leaf
+}
+
+// addRoute adds the variants of a route pattern to the tree, and gives the leaf of each
+// variant to onLeaf with the variant and its segments.
+func (n *routeNode) addRoute(rt *route, onLeaf func(variant string, segments []radix.Segment, leaf *routeLeaf)) {
+	// Optional parameters are left out in some variants, e.g. /files for /files/:name?
+	for _, variant := range radix.ExpandOptional(rt.Pattern) {
+		segments := radix.ParsePattern(variant)
+
+		leaf := &routeLeaf{handlers: rt.Handlers}
+		for _, segment := range segments {
+			if segment.Kind != radix.Static {
+				leaf.names = append(leaf.names, segment.Name)
+				leaf.hashes = append(leaf.hashes, stringHash(segment.Name))
+			}
+		}
+
+		n.insert(segments, hasTrailingSlash(variant), leaf)
+		onLeaf(variant, segments, leaf)
+	}
+}
+
+// setRouteParams stores the captured parameter values of the leaf in the context.
+func (c *Ctx) setRouteParams(leaf *routeLeaf, values *routeValues) {
+	if len(leaf.names) == 0 {
+		return
+	}
+
+	rp := c.paramCache.routeParams
+	if rp == nil {
+		rp = getRouteParams()
+		c.paramCache.routeParams = rp
+	} else {
+		rp.Reset()
+	}
+	c.paramCache.valid = true
+
+	for i, name := range leaf.names {
+		if i < len(rp.fixedKeys) {
+			rp.fixedKeys[i] = name
+			rp.fixedValues[i] = values.values[i]
+			rp.fixedHashes[i] = leaf.hashes[i]
+			rp.count++
+		} else {
+			rp.keys = append(rp.keys, name)
+			rp.values = append(rp.values, values.values[i])
+			rp.hashes = append(rp.hashes, leaf.hashes[i])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package ngebut
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ryanbekhen/ngebut/internal/radix"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouteTreeMatch tests priorities, backtracking and trailing slashes in the route tree
+func TestRouteTreeMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	root := &routeNode{}
+	patterns := []string{
+		"/",
+		"/users",
+		"/users/",
+		"/users/new",
+		"/users/:id<int>",
+		"/users/:name",
+		"/users/:id/posts",
+		"/users/admin/settings",
+		"/files/*path",
+		"/assets/+",
+		"/a/*/b/:x",
+	}
+	for _, pattern := range patterns {
+		router := NewRouter()
+		router.GET(pattern, func(c *Ctx) {})
+		rt := router.Routes[0]
+		root.addRoute(&rt, func(string, []radix.Segment, *routeLeaf) {})
+	}
+
+	tests := []struct {
+		path    string
+		pattern string
+		names   []string
+		values  []string
+	}{
+		{"/", "/", nil, nil},
+		{"/users", "/users", nil, nil},
+		{"/users/", "/users/", nil, nil},
+		{"//users", "/users", nil, nil},
+		{"/users/new", "/users/new", nil, nil},
+		{"/users/42", "/users/:id<int>", []string{"id"}, []string{"42"}},
+		{"/users/bob", "/users/:name", []string{"name"}, []string{"bob"}},
+		{"/users/admin/posts", "/users/:id/posts", []string{"id"}, []string{"admin"}},
+		{"/users/admin/settings", "/users/admin/settings", nil, nil},
+		{"/files", "/files/*path", []string{"path"}, []string{""}},
+		{"/files/css/site.css/", "/files/*path", []string{"path"}, []string{"css/site.css"}},
+		{"/assets/logo.png", "/assets/+", []string{"+"}, []string{"logo.png"}},
+		{"/a/1/2/b/3", "/a/*/b/:x", []string{"*", "x"}, []string{"1/2", "3"}},
+		{"/a/b/4", "/a/*/b/:x", []string{"*", "x"}, []string{"", "4"}},
+		{"/assets", "", nil, nil},
+		{"/users/bob/", "", nil, nil},
+		{"/missing", "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		var values routeValues
+		leaf := root.match(tt.path, 0, &values)
+		if tt.pattern == "" {
+			assert.Nil(leaf, "%s should not match", tt.path)
+			continue
+		}
+		if !assert.NotNil(leaf, "%s should match %s", tt.path, tt.pattern) {
+			continue
+		}
+		assert.Equal(tt.names, leaf.names, tt.path)
+		if tt.values != nil {
+			assert.Equal(tt.values, values.values[:values.count], tt.path)
+		}
+	}
+}
+
+// TestRouteTreeMatchFold tests matching paths in the route tree ignoring the case of static segments
+func TestRouteTreeMatchFold(t *testing.T) {
+	assert := assert.New(t)
+
+	root := &routeNode{}
+	for _, pattern := range []string{"/", "/Users", "/users/new", "/users/:name", "/users/:id<int>/Posts", "/Files/*path", "/docs/"} {
+		router := NewRouter()
+		router.GET(pattern, func(c *Ctx) {})
+		rt := router.Routes[0]
+		root.addRoute(&rt, func(string, []radix.Segment, *routeLeaf) {})
+	}
+
+	tests := []struct {
+		path  string
+		fixed string
+	}{
+		{"/", "/"},
+		{"/USERS", "/Users"},
+		{"//users", "/Users"},
+		{"/USERS/NEW", "/users/new"},
+		{"/Users/Bob", "/users/Bob"},
+		{"/USERS/42/posts", "/users/42/Posts"},
+		{"/files/CSS/Site.css", "/Files/CSS/Site.css"},
+		{"/FILES", "/Files"},
+		{"/DOCS/", "/docs/"},
+		{"/users/bob/posts", ""},
+		{"/DOCS", ""},
+		{"/missing", ""},
+	}
+
+	for _, tt := range tests {
+		fixed, ok := root.matchFold(tt.path, 0, nil)
+		if tt.fixed == "" {
+			assert.False(ok, "%s should not match", tt.path)
+			continue
+		}
+		assert.True(ok, "%s should match", tt.path)
+		assert.Equal(tt.fixed, string(fixed), tt.path)
+	}
+}
+
+// TestRouteTreeManyParams tests routes with more parameters than the fixed parameter storage
+func TestRouteTreeManyParams(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+
+	var pattern, path strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&pattern, "/:p%d", i)
+		fmt.Fprintf(&path, "/v%d", i)
+	}
+	router.GET(pattern.String(), func(c *Ctx) {
+		c.String("%s %s", c.Param("p0"), c.Param("p19"))
+	})
+
+	req, _ := http.NewRequest(MethodGet, "http://example.com"+path.String(), nil)
+	w := httptest.NewRecorder()
+	ctx := GetContext(w, req)
+	defer ReleaseContext(ctx)
+	router.ServeHTTP(ctx, ctx.Request)
+	ctx.Writer.Flush()
+
+	assert.Equal("v0 v19", w.Body.String())
+	assert.Len(ctx.AllParams(), 20)
+
+	assert.Panics(func() {
+		router.GET(strings.Repeat("/:p", maxRouteParams+1), func(c *Ctx) {})
+	}, "routes with too many parameters should panic")
+}
@@ -0,0 +1,254 @@
+package ngebut
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Versioning configures how the API version of a request is selected, see Router.Version.
+type Versioning struct {
+	// Header is the request header holding the version, "Accept-Version" by default.
+	Header string
+
+	// AcceptParam is the parameter of the Accept media type holding the version,
+	// as in "application/json; version=2". "version" by default.
+	AcceptParam string
+
+	// VendorType is the prefix of vendor media types holding the version, such as
+	// "application/vnd.example" for "application/vnd.example.v2+json". Disabled when empty.
+	VendorType string
+
+	// Default is the version of requests that don't ask for one.
+	// When empty, the most recently registered version is used.
+	Default string
+}
+
+// APIVersion is a version of the API registered with Router.Version.
+type APIVersion struct {
+	version  string
+	router   *Router
+	handlers []Handler // Serves the request with router, wrapped by the parent middleware
+
+	deprecated bool
+	sunset     time.Time
+	link       string
+}
+
+// Version registers the routes of an API version, defined by fn on a group of a router
+// dedicated to the version. Requests select the version with the Versioning.Header header,
+// the Versioning.AcceptParam parameter of the Accept header, or a Versioning.VendorType
+// media type. Versions are compared without a leading "v", so "v2" and "2" are the same.
+//
+// Requests without a version are served by the Versioning.Default version. The routes of
+// the router itself serve the paths the selected version has no route for. Requests for a
+// version that isn't registered get 406 Not Acceptable when another version has a route
+// for the path. Responses of versioned routes vary on the version header and Accept.
+//
+// Example:
+//
+//	router.Version("1", func(g *ngebut.Group) {
+//		g.GET("/users", listUsersV1)
+//	}).Deprecate(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), "https://example.com/migrate")
+//
+//	router.Version("2", func(g *ngebut.Group) {
+//		g.GET("/users", listUsersV2)
+//	})
+func (r *Router) Version(version string, fn func(g *Group)) *APIVersion {
+	version = trimVersion(version)
+	if version == "" {
+		panic("API version must not be empty")
+	}
+
+	v := r.findVersion(version)
+	if v == nil {
+		v = &APIVersion{version: version, router: NewRouter()}
+		v.handlers = []Handler{v.serve}
+		r.inheritOptions(v.router)
+		r.versions = append(r.versions, v)
+	}
+
+	fn(v.router.Group(""))
+	return v
+}
+
+// Deprecate marks the version as deprecated. Its responses get a "Deprecation: true"
+// header, a Sunset header with the date it will be removed unless sunset is zero, and
+// a Link header to the deprecation documentation unless link is empty.
+func (v *APIVersion) Deprecate(sunset time.Time, link string) *APIVersion {
+	v.deprecated = true
+	v.sunset = sunset
+	v.link = link
+	return v
+}
+
+// serve sets the version headers and serves the request with the router of the version.
+func (v *APIVersion) serve(c *Ctx) {
+	if v.deprecated {
+		c.Set(HeaderDeprecation, "true")
+		if !v.sunset.IsZero() {
+			c.Set(HeaderSunset, v.sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.link != "" {
+			c.Set(HeaderLink, "<"+v.link+`>; rel="deprecation"`)
+		}
+	}
+
+	router := c.router
+	v.router.ServeHTTP(c, c.Request)
+	c.router = router
+}
+
+// findVersion returns the registered version, or nil if there is none.
+func (r *Router) findVersion(version string) *APIVersion {
+	for _, v := range r.versions {
+		if v.version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// selectedVersion returns the version the request asks for, or the default version when
+// it asks for none. It returns nil if the version isn't registered.
+func (r *Router) selectedVersion(req *Request) *APIVersion {
+	version := r.requestedVersion(req)
+	if version == "" {
+		version = r.Versioning.Default
+		if version == "" {
+			version = r.versions[len(r.versions)-1].version
+		}
+	}
+	return r.findVersion(trimVersion(version))
+}
+
+// serveVersion serves the request with the router of the version it asks for, and reports
+// whether it did. The request is left to the router when the version has no route for the path.
+func (r *Router) serveVersion(ctx *Ctx, req *Request) bool {
+	v := r.selectedVersion(req)
+	if v == nil {
+		// Unsupported versions are only refused for the paths of versioned routes
+		for _, other := range r.versions {
+			if other.router.servesPath(req.Method, req.URL.Path) {
+				r.addVersionVary(ctx)
+				r.setupMiddleware(ctx, []Handler{notAcceptableHandler})
+				return true
+			}
+		}
+		return false
+	}
+
+	if !v.router.servesPath(req.Method, req.URL.Path) {
+		return false
+	}
+
+	r.addVersionVary(ctx)
+	r.setupMiddleware(ctx, v.handlers)
+	return true
+}
+
+// versionAllowedMethods appends the methods, other than skip, that have a route of the
+// version the request asks for matching path, so that requests with another method get
+// 405 Method Not Allowed, or the automatic OPTIONS response, as with unversioned routes.
+func (r *Router) versionAllowedMethods(ctx *Ctx, req *Request, path, skip string, allowed []string) []string {
+	v := r.selectedVersion(req)
+	if v == nil {
+		return allowed
+	}
+
+	n := len(allowed)
+	allowed = v.router.allowedMethods(path, skip, allowed)
+	if len(allowed) > n {
+		r.addVersionVary(ctx)
+	}
+	return allowed
+}
+
+// requestedVersion returns the version the request asks for, or an empty string.
+func (r *Router) requestedVersion(req *Request) string {
+	header := r.Versioning.Header
+	if header == "" {
+		header = HeaderAcceptVersion
+	}
+	if version := req.Header.Get(header); version != "" {
+		return strings.TrimSpace(version)
+	}
+
+	accept := req.Header.Get(HeaderAccept)
+	if accept == "" {
+		return ""
+	}
+
+	param := r.Versioning.AcceptParam
+	if param == "" {
+		param = "version"
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		mediaType = strings.TrimSpace(mediaType)
+
+		// Vendor media types, e.g. application/vnd.example.v2+json
+		if vendor := r.Versioning.VendorType; vendor != "" && strings.HasPrefix(mediaType, vendor+".") {
+			version, _, _ := strings.Cut(mediaType[len(vendor)+1:], "+")
+			if version != "" {
+				return version
+			}
+		}
+
+		// Media type parameters, e.g. application/json; version=2
+		for params != "" {
+			var p string
+			p, params, _ = strings.Cut(params, ";")
+			name, value, _ := strings.Cut(p, "=")
+			if strings.EqualFold(strings.TrimSpace(name), param) {
+				if value = strings.TrimSpace(value); value != "" {
+					if unquoted, err := strconv.Unquote(value); err == nil {
+						value = unquoted
+					}
+					return value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// addVersionVary adds the request headers selecting the version to the Vary header.
+func (r *Router) addVersionVary(c *Ctx) {
+	header := r.Versioning.Header
+	if header == "" {
+		header = HeaderAcceptVersion
+	}
+	addVary(c, header)
+	addVary(c, HeaderAccept)
+}
+
+// addVary adds a request header name to the Vary response header, unless it is listed already.
+func addVary(c *Ctx, header string) {
+	vary := c.Get(HeaderVary)
+	for _, name := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(name), header) {
+			return
+		}
+	}
+	if vary != "" {
+		header = vary + ", " + header
+	}
+	c.Set(HeaderVary, header)
+}
+
+// trimVersion returns the version without a leading "v".
+func trimVersion(version string) string {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') {
+		return version[1:]
+	}
+	return version
+}
+
+// Pre-allocated handler for requests asking for an unsupported API version
+var notAcceptableHandler = func(c *Ctx) {
+	c.Status(StatusNotAcceptable)
+	c.String("Not Acceptable")
+}
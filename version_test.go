@@ -0,0 +1,138 @@
+package ngebut
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouterVersion tests selecting the routes of an API version with request headers
+func TestRouterVersion(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.Versioning.VendorType = "application/vnd.example"
+	router.Versioning.Default = "1"
+
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.Version("v1", func(g *Group) {
+		g.GET("/users/:id", func(c *Ctx) { c.String("v1 user %s", c.Param("id")) })
+	}).Deprecate(sunset, "https://example.com/migrate")
+	router.Version("2", func(g *Group) {
+		g.GET("/users/:id", func(c *Ctx) { c.String("v2 user %s", c.Param("id")) })
+		g.GET("/posts", func(c *Ctx) { c.String("v2 posts") })
+	})
+	router.GET("/health", func(c *Ctx) { c.String("ok") })
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(MethodGet, "http://example.com"+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Version header
+	w := serve("/users/7", http.Header{"Accept-Version": {"2"}})
+	assert.Equal("v2 user 7", w.Body.String())
+	assert.Empty(w.Header().Get(HeaderDeprecation))
+	assert.Equal("Accept-Version, Accept", w.Header().Get(HeaderVary))
+
+	// Accept parameter and vendor media type
+	assert.Equal("v2 user 7", serve("/users/7", http.Header{"Accept": {`application/json; version="2"`}}).Body.String())
+	assert.Equal("v2 user 7", serve("/users/7", http.Header{"Accept": {"application/vnd.example.v2+json"}}).Body.String())
+
+	// Default version, with the deprecation headers
+	w = serve("/users/7", nil)
+	assert.Equal("v1 user 7", w.Body.String())
+	assert.Equal("true", w.Header().Get(HeaderDeprecation))
+	assert.Equal("Fri, 01 Jan 2027 00:00:00 GMT", w.Header().Get(HeaderSunset))
+	assert.Equal(`<https://example.com/migrate>; rel="deprecation"`, w.Header().Get(HeaderLink))
+
+	// Unsupported versions
+	assert.Equal(StatusNotAcceptable, serve("/users/7", http.Header{"Accept-Version": {"3"}}).Code)
+
+	// Paths without a route in the version
+	assert.Equal(StatusNotFound, serve("/posts", http.Header{"Accept-Version": {"1"}}).Code)
+	assert.Equal("ok", serve("/health", http.Header{"Accept-Version": {"3"}}).Body.String())
+	assert.Equal("ok", serve("/health", nil).Body.String())
+
+	// Custom header, and the most recent version as the default
+	router.Versioning.Header = "X-API-Version"
+	router.Versioning.Default = ""
+	assert.Equal("v1 user 7", serve("/users/7", http.Header{"X-Api-Version": {"v1"}}).Body.String())
+	assert.Equal("v2 user 7", serve("/users/7", nil).Body.String())
+
+	infos := router.routeInfos()
+	if assert.Len(infos, 4) {
+		assert.Equal("1", infos[2].Version)
+		assert.Equal("/users/:id", infos[2].Pattern)
+		assert.Equal("2", infos[3].Version)
+	}
+}
+
+// TestRouterVersionMethodNotAllowed tests 405 and OPTIONS responses and options of version routers
+func TestRouterVersionMethodNotAllowed(t *testing.T) {
+	assert := assert.New(t)
+	router := NewRouter()
+	router.CaseSensitive = false
+	router.StrictRegistration = true
+
+	v1 := router.Version("1", func(g *Group) {
+		g.GET("/users", func(c *Ctx) { c.String("v1 users") })
+		g.PUT("/users", func(c *Ctx) { c.String("v1 put") })
+	})
+	router.Version("2", func(g *Group) {
+		g.DELETE("/users", func(c *Ctx) { c.String("v2 delete") })
+	})
+	router.POST("/users", func(c *Ctx) { c.String("post") })
+
+	serve := func(method, path, version string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://example.com"+path, nil)
+		req.Header.Set(HeaderAcceptVersion, version)
+		w := httptest.NewRecorder()
+		ctx := GetContext(w, req)
+		defer ReleaseContext(ctx)
+		router.ServeHTTP(ctx, ctx.Request)
+		ctx.Writer.Flush()
+		return w
+	}
+
+	// Methods of the version and of the router are allowed
+	w := serve(MethodPatch, "/users", "1")
+	assert.Equal(StatusMethodNotAllowed, w.Code)
+	assert.Equal("GET, HEAD, POST, PUT", w.Header().Get(HeaderAllow))
+	assert.Contains(w.Header().Get(HeaderVary), HeaderAcceptVersion)
+
+	w = serve(MethodOptions, "/users", "1")
+	assert.Equal(StatusNoContent, w.Code)
+	assert.Equal("GET, HEAD, POST, PUT, OPTIONS", w.Header().Get(HeaderAllow))
+
+	// Only the methods of the requested version are listed
+	w = serve(MethodPatch, "/users", "2")
+	assert.Equal(StatusMethodNotAllowed, w.Code)
+	assert.Equal("DELETE, POST", w.Header().Get(HeaderAllow))
+
+	// Routes of the router still serve their methods
+	assert.Equal("post", serve(MethodPost, "/users", "1").Body.String())
+
+	// Version routers take the routing options of the router
+	assert.False(v1.router.CaseSensitive)
+	assert.Equal("v1 users", serve(MethodGet, "/USERS", "1").Body.String())
+
+	assert.NotPanics(func() {
+		router.Version("1", func(g *Group) {
+			g.GET("/items/:id", func(c *Ctx) {})
+			g.GET("/items/:name", func(c *Ctx) {})
+		})
+	})
+	var conflict *RouteConflictError
+	assert.ErrorAs(router.Err(), &conflict, "conflicts of version routes should be reported by the router")
+}